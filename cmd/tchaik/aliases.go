@@ -0,0 +1,54 @@
+// Copyright 2015, David Howden
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"os"
+
+	"github.com/amiforus/tchaik/index"
+)
+
+// loadAliases reads the alias file at path.  Returns nil if the file doesn't exist.
+func loadAliases(path string) (*index.Aliases, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	defer f.Close()
+	return index.ReadAliases(f)
+}
+
+// aliasHandler is an http.Handler which serves the artist and composer aliases of the
+// library, along with the suspected variants which haven't been aliased (to help curate
+// the alias file).
+type aliasHandler struct {
+	lib *liveLibrary
+}
+
+// ServeHTTP implements http.Handler.
+func (h aliasHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	l := h.lib.Get()
+	suspects := l.suspects
+	if suspects == nil {
+		suspects = []index.AliasSuspect{}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	err := json.NewEncoder(w).Encode(struct {
+		Aliases  map[string][]string  `json:"aliases"`
+		Suspects []index.AliasSuspect `json:"suspects"`
+	}{
+		Aliases:  l.aliases.Variants(),
+		Suspects: suspects,
+	})
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	}
+}
@@ -11,6 +11,20 @@ import (
 	"github.com/amiforus/tchaik/index/attr"
 )
 
+// searchFields are the fields which can be used in search queries (i.e. composer:bach).
+// Composers and artists are searched by their original and canonical names.
+var searchFields = []attr.Interface{
+	attr.String("Composer"),
+	attr.Strings("Composer"),
+	attr.String("Artist"),
+	attr.Strings("Artist"),
+	attr.String("AlbumArtist"),
+	attr.String("Album"),
+	attr.String("Name"),
+	attr.String("Genre"),
+	attr.Int("Year"),
+}
+
 // newBootstrapSearch creates a new index.Searcher which builds the search index
 // on the first call to Search.
 func newBootstrapSearcher(root index.Collection) index.Searcher {
@@ -27,10 +41,7 @@ type bootstrapSearcher struct {
 }
 
 func (b *bootstrapSearcher) bootstrap() {
-	wi := index.BuildCollectionWordIndex(b.root, []string{"Composer", "Artist", "Album", "Name"})
-	b.Searcher = index.FlatSearcher{
-		Searcher: index.WordsIntersectSearcher(index.BuildPrefixExpandSearcher(wi, wi, 10)),
-	}
+	b.Searcher = index.BuildQuerySearcher(b.root, searchFields, []string{"Composer", "Artist", "Album", "Name"})
 }
 
 // Search implements index.Searcher.
@@ -57,7 +68,7 @@ type bootstrapFilter struct {
 }
 
 func (b *bootstrapFilter) bootstrap() {
-	b.Filter = index.FilterCollection(b.root, b.field)
+	b.Filter = index.FilterCollection(b.root, b.field, collation)
 }
 
 // Items implements index.Filter.
@@ -0,0 +1,99 @@
+// Copyright 2015, David Howden
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/amiforus/tchaik/index"
+	"github.com/amiforus/tchaik/index/attr"
+)
+
+// collectionBuilder is a function which builds an alternative root collection from the
+// album collection (root), and the album collection with split artists and composers.
+type collectionBuilder func(root, rootSplit index.Collection) index.Collection
+
+// collectionBuilders are the alternative root collections which can be enabled with the
+// -collections flag.  Each is addressable by name as the first element of a path.
+var collectionBuilders = map[string]collectionBuilder{
+	// Artist -> Album
+	"Artist": func(_, rootSplit index.Collection) index.Collection {
+		c := index.Collect(rootSplit, index.ByEach(attr.Strings("Artist")))
+		return index.SubCollect(c, index.By(attr.String("Album")))
+	},
+
+	// Composer -> Work -> Recording
+	"Composer": func(_, rootSplit index.Collection) index.Collection {
+		c := index.Collect(rootSplit, index.ByEach(attr.Strings("Composer")))
+		c = index.SubCollect(c, index.ByWork("Name", "Album"))
+		return index.SubCollect(c, index.By(attr.String("Album")))
+	},
+
+	// Genre -> Album
+	"Genre": func(root, _ index.Collection) index.Collection {
+		c := index.Collect(root, index.ByEach(attr.String("Genre")))
+		return index.SubCollect(c, index.By(attr.String("Album")))
+	},
+
+	// Decade -> Year -> Album
+	"Year": func(root, _ index.Collection) index.Collection {
+		c := index.Collect(root, index.ByDecade("Year"))
+		c = index.SubCollect(c, index.By(attr.Int("Year")))
+		return index.SubCollect(c, index.By(attr.String("Album")))
+	},
+
+	// Folder tree
+	"Folder": func(root, _ index.Collection) index.Collection {
+		return index.Collect(root, index.ByFolder("Location"))
+	},
+}
+
+// parseCollections parses a comma separated list of collection names (case insensitive),
+// and returns the corresponding names in collectionBuilders.
+func parseCollections(s string) ([]string, error) {
+	var names []string
+	for _, x := range strings.Split(s, ",") {
+		x = strings.TrimSpace(x)
+		if x == "" {
+			continue
+		}
+
+		found := false
+		for n := range collectionBuilders {
+			if strings.EqualFold(n, x) {
+				names = append(names, n)
+				found = true
+				break
+			}
+		}
+		if !found {
+			return nil, fmt.Errorf("unknown collection: %v (expected one of: %v)", x, strings.Join(collectionNames(), ", "))
+		}
+	}
+	return names, nil
+}
+
+// collectionNames returns the sorted names of the collections in collectionBuilders.
+func collectionNames() []string {
+	names := make([]string, 0, len(collectionBuilders))
+	for n := range collectionBuilders {
+		names = append(names, n)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// sortCollection sorts the keys of the collection, and all its sub-collections, by group
+// name (see index.Collation).
+func sortCollection(c index.Collection) {
+	collation.SortKeys(c)
+	for _, k := range c.Keys() {
+		if gc, ok := c.Get(k).(index.Collection); ok {
+			sortCollection(gc)
+		}
+	}
+}
@@ -51,7 +51,7 @@ func rootHandler(w http.ResponseWriter, r *http.Request) {
 }
 
 // NewHandler creates the root http.Handler.
-func NewHandler(l Library, m *Meta, mediaFileSystem, artworkFileSystem store.FileSystem) http.Handler {
+func NewHandler(l *liveLibrary, m *Meta, mediaFileSystem, artworkFileSystem store.FileSystem) http.Handler {
 	var c httpauth.Checker = httpauth.Skip
 	if authUser != "" {
 		c = httpauth.Creds(map[string]string{
@@ -78,6 +78,8 @@ func NewHandler(l Library, m *Meta, mediaFileSystem, artworkFileSystem store.Fil
 	p := player.NewPlayers()
 	h.Handle("/socket", NewWebsocketHandler(l, m, p))
 	h.Handle("/api/players/", http.StripPrefix("/api/players/", player.NewHTTPHandler(p)))
+	h.Handle("/api/playlists/", http.StripPrefix("/api/playlists/", playlistHandler{l, m}))
+	h.Handle("/api/aliases", aliasHandler{l})
 
 	return h
 }
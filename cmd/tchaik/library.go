@@ -9,11 +9,13 @@ import (
 	"net/http"
 	"path/filepath"
 	"strings"
+	"sync"
 
 	"golang.org/x/net/context"
 
 	"github.com/amiforus/tchaik/index"
 	"github.com/amiforus/tchaik/index/attr"
+	"github.com/amiforus/tchaik/index/playlist"
 	"github.com/amiforus/tchaik/store"
 )
 
@@ -22,31 +24,59 @@ type Library struct {
 	index.Library
 
 	collections map[string]index.Collection
+	roots       map[string]*index.RootCollection
 	filters     map[string]index.Filter
 	recent      Lister
 	searcher    index.Searcher
+	smart       *smartTracks
+
+	aliases  *index.Aliases
+	suspects []index.AliasSuspect
 }
 
 func NewLibrary(l index.Library) Library {
 	fmt.Printf("Building root collection...")
-	root := buildRootCollection(l)
+	root := index.CollectAlbums(l, albumGrouping, collation)
 	fmt.Println("done.")
 
 	fmt.Printf("Processing artist names and composers...")
-	rootSplit := index.SubTransform(root, index.SplitList("Artist", "Composer"))
+	a, suspects := index.DetectAliases(l, splitRules, []string{"Artist", "Composer"}, aliases)
+	rootSplit := index.SubTransform(root, index.SplitAliasList(splitRules, a, "Artist", "Composer"))
 	fmt.Println("done.")
 
+	collections := map[string]index.Collection{
+		"Root": root,
+	}
+	for _, n := range altCollections {
+		fmt.Printf("Building %v collection...", n)
+		c := collectionBuilders[n](root, rootSplit)
+		sortCollection(c)
+		collections[n] = c
+		fmt.Println("done.")
+	}
+
+	// Each collection has its own cache of transformed groups, which is dropped along with
+	// the Library when it is reloaded.
+	roots := make(map[string]*index.RootCollection, len(collections))
+	for n, c := range collections {
+		rc := newRootCollection(c)
+		rc.Cache = index.NewGroupCache(groupCacheSize)
+		roots[n] = rc
+	}
+
 	return Library{
-		Library: l,
-		collections: map[string]index.Collection{
-			"Root": root,
-		},
+		Library:     l,
+		collections: collections,
+		roots:       roots,
 		filters: map[string]index.Filter{
 			"Artist":   newBootstrapFilter(rootSplit, attr.Strings("Artist")),
 			"Composer": newBootstrapFilter(rootSplit, attr.Strings("Composer")),
 		},
 		recent:   &bootstrapRecent{root: root, n: 150},
-		searcher: newBootstrapSearcher(root),
+		searcher: newBootstrapSearcher(rootSplit),
+		smart:    &smartTracks{root: newRootCollection(root)},
+		aliases:  a,
+		suspects: suspects,
 	}
 }
 
@@ -87,33 +117,74 @@ func (l *Library) Fetch(p index.Path) (index.Group, index.Key, error) {
 		return root, p[0], nil
 	}
 
-	g, err := l.Build(root, p[1:])
+	g, err := l.Build(l.rootCollection(string(p[0])), p[1:])
 	if err != nil {
 		return nil, "", fmt.Errorf("error in Fetch: %v (path: %#v)", err, p[1:])
 	}
 	return g, p[1], nil
 }
 
+// newRootCollection wraps the "Root" collection c in an index.RootCollection which uses the
+// split rules.
+func newRootCollection(c index.Collection) *index.RootCollection {
+	return &index.RootCollection{
+		Collection: c,
+		SplitRules: splitRules,
+	}
+}
+
+// rootCollection returns the index.RootCollection (with its cache of transformed groups) for
+// the named collection.
+func (l Library) rootCollection(name string) *index.RootCollection {
+	if rc, ok := l.roots[name]; ok {
+		return rc
+	}
+	return newRootCollection(l.collections[name])
+}
+
+// smartTracks is the list of tracks which smart playlists are evaluated against, which is
+// built the first time it is used.
+type smartTracks struct {
+	once   sync.Once
+	root   index.Collection
+	tracks *playlist.Tracks
+}
+
+// playlistTracks returns the tracks of the "Root" collection which smart playlists are
+// evaluated against.
+func (l Library) playlistTracks() *playlist.Tracks {
+	if l.smart == nil {
+		return playlist.NewTracks(l.rootCollection("Root"))
+	}
+	// The tracks are walked without the cache of transformed groups, which would otherwise
+	// be emptied.
+	l.smart.once.Do(func() {
+		l.smart.tracks = playlist.NewTracks(l.smart.root)
+	})
+	return l.smart.tracks
+}
+
 // Build fetches a Group from the index.Collection given by the Path.
 func (l *Library) Build(c index.Collection, p index.Path) (index.Group, error) {
 	if len(p) == 0 {
 		return c, nil
 	}
 
-	g, err := index.GroupFromPath(&rootCollection{c}, p)
+	rc, ok := c.(*index.RootCollection)
+	if !ok {
+		rc = newRootCollection(c)
+	}
+	g, err := index.GroupFromPath(rc, p)
 	if err != nil {
 		return nil, err
 	}
+	if rc, ok := g.(*index.RootCollection); ok {
+		g = rc.Collection
+	}
 	g = index.FirstTrackAttr(attr.String("ID"), g)
 	return g, nil
 }
 
-// FileSystem wraps the http.FileSystem in a library lookup which will translate /ID
-// requests into their corresponding track paths.
-func (l *Library) FileSystem(fs store.FileSystem) store.FileSystem {
-	return store.Trace(&libraryFileSystem{fs, l.Library}, "libraryFileSystem")
-}
-
 // ExpandPaths constructs a collection (group) whose sub-groups are taken from the "Root"
 // collection.
 func (l *Library) ExpandPaths(paths []index.Path) index.Group {
@@ -122,3 +193,137 @@ func (l *Library) ExpandPaths(paths []index.Path) index.Group {
 		Key:   index.Key("Root"),
 	}
 }
+
+// remap returns an index.PathMap which maps paths in the collections of old to the equivalent
+// paths in the collections of l (see index.Remapper).  Paths which aren't in a collection of
+// both are left unchanged.
+func (l Library) remap(old Library) index.PathMap {
+	rs := make(map[index.Key]*index.Remapper, len(l.collections))
+	for n, c := range l.collections {
+		if oc, ok := old.collections[n]; ok {
+			rs[index.Key(n)] = index.NewRemapper(newRootCollection(oc), newRootCollection(c))
+		}
+	}
+
+	return func(p index.Path) (index.Path, bool) {
+		if len(p) == 0 {
+			return p, false
+		}
+		r, ok := rs[p[0]]
+		if !ok {
+			return p, true
+		}
+		return r.Map(p)
+	}
+}
+
+// firstPathMap returns an index.PathMap which returns the result of the first of pms which
+// can map the path.
+func firstPathMap(pms ...index.PathMap) index.PathMap {
+	return func(p index.Path) (index.Path, bool) {
+		for _, pm := range pms {
+			if np, ok := pm(p); ok {
+				return np, true
+			}
+		}
+		return p, false
+	}
+}
+
+// libraryUpdate is a summary of the changes made when a Library is replaced.
+type libraryUpdate struct {
+	Added   int `json:"added"`
+	Updated int `json:"updated"`
+	Removed int `json:"removed"`
+}
+
+// liveLibrary is a container for a Library which can be replaced while the server is
+// running.  It implements index.Library using the current Library.
+type liveLibrary struct {
+	sync.RWMutex // protects lib and subs
+
+	lib  Library
+	subs map[chan libraryUpdate]bool
+}
+
+func newLiveLibrary(l Library) *liveLibrary {
+	return &liveLibrary{
+		lib:  l,
+		subs: make(map[chan libraryUpdate]bool),
+	}
+}
+
+// Get returns the current Library.
+func (l *liveLibrary) Get() Library {
+	l.RLock()
+	defer l.RUnlock()
+
+	return l.lib
+}
+
+// Set replaces the current Library, and sends the update to all subscribers (see sendUpdate).
+func (l *liveLibrary) Set(lib Library, u libraryUpdate) {
+	l.Lock()
+	defer l.Unlock()
+
+	l.lib = lib
+	for ch := range l.subs {
+		sendUpdate(ch, u)
+	}
+}
+
+// sendUpdate sends u to the subscriber channel ch without blocking.  If ch still holds an
+// update which hasn't been received then it is replaced by the sum of both, so that no changes
+// are lost.  The liveLibrary must be locked, so that there are no other senders and ch can't
+// be closed.
+func sendUpdate(ch chan libraryUpdate, u libraryUpdate) {
+	for {
+		select {
+		case ch <- u:
+			return
+		case p := <-ch:
+			u.Added += p.Added
+			u.Updated += p.Updated
+			u.Removed += p.Removed
+		}
+	}
+}
+
+// Subscribe returns a channel which receives an update each time the Library is replaced.
+func (l *liveLibrary) Subscribe() <-chan libraryUpdate {
+	l.Lock()
+	defer l.Unlock()
+
+	ch := make(chan libraryUpdate, 1)
+	l.subs[ch] = true
+	return ch
+}
+
+// Unsubscribe removes (and closes) a channel created by Subscribe.
+func (l *liveLibrary) Unsubscribe(ch <-chan libraryUpdate) {
+	l.Lock()
+	defer l.Unlock()
+
+	for x := range l.subs {
+		if x == ch {
+			delete(l.subs, x)
+			close(x)
+		}
+	}
+}
+
+// Tracks implements index.Library.
+func (l *liveLibrary) Tracks() []index.Track {
+	return l.Get().Tracks()
+}
+
+// Track implements index.Library.
+func (l *liveLibrary) Track(id string) (index.Track, bool) {
+	return l.Get().Track(id)
+}
+
+// FileSystem wraps the http.FileSystem in a library lookup which will translate /ID
+// requests into their corresponding track paths (using the current Library).
+func (l *liveLibrary) FileSystem(fs store.FileSystem) store.FileSystem {
+	return store.Trace(&libraryFileSystem{fs, l}, "libraryFileSystem")
+}
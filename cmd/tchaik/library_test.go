@@ -0,0 +1,76 @@
+// Copyright 2015, David Howden
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestLiveLibrarySubscribe(t *testing.T) {
+	l := newLiveLibrary(Library{})
+	ch := l.Subscribe()
+
+	u := libraryUpdate{Added: 1}
+	l.Set(Library{}, u)
+	select {
+	case got := <-ch:
+		if got != u {
+			t.Errorf("received %#v, expected %#v", got, u)
+		}
+	case <-time.After(time.Second):
+		t.Fatalf("no update received")
+	}
+
+	select {
+	case got := <-ch:
+		t.Errorf("received %#v, expected only one update", got)
+	default:
+	}
+
+	// Updates which haven't been received are combined.
+	l.Set(Library{}, libraryUpdate{Added: 1, Removed: 2})
+	l.Set(Library{}, libraryUpdate{Added: 2, Updated: 3})
+	if got, expected := <-ch, (libraryUpdate{Added: 3, Updated: 3, Removed: 2}); got != expected {
+		t.Errorf("received %#v, expected %#v", got, expected)
+	}
+
+	l.Unsubscribe(ch)
+	if _, ok := <-ch; ok {
+		t.Errorf("received update after Unsubscribe, expected channel to be closed")
+	}
+
+	// Set after Unsubscribe doesn't send to (or panic on) the closed channel.
+	l.Set(Library{}, u)
+}
+
+func TestLiveLibraryConcurrent(t *testing.T) {
+	l := newLiveLibrary(Library{})
+
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			ch := l.Subscribe()
+			go func() {
+				for range ch {
+				}
+			}()
+			l.Unsubscribe(ch)
+		}()
+		go func() {
+			defer wg.Done()
+			l.Set(Library{}, libraryUpdate{Added: 1})
+			l.Get()
+		}()
+	}
+	wg.Wait()
+
+	if n := len(l.subs); n != 0 {
+		t.Errorf("%d subscribers remaining, expected 0", n)
+	}
+}
@@ -14,6 +14,17 @@ A common use case is to begin by use using an existing iTunes Library file:
 
   tchaik -itlXML /path/to/iTunesMusicLibrary.xml
 
+Libraries from Rhythmbox, Clementine (or Strawberry) and beets can be used in the same way with -rhythmbox,
+-clementine and -beets.  Their play counts and ratings are added to the play history and ratings files.
+
+Playlists can be exported to (or imported from) M3U, M3U8, PLS and XSPF files using the playlist subcommand,
+which uses the same library flags (the file format is determined by the extension):
+
+  tchaik -lib lib.tch playlist export <name> playlist.m3u8
+  tchaik -lib lib.tch playlist import <name> playlist.xspf
+
+Track locations are rewritten using -trim-path-prefix and -add-path-prefix.  Playlists can also be downloaded
+from a running server at /api/playlists/<name>.<ext>.
 */
 package main
 
@@ -24,11 +35,16 @@ import (
 	"log"
 	"net/http"
 	"os"
+	"strings"
+	"time"
 
 	"github.com/amiforus/tchaik/index"
-	"github.com/amiforus/tchaik/index/attr"
 
+	"github.com/amiforus/tchaik/index/beets"
+	"github.com/amiforus/tchaik/index/clementine"
 	"github.com/amiforus/tchaik/index/itl"
+	"github.com/amiforus/tchaik/index/migrate"
+	"github.com/amiforus/tchaik/index/rhythmbox"
 	"github.com/amiforus/tchaik/index/walk"
 	"github.com/amiforus/tchaik/store"
 	"github.com/amiforus/tchaik/store/cmdflag"
@@ -36,8 +52,36 @@ import (
 
 var debug bool
 var itlXML, tchLib, walkPath string
+var rhythmboxXML, clementineDB, beetsDB string
+
+// migrated is the library read from another media player (if any), used to import its
+// play counts and ratings.
+var migrated *migrate.Library
+
+var importITLPlaylists bool
+var itlPlaylists []itl.Playlist
+
+var watch bool
+var watchPoll time.Duration
+
+var collectionsList string
+var altCollections []string
+
+var albumGroupingName string
+var albumGrouping index.AlbumGrouping
+
+var sortLocale, sortArticles string
+var collation *index.Collation
+
+var aliasesPath string
+var aliases *index.Aliases
+
+var splitRulesPath string
+var splitRules *index.SplitRules
+
+var groupCacheSize int
 
-var playHistoryPath, favouritesPath, checklistPath, playlistPath, cursorPath string
+var playHistoryPath, favouritesPath, checklistPath, playlistPath, cursorPath, ratingsPath string
 
 var listenAddr string
 var uiDir string
@@ -57,9 +101,24 @@ func init() {
 	flag.StringVar(&itlXML, "itlXML", "", "iTunes Library XML `file`")
 	flag.StringVar(&tchLib, "lib", "", "Tchaik library `file`")
 	flag.StringVar(&walkPath, "path", "", "`directory` containing music files")
+	flag.StringVar(&rhythmboxXML, "rhythmbox", "", "Rhythmbox database `file` (rhythmdb.xml)")
+	flag.StringVar(&clementineDB, "clementine", "", "Clementine or Strawberry database `file`")
+	flag.StringVar(&beetsDB, "beets", "", "beets database `file` (library.db)")
+	flag.BoolVar(&importITLPlaylists, "itl-playlists", false, "add the playlists from the iTunes Library XML file (-itlXML) to the playlists file (existing playlists are not changed)")
+	flag.BoolVar(&watch, "watch", false, "watch -path for changes and update the library while running")
+	flag.DurationVar(&watchPoll, "watch-poll", 0, "poll -path for changes every `interval` instead of using filesystem notifications (requires -watch)")
+
+	flag.StringVar(&collectionsList, "collections", "", "comma separated `list` of additional root collections to build (Artist, Composer, Genre, Year, Folder)")
+	flag.StringVar(&albumGroupingName, "group-albums", "album", "`rule` for grouping tracks into albums: album (by title only), artist (by title and album artist) or folder (by title, album artist and directory)")
+	flag.StringVar(&sortLocale, "sort-locale", "en", "`locale` used to order albums, collections and filters (i.e. en, fr, de)")
+	flag.StringVar(&aliasesPath, "aliases", "aliases.json", "artist and composer aliases `file` (a JSON object of canonical name -> list of variants)")
+	flag.StringVar(&splitRulesPath, "split-rules", "split.json", "rules `file` for splitting lists of artists and composers (a JSON object of separators by field, and exceptions)")
+	flag.IntVar(&groupCacheSize, "group-cache", 512, "maximum `number` of transformed albums to cache for each collection (0 to disable)")
+	flag.StringVar(&sortArticles, "sort-articles", "", "comma separated `list` of leading articles to ignore when ordering (default: articles for -sort-locale)")
 
 	flag.StringVar(&playHistoryPath, "play-history", "history.json", "play history `file`")
 	flag.StringVar(&favouritesPath, "favourites", "favourites.json", "favourites `file`")
+	flag.StringVar(&ratingsPath, "ratings", "ratings.json", "ratings `file`")
 	flag.StringVar(&checklistPath, "checklist", "checklist.json", "checklist `file`")
 	flag.StringVar(&playlistPath, "playlists", "playlists.json", "playlists `file`")
 	flag.StringVar(&cursorPath, "cursors", "cursors.json", "cursors `file`")
@@ -84,13 +143,13 @@ func (e *assignedCount) check(list ...string) {
 
 func readLibrary() (index.Library, error) {
 	e := assignedCount(0)
-	e.check(itlXML, tchLib, walkPath)
+	e.check(itlXML, tchLib, walkPath, rhythmboxXML, clementineDB, beetsDB)
 
 	switch {
 	case e == 0:
-		return nil, fmt.Errorf("must specify one library file or a path to build one from (-itlXML, -lib or -path)")
+		return nil, fmt.Errorf("must specify one library file or a path to build one from (-itlXML, -lib, -path, -rhythmbox, -clementine or -beets)")
 	case e > 1:
-		return nil, fmt.Errorf("must only specify one library file or a path to build one from (-itlXML, -lib or -path)")
+		return nil, fmt.Errorf("must only specify one library file or a path to build one from (-itlXML, -lib, -path, -rhythmbox, -clementine or -beets)")
 	}
 
 	var lib index.Library
@@ -122,10 +181,50 @@ func readLibrary() (index.Library, error) {
 			return nil, fmt.Errorf("error parsing iTunes library file: %v", err)
 		}
 
+		if importITLPlaylists {
+			var smart []string
+			itlPlaylists, smart, err = itl.Playlists(lib)
+			if err != nil {
+				return nil, fmt.Errorf("error reading iTunes playlists: %v", err)
+			}
+			for _, n := range smart {
+				fmt.Printf("Skipping iTunes smart playlist: '%v'\n", n)
+			}
+		}
+
 	case walkPath != "":
 		fmt.Printf("Walking %v...\n", walkPath)
 		lib = walk.NewLibrary(walkPath)
 		fmt.Println("Finished walking.")
+
+	case rhythmboxXML != "":
+		f, err := os.Open(rhythmboxXML)
+		if err != nil {
+			return nil, fmt.Errorf("could not open Rhythmbox database file: %v", err)
+		}
+		defer f.Close()
+
+		migrated, err = rhythmbox.ReadFrom(f)
+		if err != nil {
+			return nil, err
+		}
+		lib = migrated
+
+	case clementineDB != "":
+		var err error
+		migrated, err = clementine.Open(clementineDB)
+		if err != nil {
+			return nil, fmt.Errorf("error reading Clementine database: %v", err)
+		}
+		lib = migrated
+
+	case beetsDB != "":
+		var err error
+		migrated, err = beets.Open(beetsDB)
+		if err != nil {
+			return nil, fmt.Errorf("error reading beets database: %v", err)
+		}
+		lib = migrated
 	}
 
 	fmt.Printf("Building Tchaik Library...")
@@ -134,21 +233,90 @@ func readLibrary() (index.Library, error) {
 	return lib, nil
 }
 
-func buildRootCollection(l index.Library) index.Collection {
-	root := index.Collect(l, index.By(attr.String("Album")))
-	index.SortKeysByGroupName(root)
-	return root
+// parseArticles returns the list of articles given by -sort-articles, or nil if the flag
+// wasn't set (so that the articles of -sort-locale are used).
+func parseArticles() []string {
+	set := false
+	flag.Visit(func(f *flag.Flag) {
+		if f.Name == "sort-articles" {
+			set = true
+		}
+	})
+	if !set {
+		return nil
+	}
+
+	articles := []string{}
+	for _, x := range strings.Split(sortArticles, ",") {
+		if x = strings.TrimSpace(x); x != "" {
+			articles = append(articles, x)
+		}
+	}
+	return articles
+}
+
+// runCommand runs the subcommand given by args.
+func runCommand(args []string, l index.Library) error {
+	if args[0] != "playlist" {
+		return fmt.Errorf("unknown command: %v", args[0])
+	}
+
+	meta, err := loadLocalMeta()
+	if err != nil {
+		return err
+	}
+	return playlistCommand(args[1:], l, meta)
 }
 
 func main() {
 	flag.Parse()
 
+	var err error
+	altCollections, err = parseCollections(collectionsList)
+	if err != nil {
+		fmt.Printf("error: %v\n", err)
+		os.Exit(1)
+	}
+
+	albumGrouping, err = index.ParseAlbumGrouping(albumGroupingName)
+	if err != nil {
+		fmt.Printf("error: %v\n", err)
+		os.Exit(1)
+	}
+
+	collation, err = index.NewCollation(sortLocale, parseArticles())
+	if err != nil {
+		fmt.Printf("error: %v\n", err)
+		os.Exit(1)
+	}
+
+	aliases, err = loadAliases(aliasesPath)
+	if err != nil {
+		fmt.Printf("error loading aliases: %v\n", err)
+		os.Exit(1)
+	}
+
+	splitRules, err = loadSplitRules(splitRulesPath)
+	if err != nil {
+		fmt.Printf("error loading split rules: %v\n", err)
+		os.Exit(1)
+	}
+
 	l, err := readLibrary()
 	if err != nil {
 		fmt.Printf("error: %v\n", err)
 		os.Exit(1)
 	}
 
+	if flag.NArg() > 0 {
+		err = runCommand(flag.Args(), l)
+		if err != nil {
+			fmt.Printf("error: %v\n", err)
+			os.Exit(1)
+		}
+		return
+	}
+
 	mediaFileSystem, artworkFileSystem, err := cmdflag.Stores()
 	if err != nil {
 		fmt.Println("error setting up stores:", err)
@@ -167,12 +335,67 @@ func main() {
 		}()
 	}
 
-	lib := NewLibrary(l)
+	lib := newLiveLibrary(NewLibrary(l))
+
 	meta, err := loadLocalMeta()
 	if err != nil {
 		fmt.Println(err)
 		os.Exit(1)
 	}
+
+	// Update any paths which were created by older versions of tchaik, or when albums were
+	// grouped by title only (the default).
+	fmt.Printf("Checking metadata paths...")
+	pm := lib.Get().remap(lib.Get())
+	if albumGrouping != index.GroupByAlbum {
+		byTitle := Library{
+			collections: map[string]index.Collection{
+				"Root": index.CollectAlbums(l, index.GroupByAlbum, collation),
+			},
+		}
+		pm = firstPathMap(pm, lib.Get().remap(byTitle))
+	}
+	err = meta.migrate(pm)
+	if err != nil {
+		fmt.Printf("\n%v\n", err)
+		os.Exit(1)
+	}
+	fmt.Println("done.")
+
+	if watch {
+		if walkPath == "" {
+			fmt.Println("error: -watch requires -path")
+			os.Exit(1)
+		}
+		err = watchLibrary(lib, meta, walkPath, watchPoll)
+		if err != nil {
+			fmt.Printf("error watching %v: %v\n", walkPath, err)
+			os.Exit(1)
+		}
+	}
+
+	if len(itlPlaylists) > 0 {
+		fmt.Printf("Adding iTunes playlists...")
+		root := lib.Get().rootCollection("Root")
+		added, err := itl.ImportPlaylists(itlPlaylists, meta.playlists, root)
+		if err != nil {
+			fmt.Printf("\nerror adding iTunes playlists: %v\n", err)
+			os.Exit(1)
+		}
+		fmt.Printf("done (%d added).\n", len(added))
+	}
+
+	if migrated != nil {
+		fmt.Printf("Importing play counts and ratings...")
+		root := lib.Get().rootCollection("Root")
+		plays, ratings, err := migrate.Import(migrated, root, meta.history, meta.ratings)
+		if err != nil {
+			fmt.Printf("\nerror importing play counts and ratings: %v\n", err)
+			os.Exit(1)
+		}
+		fmt.Printf("done (%d play counts, %d ratings).\n", plays, ratings)
+	}
+
 	h := NewHandler(lib, meta, mediaFileSystem, artworkFileSystem)
 
 	if certFile != "" && keyFile != "" {
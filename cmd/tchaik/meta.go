@@ -13,6 +13,7 @@ import (
 	"github.com/amiforus/tchaik/index/favourite"
 	"github.com/amiforus/tchaik/index/history"
 	"github.com/amiforus/tchaik/index/playlist"
+	"github.com/amiforus/tchaik/index/rating"
 )
 
 // Meta is a container for extra metadata which wraps the central media library.
@@ -20,9 +21,14 @@ import (
 type Meta struct {
 	history    history.Store
 	favourites favourite.Store
+	ratings    rating.Store
 	checklist  checklist.Store
 	playlists  playlist.Store
 	cursors    cursor.Store
+
+	// unresolved is the set of paths which have already been reported as unresolved, by store
+	// name.
+	unresolved map[string]map[string]bool
 }
 
 func loadLocalMeta() (*Meta, error) {
@@ -40,6 +46,13 @@ func loadLocalMeta() (*Meta, error) {
 	}
 	fmt.Println("done.")
 
+	fmt.Printf("Loading ratings...")
+	ratingStore, err := rating.NewStore(ratingsPath)
+	if err != nil {
+		return nil, fmt.Errorf("\nerror loading ratings: %v", err)
+	}
+	fmt.Println("done.")
+
 	fmt.Printf("Loading checklist...")
 	checklistStore, err := checklist.NewStore(checklistPath)
 	if err != nil {
@@ -68,12 +81,57 @@ func loadLocalMeta() (*Meta, error) {
 	return &Meta{
 		history:    playHistoryStore,
 		favourites: favouriteStore,
+		ratings:    ratingStore,
 		checklist:  checklistStore,
 		playlists:  playlistStore,
 		cursors:    cursorStore,
+		unresolved: make(map[string]map[string]bool),
 	}, nil
 }
 
+// migrate maps the paths in all the stores using the index.PathMap (i.e. after the library has
+// been rebuilt), and reports any paths which could no longer be resolved.  Unresolved paths are
+// kept in the stores (so they are resolved again if their tracks come back), but are only
+// reported the first time.
+func (m *Meta) migrate(pm index.PathMap) error {
+	stores := []struct {
+		name    string
+		migrate func(index.PathMap) ([]index.Path, error)
+	}{
+		{"play history", m.history.Migrate},
+		{"favourites", m.favourites.Migrate},
+		{"ratings", m.ratings.Migrate},
+		{"checklist", m.checklist.Migrate},
+		{"playlists", m.playlists.Migrate},
+	}
+
+	for _, s := range stores {
+		unresolved, err := s.migrate(pm)
+		if err != nil {
+			return fmt.Errorf("error migrating %v: %v", s.name, err)
+		}
+		reported := make(map[string]bool, len(unresolved))
+		for _, p := range unresolved {
+			k := p.String()
+			if !m.unresolved[s.name][k] {
+				fmt.Printf("%v: could not resolve path: %v\n", s.name, p)
+			}
+			reported[k] = true
+		}
+		m.unresolved[s.name] = reported
+	}
+	return nil
+}
+
+// playlistSources returns the metadata sources used to evaluate smart playlists.
+func (m *Meta) playlistSources() playlist.Sources {
+	return playlist.Sources{
+		History:    m.history,
+		Ratings:    m.ratings,
+		Favourites: m.favourites,
+	}
+}
+
 type metaFieldGrp struct {
 	index.Group
 
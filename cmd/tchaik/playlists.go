@@ -0,0 +1,137 @@
+// Copyright 2015, David Howden
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/amiforus/tchaik/index"
+	"github.com/amiforus/tchaik/index/playlist"
+	"github.com/amiforus/tchaik/store/cmdflag"
+)
+
+// playlistRewrite returns the location rewriting for playlist files, set by the
+// -trim-path-prefix and -add-path-prefix flags.
+func playlistRewrite() playlist.PathRewrite {
+	trim, add := cmdflag.PathPrefixes()
+	return playlist.PathRewrite{TrimPrefix: trim, AddPrefix: add}
+}
+
+// exportPlaylist writes the named playlist to w in the format f.
+func exportPlaylist(w io.Writer, f playlist.Format, name string, root index.Collection, m *Meta) error {
+	p := m.playlists.Get(name)
+	if p == nil {
+		return fmt.Errorf("invalid playlist name: '%v'", name)
+	}
+	if p.Smart() != nil {
+		p = p.Copy()
+		p.Update(playlist.NewTracks(root), m.playlistSources())
+	}
+
+	entries, err := playlist.Entries(p, root, playlistRewrite())
+	if err != nil {
+		return err
+	}
+	return playlist.WriteEntries(w, f, name, entries)
+}
+
+// playlistCommand runs the playlist subcommand:
+//
+//	tchaik [flags] playlist export <name> <file>
+//	tchaik [flags] playlist import <name> <file>
+//
+// The format of the playlist file is determined by its extension (.m3u, .m3u8, .pls or
+// .xspf).
+func playlistCommand(args []string, l index.Library, m *Meta) error {
+	if len(args) != 3 || (args[0] != "import" && args[0] != "export") {
+		return fmt.Errorf("usage: tchaik [flags] playlist (import|export) <name> <file>")
+	}
+	action, name, path := args[0], args[1], args[2]
+	root := newRootCollection(index.CollectAlbums(l, albumGrouping, collation))
+
+	f, err := playlist.FormatFromExt(path)
+	if err != nil {
+		return err
+	}
+
+	if action == "export" {
+		out, err := os.Create(path)
+		if err != nil {
+			return err
+		}
+		err = exportPlaylist(out, f, name, root, m)
+		if err1 := out.Close(); err == nil {
+			err = err1
+		}
+		return err
+	}
+
+	if m.playlists.Get(name) != nil {
+		return fmt.Errorf("playlist already exists: '%v'", name)
+	}
+
+	in, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	entries, err := playlist.ReadEntries(in, f)
+	if err != nil {
+		return err
+	}
+
+	// Locations in playlist files are often relative to the file, and locations in the library
+	// are absolute.
+	dir, err := filepath.Abs(filepath.Dir(path))
+	if err != nil {
+		return err
+	}
+	for i, e := range entries {
+		if !filepath.IsAbs(e.Location) && !strings.Contains(e.Location, "://") {
+			entries[i].Location = filepath.Join(dir, e.Location)
+		}
+	}
+
+	p, missing := playlist.Import(entries, root, playlistRewrite())
+	for _, e := range missing {
+		fmt.Printf("track not found: '%v'\n", e.Location)
+	}
+	fmt.Printf("Imported %d of %d tracks into playlist '%v'.\n", len(entries)-len(missing), len(entries), name)
+	return m.playlists.Set(name, p)
+}
+
+// playlistHandler is an http.Handler which serves playlist files: requests for
+// <name>.<ext> export the playlist <name> in the format given by <ext>.
+type playlistHandler struct {
+	lib  *liveLibrary
+	meta *Meta
+}
+
+// ServeHTTP implements http.Handler.
+func (h playlistHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f, err := playlist.FormatFromExt(r.URL.Path)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+	name := strings.TrimSuffix(r.URL.Path, filepath.Ext(r.URL.Path))
+	if h.meta.playlists.Get(name) == nil {
+		http.NotFound(w, r)
+		return
+	}
+
+	w.Header().Set("Content-Type", f.ContentType())
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", filepath.Base(r.URL.Path)))
+	root := h.lib.Get().rootCollection("Root")
+	if err := exportPlaylist(w, f, name, root, h.meta); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	}
+}
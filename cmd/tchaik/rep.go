@@ -31,6 +31,9 @@ func (g *Group) MarshalJSON() ([]byte, error) {
 		BitRate:     g.Field("BitRate"),
 		DiscNumber:  g.Field("DiscNumber"),
 		ListStyle:   g.Field("ListStyle"),
+		ListNumbers: g.Field("ListNumbers"),
+		Work:        g.Field("Work"),
+		Catalogue:   g.Field("Catalogue"),
 		Kind:        g.Field("Kind"),
 		ID:          g.Field("ID"),
 		Favourite:   g.Field("Favourite"),
@@ -102,6 +105,7 @@ func buildCollection(h group, c index.Collection) group {
 			Key:         k,
 			AlbumArtist: g.Field("AlbumArtist"),
 			Artist:      g.Field("Artist"),
+			Catalogue:   g.Field("Catalogue"),
 		})
 	}
 	return h
@@ -127,12 +131,13 @@ func (t *Track) GetString(field string) string {
 	return t.Track.GetString(field)
 }
 
-// GetStrings implements index.Track.
+// GetStrings implements index.Track.  Tracks always have their original names (and not
+// split lists of canonical names, see index.SplitAliasList).
 func (t *Track) GetStrings(field string) []string {
 	if t.group.Field(field) != nil {
 		return nil
 	}
-	return t.Track.GetStrings(field)
+	return index.DefaultGetStrings(t.Track, field)
 }
 
 // GetInt implements index.Track.
@@ -185,6 +190,9 @@ type group struct {
 	BitRate     interface{}   `json:"bitRate,omitempty"`
 	DiscNumber  interface{}   `json:"discNumber,omitempty"`
 	ListStyle   interface{}   `json:"listStyle,omitempty"`
+	ListNumbers interface{}   `json:"listNumbers,omitempty"`
+	Work        interface{}   `json:"work,omitempty"`
+	Catalogue   interface{}   `json:"catalogue,omitempty"`
 	ID          interface{}   `json:"id,omitempty"`
 	Year        interface{}   `json:"year,omitempty"`
 	Kind        interface{}   `json:"kind,omitempty"`
@@ -193,34 +201,3 @@ type group struct {
 	Groups      []group       `json:"groups,omitempty"`
 	Tracks      []index.Track `json:"tracks,omitempty"`
 }
-
-type rootCollection struct {
-	index.Collection
-}
-
-func (r *rootCollection) Get(k index.Key) index.Group {
-	g := r.Collection.Get(k)
-	if g == nil {
-		return g
-	}
-
-	index.Sort(g.Tracks(), index.MultiSort(index.SortByString("Kind"), index.SortByInt("DiscNumber"), index.SortByInt("TrackNumber")))
-	g = index.Transform(g, index.SplitList("Artist", "AlbumArtist", "Composer"))
-	g = index.Transform(g, index.TrimTrackNumPrefix)
-	c := index.Collect(g, index.ByPrefix("Name"))
-	g = index.SubTransform(c, index.TrimEnumPrefix)
-	g = index.SumGroupIntAttr("TotalTime", g)
-	commonFields := []attr.Interface{
-		attr.String("Album"),
-		attr.Strings("Artist"),
-		attr.Strings("AlbumArtist"),
-		attr.Strings("Composer"),
-		attr.String("Kind"),
-		attr.Int("Year"),
-		attr.Int("BitRate"),
-		attr.Int("DiscNumber"),
-	}
-	g = index.CommonGroupAttr(commonFields, g)
-	g = index.RemoveEmptyCollections(g)
-	return g
-}
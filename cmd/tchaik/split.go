@@ -0,0 +1,25 @@
+// Copyright 2015, David Howden
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"os"
+
+	"github.com/amiforus/tchaik/index"
+)
+
+// loadSplitRules reads the rules for splitting lists of artists and composers from the file
+// at path.  Returns nil (the default rules) if the file doesn't exist.
+func loadSplitRules(path string) (*index.SplitRules, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	defer f.Close()
+	return index.ReadSplitRules(f)
+}
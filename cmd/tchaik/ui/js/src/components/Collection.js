@@ -93,7 +93,7 @@ export class Group extends React.Component {
         );
       }
 
-      attributes = <GroupAttributes data={common} attributes={["albumArtist", "artist", "composer", "year"]} />;
+      attributes = <GroupAttributes data={common} attributes={["albumArtist", "artist", "composer", "catalogue", "year"]} />;
 
       const favouriteIcon = this.state.favourite ? "favorite" : "favorite_border";
       const checklistIcon = this.state.checklist ? "check_circle" : "check";
@@ -241,7 +241,7 @@ class GroupContent extends React.Component {
     if (item.groups) {
       return <GroupList path={this.props.path} depth={this.props.depth} list={item.groups} />;
     }
-    return <TrackList path={this.props.path} list={item.tracks} listStyle={item.listStyle} />;
+    return <TrackList path={this.props.path} list={item.tracks} listStyle={item.listStyle} listNumbers={item.listNumbers} />;
   }
 
   _onChange(keyPath) {
@@ -249,7 +249,7 @@ class GroupContent extends React.Component {
       const item = CollectionStore.getCollection(this.props.path);
 
       const common = {};
-      for (const f of ["totalTime", "albumArtist", "artist", "id", "composer", "year", "kind"]) {
+      for (const f of ["totalTime", "albumArtist", "artist", "id", "composer", "catalogue", "year", "kind"]) {
         if (item[f]) {
           common[f] = item[f];
         }
@@ -312,8 +312,10 @@ class TrackList extends React.Component {
     });
 
     const ols = [];
+    const listNumbers = this.props.listNumbers;
     const buildTrack = function(track) {
-      return <Track key={track.id} data={track} path={this.props.path.concat([track.key])} />;
+      const number = listNumbers ? listNumbers[parseInt(track.key)] : null;
+      return <Track key={track.id} data={track} path={this.props.path.concat([track.key])} number={number} />;
     }.bind(this);
 
     for (const discIndex of discIndices) {
@@ -337,6 +339,7 @@ TrackList.propTypes = {
   path: React.PropTypes.array.isRequired,
   list: React.PropTypes.array.isRequired,
   listStyle: React.PropTypes.string.isRequired,
+  listNumbers: React.PropTypes.array,
 };
 
 
@@ -389,8 +392,15 @@ class Track extends React.Component {
       );
     }
 
+    // Tracks are numbered by the list counter, which is reset when the enumeration isn't
+    // 1, 2, 3... (see index.TrimEnumPrefix).
+    let style = null;
+    if (this.props.number) {
+      style = {"counterReset": "li " + (this.props.number - 1)};
+    }
+
     return (
-      <li className={classNames(liClasses)} onClick={this._onClick}>
+      <li className={classNames(liClasses)} style={style} onClick={this._onClick}>
         <span id={`track_${this.props.data.id}`} className="name">{this.props.data.name}</span>
         <TimeFormatter className="duration" time={durationSecs} />
         <span className="controls">
@@ -433,4 +443,7 @@ class Track extends React.Component {
   }
 }
 
-Track.propTypes = {path: React.PropTypes.array.isRequired};
+Track.propTypes = {
+  path: React.PropTypes.array.isRequired,
+  number: React.PropTypes.number,
+};
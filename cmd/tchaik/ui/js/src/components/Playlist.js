@@ -224,7 +224,7 @@ class GroupContent extends React.Component {
     if (item.groups) {
       return <GroupList path={this.props.path} list={item.groups} itemIndex={this.props.itemIndex} keys={keys} />;
     }
-    return <TrackList path={this.props.path} list={item.tracks} listStyle={item.listStyle} itemIndex={this.props.itemIndex} keys={keys} />;
+    return <TrackList path={this.props.path} list={item.tracks} listStyle={item.listStyle} listNumbers={item.listNumbers} itemIndex={this.props.itemIndex} keys={keys} />;
   }
 
   _onChange(keyPath) {
@@ -343,7 +343,8 @@ class TrackList extends React.Component {
       const path = this.props.path.concat([i]);
       let isCurrent = this.state.hasCurrent && pathsEqual(path, currentPath);
 
-      return <Track key={list[i].id} data={list[i]} path={path} isCurrent={isCurrent} itemIndex={this.props.itemIndex} index={i} />;
+      const number = this.props.listNumbers ? this.props.listNumbers[i] : i + 1;
+      return <Track key={list[i].id} data={list[i]} path={path} isCurrent={isCurrent} itemIndex={this.props.itemIndex} index={i} number={number} />;
     }.bind(this));
 
     return (
@@ -366,6 +367,7 @@ TrackList.propTypes = {
   list: React.PropTypes.array.isRequired,
   keys: React.PropTypes.array.isRequired,
   listStyle: React.PropTypes.string.isRequired,
+  listNumbers: React.PropTypes.array,
 };
 
 function isPlaying(id) {
@@ -422,7 +424,7 @@ class Track extends React.Component {
     };
 
     return (
-      <li onClick={this._onClick} style={{"counterReset": "li " + (this.props.index + 1)}} className={classNames(style)}>
+      <li onClick={this._onClick} style={{"counterReset": "li " + this.props.number}} className={classNames(style)}>
         <span id={"track_" + this.props.data.id} className="name">{this.props.data.name}</span>
         <span className="info">
           <Icon icon="clear" onClick={this._onClickRemove} />
@@ -449,5 +451,6 @@ class Track extends React.Component {
 Track.propTypes = {
   itemIndex: React.PropTypes.number.isRequired,
   index: React.PropTypes.number.isRequired,
+  number: React.PropTypes.number.isRequired,
   path: React.PropTypes.array.isRequired,
 };
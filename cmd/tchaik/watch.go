@@ -0,0 +1,58 @@
+// Copyright 2015, David Howden
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/amiforus/tchaik/index"
+	"github.com/amiforus/tchaik/index/walk"
+)
+
+// watchLibrary watches the directory tree under path, and replaces the Library in l
+// whenever audio files are added, removed or modified.  Paths in m are migrated to the
+// new Library, unless most of it has been removed (i.e. when path is on a network share
+// which is briefly unavailable).
+func watchLibrary(l *liveLibrary, m *Meta, path string, poll time.Duration) error {
+	w, err := walk.Watch(path, poll)
+	if err != nil {
+		return err
+	}
+
+	go func() {
+		for range w.Changes {
+			fmt.Printf("Updating library from %v...", path)
+			old := l.Get()
+			nl, c := walk.Update(old.Library, path)
+			u := libraryUpdate{
+				Added:   len(c.Added),
+				Updated: len(c.Updated),
+				Removed: len(c.Removed),
+			}
+			if len(c.Failed) > 0 {
+				fmt.Printf("could not read %d file(s)...", len(c.Failed))
+			}
+			if u.Added+u.Updated+u.Removed == 0 {
+				fmt.Println("no changes.")
+				continue
+			}
+			fmt.Printf("added: %d, updated: %d, removed: %d.\n", u.Added, u.Updated, u.Removed)
+
+			lib := NewLibrary(index.Convert(nl, "ID"))
+			if 2*u.Removed > len(old.Tracks()) {
+				fmt.Println("most of the library was removed, not migrating metadata paths.")
+			} else {
+				// Paths which are no longer in old (i.e. kept from an earlier update which
+				// removed their tracks) are resolved again if they are in the new library.
+				if err := m.migrate(firstPathMap(lib.remap(old), lib.remap(lib))); err != nil {
+					fmt.Println(err)
+				}
+			}
+			l.Set(lib, u)
+		}
+	}()
+	return nil
+}
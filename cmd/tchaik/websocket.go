@@ -15,6 +15,7 @@ import (
 	"github.com/amiforus/tchaik/index"
 	"github.com/amiforus/tchaik/index/cursor"
 	"github.com/amiforus/tchaik/index/playlist"
+	"github.com/amiforus/tchaik/index/rating"
 	"github.com/amiforus/tchaik/player"
 )
 
@@ -122,10 +123,12 @@ const (
 	// Path Actions
 	ActionRecordPlay   = "RECORD_PLAY"
 	ActionSetFavourite = "SET_FAVOURITE"
+	ActionSetRating    = "SET_RATING"
 	ActionSetChecklist = "SET_CHECKLIST"
 
 	// Playlist Actions
-	ActionPlaylist = "PLAYLIST"
+	ActionPlaylist      = "PLAYLIST"
+	ActionPlaylistNames = "PLAYLIST_NAMES"
 
 	// Cursor Actions
 	ActionCursor = "CURSOR"
@@ -137,6 +140,9 @@ const (
 	ActionFilterList    = "FILTER_LIST"
 	ActionFilterPaths   = "FILTER_PATHS"
 	ActionFetchPathList = "FETCH_PATHLIST"
+
+	// ActionLibraryUpdate is sent to clients when the library has been updated.
+	ActionLibraryUpdate = "LIBRARY_UPDATE"
 )
 
 type websocketHandlerFunc func(c Command, r *Response) error
@@ -158,30 +164,41 @@ func (w *websocketMux) Handle(c Command, r *Response) error {
 }
 
 // NewWebsocketHandler creates a websocket handler for the library, players and history.
-func NewWebsocketHandler(l Library, m *Meta, p *player.Players) http.Handler {
+func NewWebsocketHandler(l *liveLibrary, m *Meta, p *player.Players) http.Handler {
 	return websocket.Handler(func(ws *websocket.Conn) {
 		defer ws.Close()
 		mux := &websocketMux{
 			m: make(map[string]websocketHandlerFunc),
 		}
 
+		updates := l.Subscribe()
+		defer l.Unsubscribe(updates)
+		go func() {
+			for u := range updates {
+				websocket.JSON.Send(ws, &Response{
+					Action: ActionLibraryUpdate,
+					Data:   u,
+				})
+			}
+		}()
+
 		h := &websocketHandler{
 			Conn:    ws,
 			mux:     mux,
-			lib:     l,
+			libs:    l,
 			meta:    m,
 			players: p,
-			searcher: &sameSearcher{
-				Searcher: l.searcher,
-			},
 		}
+		h.refreshLibrary()
 
 		mux.HandleFunc(ActionKey, h.key)
 		mux.HandleFunc(ActionPlayer, h.player)
 		mux.HandleFunc(ActionRecordPlay, h.recordPlay)
 		mux.HandleFunc(ActionSetFavourite, h.setFavourite)
+		mux.HandleFunc(ActionSetRating, h.setRating)
 		mux.HandleFunc(ActionSetChecklist, h.setChecklist)
 		mux.HandleFunc(ActionPlaylist, h.playlist)
+		mux.HandleFunc(ActionPlaylistNames, h.playlistNames)
 		mux.HandleFunc(ActionCursor, h.cursor)
 		mux.HandleFunc(ActionFetch, h.collectionList)
 		mux.HandleFunc(ActionSearch, h.search)
@@ -197,6 +214,7 @@ type websocketHandler struct {
 	*websocket.Conn
 	mux      *websocketMux
 	players  *player.Players
+	libs     *liveLibrary
 	lib      Library
 	searcher *sameSearcher
 	meta     *Meta
@@ -204,6 +222,18 @@ type websocketHandler struct {
 	playerKey string
 }
 
+// refreshLibrary updates the Library used by the handler to the current Library.  Must
+// only be called from the handler goroutine.
+func (h *websocketHandler) refreshLibrary() {
+	lib := h.libs.Get()
+	if h.searcher == nil || lib.searcher != h.lib.searcher {
+		h.searcher = &sameSearcher{
+			Searcher: lib.searcher,
+		}
+	}
+	h.lib = lib
+}
+
 func (h *websocketHandler) handle() {
 	defer h.players.Remove(h.playerKey)
 
@@ -218,6 +248,7 @@ func (h *websocketHandler) handle() {
 			break
 		}
 
+		h.refreshLibrary()
 		resp := &Response{
 			Action: c.Action,
 		}
@@ -311,6 +342,22 @@ func (h *websocketHandler) setFavourite(c Command, resp *Response) error {
 	return h.meta.favourites.Set(p, value)
 }
 
+func (h *websocketHandler) setRating(c Command, resp *Response) error {
+	p, err := c.getPath("path")
+	if err != nil {
+		return err
+	}
+	value, err := c.getInt("value")
+	if err != nil {
+		return err
+	}
+	v := rating.Value(value)
+	if value < 0 || !v.IsValid() {
+		return fmt.Errorf("invalid rating: %d", value)
+	}
+	return h.meta.ratings.Set(p, v)
+}
+
 func (h *websocketHandler) setChecklist(c Command, resp *Response) error {
 	p, err := c.getPath("path")
 	if err != nil {
@@ -336,17 +383,23 @@ func (h *websocketHandler) cursor(c Command, resp *Response) error {
 
 	if action != "FETCH" {
 		path, _ := c.getPath("path")
-		index, _ := c.getInt("index")
+		i, _ := c.getInt("index")
+		mode, _ := c.getString("mode")
 
 		ra := cursor.RepAction{
 			Name:   name,
 			Action: cursor.Action(action),
 			Path:   path,
-			Index:  index,
+			Index:  i,
+			Mode:   mode,
 		}
 
-		root := &rootCollection{h.lib.collections["Root"]}
-		err = ra.Apply(h.meta.cursors, h.meta.playlists, root)
+		if ra.Action == "SET" {
+			if err := h.updateSmartPlaylist(name); err != nil {
+				return err
+			}
+		}
+		err = ra.Apply(h.meta.cursors, h.meta.playlists, h.lib.rootCollection("Root"))
 		if err != nil {
 			return err
 		}
@@ -368,29 +421,78 @@ func (h *websocketHandler) playlist(c Command, resp *Response) error {
 	}
 
 	if action != "FETCH" {
-		path, err := c.getPath("path")
-		if err != nil {
-			return err
-		}
-		index, _ := c.getInt("index")
-
 		ra := playlist.RepAction{
 			Name:   name,
 			Action: playlist.Action(action),
-			Path:   path,
-			Index:  index,
 		}
 
+		if raw, ok := c.Data["smart"]; ok && ra.Action == playlist.ActionCreate {
+			ra.Smart, err = playlist.SmartFromJSONInterface(raw)
+			if err != nil {
+				return err
+			}
+		}
+		ra.Path, _ = c.getPath("path")
+		ra.Index, _ = c.getInt("index")
+		ra.To, _ = c.getInt("to")
+		ra.NewName, _ = c.getString("newName")
+
 		err = ra.Apply(h.meta.playlists)
 		if err != nil {
 			return err
 		}
+
+		// Cursors are named after their playlist.
+		switch action {
+		case "DELETE":
+			resp.Data = nil
+			return h.meta.cursors.Delete(name)
+
+		case "RENAME":
+			if cur := h.meta.cursors.Get(name); cur != nil {
+				if err := h.meta.cursors.Set(ra.NewName, cur); err != nil {
+					return err
+				}
+				if err := h.meta.cursors.Delete(name); err != nil {
+					return err
+				}
+			}
+			name = ra.NewName
+
+		case "DUPLICATE":
+			name = ra.NewName
+		}
 	}
 
+	err = h.updateSmartPlaylist(name)
+	if err != nil {
+		return err
+	}
 	resp.Data = h.meta.playlists.Get(name)
 	return nil
 }
 
+func (h *websocketHandler) playlistNames(c Command, resp *Response) error {
+	resp.Data = h.meta.playlists.Names()
+	return nil
+}
+
+// updateSmartPlaylist re-evaluates the playlist if it is a smart playlist, so that its items
+// reflect the current library and metadata.  The playlist is only replaced (and persisted)
+// when its items have changed.
+func (h *websocketHandler) updateSmartPlaylist(name string) error {
+	p := h.meta.playlists.Get(name)
+	if p == nil || p.Smart() == nil {
+		return nil
+	}
+	// p is shared with other connections, so the copy is updated and replaces it.
+	p = p.Copy()
+	if !p.Update(h.lib.playlistTracks(), h.meta.playlistSources()) {
+		return nil
+	}
+	return h.meta.playlists.Set(name, p)
+}
+
 func (h *websocketHandler) collectionList(c Command, resp *Response) error {
 	p, err := c.getPath("path")
 	if err != nil {
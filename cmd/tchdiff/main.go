@@ -0,0 +1,137 @@
+// Copyright 2015, David Howden
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+/*
+tchdiff is a tool that compares two Tchaik libraries (i.e. built from different sources), and can merge them into
+one.
+
+Tracks are matched by ID, and then by their normalised Name, Album, Artist and TrackNumber, so that tracks with
+different IDs in each library are still matched.  Tracks which are only in -b are listed as added (+), those
+only in -a as removed (-), and those in both with different attributes as changed (~):
+
+	tchdiff -a itunes.tch -b nas.tch
+
+Use -out to write a library containing the tracks of both, where the attributes of matched tracks are taken from
+the library given by -prefer (unless they are unset there).  The precedence of individual fields can be set
+using -prefer-fields:
+
+	tchdiff -a itunes.tch -b nas.tch -prefer a -prefer-fields Location=b,Kind=b,BitRate=b -out merged.tch
+
+All configuration is done through command line parameters, see --help flag for details.
+*/
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/amiforus/tchaik/index"
+	"github.com/amiforus/tchaik/index/merge"
+)
+
+var libA, libB string
+var prefer, preferFields string
+var out, format string
+
+func init() {
+	flag.StringVar(&libA, "a", "", "first Tchaik library `file`")
+	flag.StringVar(&libB, "b", "", "second Tchaik library `file`")
+
+	flag.StringVar(&prefer, "prefer", "a", "`library` (a or b) which takes precedence when merging")
+	flag.StringVar(&preferFields, "prefer-fields", "", "comma separated `list` of field=library pairs which override -prefer")
+
+	flag.StringVar(&out, "out", "", "write a merged Tchaik library to `file`")
+	flag.StringVar(&format, "format", "json", "output library `format`: json (gzipped-JSON) or binary")
+}
+
+func main() {
+	flag.Parse()
+
+	if libA == "" || libB == "" {
+		fmt.Println("must specify two library files (-a and -b), see -help for more details")
+		os.Exit(1)
+	}
+
+	if format != "json" && format != "binary" {
+		fmt.Println("-format must be either json or binary, see -help for more details")
+		os.Exit(1)
+	}
+
+	side, err := merge.ParseSide(prefer)
+	if err != nil {
+		fmt.Printf("invalid -prefer: %v\n", err)
+		os.Exit(1)
+	}
+
+	p, err := merge.ParsePrecedence(side, preferFields)
+	if err != nil {
+		fmt.Printf("invalid -prefer-fields: %v\n", err)
+		os.Exit(1)
+	}
+
+	a, err := readLibrary(libA)
+	if err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
+
+	b, err := readLibrary(libB)
+	if err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
+
+	d := merge.Compare(a, b)
+	for _, t := range d.Added {
+		fmt.Printf("+ %v: '%v'\n", t.GetString("ID"), t.GetString("Location"))
+	}
+	for _, t := range d.Removed {
+		fmt.Printf("- %v: '%v'\n", t.GetString("ID"), t.GetString("Location"))
+	}
+	for _, c := range d.Changed {
+		id := c.A.GetString("ID")
+		if idB := c.B.GetString("ID"); idB != id {
+			id += " -> " + idB
+		}
+		fmt.Printf("~ %v: '%v' (%v)\n", id, c.A.GetString("Location"), strings.Join(c.Fields, ", "))
+	}
+	fmt.Printf("Completed: %d added, %d removed, %d changed.\n", len(d.Added), len(d.Removed), len(d.Changed))
+
+	if out != "" {
+		err = writeLibrary(index.Convert(merge.Merge(d, p), "ID"))
+		if err != nil {
+			fmt.Printf("error writing library: %v\n", err)
+			os.Exit(1)
+		}
+	}
+}
+
+func writeLibrary(l index.Library) error {
+	f, err := os.Create(out)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	if format == "binary" {
+		return index.WriteBinaryTo(l, f)
+	}
+	return index.WriteTo(l, f)
+}
+
+func readLibrary(path string) (index.Library, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("could not open Tchaik library file: %v", err)
+	}
+	defer f.Close()
+
+	l, err := index.ReadFrom(f)
+	if err != nil {
+		return nil, fmt.Errorf("error parsing Tchaik library file '%v': %v", path, err)
+	}
+	return l, nil
+}
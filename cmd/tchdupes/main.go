@@ -0,0 +1,214 @@
+// Copyright 2015, David Howden
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+/*
+tchdupes is a tool that finds duplicate tracks in an index (i.e. the same recording ripped more than once in
+different formats), and suggests which copy of each to keep.
+
+Tracks are duplicates if their normalised Name, Album, Artist and TrackNumber are the same and their durations are
+within -tolerance of each other.  With -hash the audio in each file is also hashed (ignoring tags), so that copies
+of the same file with different tags are found too.  Lossless files are preferred, then higher bit rates.
+
+	tchdupes -lib lib.tch
+
+A library without the non-preferred copies can be written using -out:
+
+	tchdupes -lib lib.tch -out dedup.tch
+
+All configuration is done through command line parameters, see --help flag for details.
+*/
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/dhowden/tag"
+
+	"github.com/amiforus/tchaik/index"
+	"github.com/amiforus/tchaik/index/dupes"
+	"github.com/amiforus/tchaik/index/itl"
+)
+
+var itlXML, tchLib string
+var trimPathPrefix, addPathPrefix string
+var tolerance time.Duration
+var hash bool
+var out, format string
+
+func init() {
+	flag.StringVar(&itlXML, "itlXML", "", "iTunes Library XML `file`")
+	flag.StringVar(&tchLib, "lib", "", "Tchaik library `file`")
+
+	flag.StringVar(&trimPathPrefix, "trim-path-prefix", "", "remove `prefix` from every path")
+	flag.StringVar(&addPathPrefix, "add-path-prefix", "", "add `prefix` to every path")
+
+	flag.DurationVar(&tolerance, "tolerance", 2*time.Second, "maximum difference in duration between duplicates")
+	flag.BoolVar(&hash, "hash", false, "also find duplicates by hashing the audio in each file (ignoring tags)")
+
+	flag.StringVar(&out, "out", "", "write a Tchaik library `file` without the non-preferred copies")
+	flag.StringVar(&format, "format", "json", "output library `format`: json (gzipped-JSON) or binary")
+}
+
+var workers = 4
+
+func main() {
+	flag.Parse()
+
+	if format != "json" && format != "binary" {
+		fmt.Println("-format must be either json or binary, see -help for more details")
+		os.Exit(1)
+	}
+
+	l, err := readLibrary()
+	if err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
+
+	tracks := l.Tracks()
+	fmt.Printf("Checking %d tracks...\n", len(tracks))
+
+	var sums map[string]string
+	if hash {
+		sums = sumTracks(tracks)
+	}
+
+	clusters := dupes.Find(tracks, tolerance, sums)
+	var n int
+	for _, c := range clusters {
+		printCluster(c)
+		n += len(c.Remove())
+	}
+	fmt.Printf("Completed: %d duplicate(s) found, %d track(s) could be removed.\n", len(clusters), n)
+
+	if out != "" {
+		err = writeLibrary(index.Convert(dupes.Filter(l, clusters), "ID"))
+		if err != nil {
+			fmt.Println(err)
+			os.Exit(1)
+		}
+	}
+}
+
+func printCluster(c dupes.Cluster) {
+	k := c.Keep()
+	fmt.Printf("\n%v - %v - %v\n", k.GetString("Artist"), k.GetString("Album"), k.GetString("Name"))
+	printTrack("keep", k)
+	for _, t := range c.Remove() {
+		printTrack("remove", t)
+	}
+}
+
+func printTrack(action string, t index.Track) {
+	fmt.Printf("  %-6v  %v, %dkbps, %v  %v\n", action, t.GetString("Kind"), t.GetInt("BitRate"),
+		time.Duration(t.GetInt("TotalTime"))*time.Millisecond, t.GetString("Location"))
+}
+
+// sumTracks returns a map of track ID -> hash of the audio in the track's file, using a pool
+// of workers.  Any errors are logged to stdout.
+func sumTracks(tracks []index.Track) map[string]string {
+	ch := make(chan index.Track)
+	go func() {
+		for _, t := range tracks {
+			ch <- t
+		}
+		close(ch)
+	}()
+
+	var mu sync.Mutex
+	sums := make(map[string]string, len(tracks))
+
+	wg := &sync.WaitGroup{}
+	wg.Add(workers)
+	for i := 0; i < workers; i++ {
+		go func() {
+			defer wg.Done()
+			for t := range ch {
+				loc := rewritePath(t.GetString("Location"))
+				s, err := sumFile(loc)
+				if err != nil {
+					fmt.Printf("could not hash file '%v': %v\n", loc, err)
+					continue
+				}
+				mu.Lock()
+				sums[t.GetString("ID")] = s
+				mu.Unlock()
+			}
+		}()
+	}
+	wg.Wait()
+	return sums
+}
+
+func sumFile(path string) (string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+	return tag.Sum(f)
+}
+
+func rewritePath(path string) string {
+	if trimPathPrefix != "" {
+		path = strings.TrimPrefix(path, trimPathPrefix)
+	}
+	if addPathPrefix != "" {
+		path = addPathPrefix + path
+	}
+	return path
+}
+
+func writeLibrary(l index.Library) error {
+	f, err := os.Create(out)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	if format == "binary" {
+		return index.WriteBinaryTo(l, f)
+	}
+	return index.WriteTo(l, f)
+}
+
+func readLibrary() (index.Library, error) {
+	if itlXML == "" && tchLib == "" {
+		return nil, fmt.Errorf("must specify one library file (-itlXML or -lib)")
+	}
+
+	if itlXML != "" && tchLib != "" {
+		return nil, fmt.Errorf("must only specify one library file (-itlXML or -lib)")
+	}
+
+	if itlXML != "" {
+		f, err := os.Open(itlXML)
+		if err != nil {
+			return nil, fmt.Errorf("could not open iTunes library file: %v", err)
+		}
+		defer f.Close()
+		il, err := itl.ReadFrom(f)
+		if err != nil {
+			return nil, fmt.Errorf("error parsing iTunes library file: %v", err)
+		}
+		return index.Convert(il, "ID"), nil
+	}
+
+	f, err := os.Open(tchLib)
+	if err != nil {
+		return nil, fmt.Errorf("could not open Tchaik library file: %v", err)
+	}
+	defer f.Close()
+
+	l, err := index.ReadFrom(f)
+	if err != nil {
+		return nil, fmt.Errorf("error parsing Tchaik library file: %v", err)
+	}
+	return l, nil
+}
@@ -11,12 +11,36 @@ for each track (so a much smaller memory footprint).
 
   tchimport -itlXML <itunes-library> -out lib.tch
 
+Playlists in the iTunes Library can also be added to a Tchaik playlists file using -playlists (folder names are
+kept as prefixes of playlist names, smart playlists are skipped):
+
+  tchimport -itlXML <itunes-library> -out lib.tch -playlists playlists.json
+
 Alternatively you can specify a path which will be transversed. All supported audio files within this path
 (.mp3, .m4a, .flac - ID3.v1,2.{2,3,4}, MP4 and FLAC) will be scanned for metadata. Only tracks which have readable
 metadata will be added to the library.  Any errors are logged to stdout. As no other unique identifying data is know,
 the SHA1 sum of the file path is used as the ID.
 
   tchimport -path <directory-path> -out lib.tch
+
+To avoid re-reading every file when rebuilding a library from a directory tree, pass an existing Tchaik library
+using -lib.  Only files which are new, or whose modification time differs from the library entry, are re-read and
+tracks whose files no longer exist are removed.  A summary of added, updated and removed tracks is printed on
+completion.
+
+  tchimport -path <directory-path> -lib lib.tch -out lib.tch
+
+Libraries are written as gzipped-JSON by default.  Use -format binary to write the binary library format instead,
+which is more compact and much faster to load (tchaik detects the format of the library given to -lib):
+
+  tchimport -itlXML <itunes-library> -out lib.tch -format binary
+
+Libraries can also be imported from Rhythmbox (-rhythmbox rhythmdb.xml), Clementine or Strawberry
+(-clementine clementine.db) and beets (-beets library.db).  Play counts and ratings from these libraries can be
+added to Tchaik play history and ratings files using -play-history and -ratings (tracks which already have play
+history or a rating are left unchanged):
+
+  tchimport -rhythmbox rhythmdb.xml -out lib.tch -play-history history.json -ratings ratings.json
 */
 package main
 
@@ -26,24 +50,58 @@ import (
 	"os"
 
 	"github.com/amiforus/tchaik/index"
+	"github.com/amiforus/tchaik/index/beets"
+	"github.com/amiforus/tchaik/index/clementine"
+	"github.com/amiforus/tchaik/index/history"
 	"github.com/amiforus/tchaik/index/itl"
+	"github.com/amiforus/tchaik/index/migrate"
+	"github.com/amiforus/tchaik/index/playlist"
+	"github.com/amiforus/tchaik/index/rating"
+	"github.com/amiforus/tchaik/index/rhythmbox"
 	"github.com/amiforus/tchaik/index/walk"
 )
 
 var itlXML, path string
-var out string
+var rhythmboxXML, clementineDB, beetsDB string
+var playHistoryPath, ratingsPath string
+var tchLib string
+var out, format, playlistsPath string
+var verbose bool
+var albumGroupingName string
+var albumGrouping index.AlbumGrouping
 
 func init() {
 	flag.StringVar(&itlXML, "itlXML", "", "iTunes Music Library XML `file`")
 	flag.StringVar(&path, "path", "", "`directory` containing music files")
-	flag.StringVar(&out, "out", "", "output `file` (Tchaik library binary format)")
+	flag.StringVar(&tchLib, "lib", "", "existing Tchaik library `file` to update (requires -path)")
+	flag.StringVar(&out, "out", "", "output Tchaik library `file`")
+	flag.StringVar(&format, "format", "json", "output library `format`: json (gzipped-JSON) or binary")
+	flag.StringVar(&playlistsPath, "playlists", "", "playlists `file` to add the iTunes Library playlists to (requires -itlXML)")
+	flag.StringVar(&rhythmboxXML, "rhythmbox", "", "Rhythmbox database `file` (rhythmdb.xml)")
+	flag.StringVar(&clementineDB, "clementine", "", "Clementine or Strawberry database `file`")
+	flag.StringVar(&beetsDB, "beets", "", "beets database `file` (library.db)")
+	flag.StringVar(&playHistoryPath, "play-history", "", "play history `file` to add play counts to (requires -rhythmbox, -clementine or -beets)")
+	flag.StringVar(&ratingsPath, "ratings", "", "ratings `file` to add ratings to (requires -rhythmbox, -clementine or -beets)")
+	flag.StringVar(&albumGroupingName, "group-albums", "album", "`rule` for grouping tracks into albums when adding playlists, play counts and ratings (must match tchaik -group-albums): album, artist or folder")
+	flag.BoolVar(&verbose, "v", false, "list the location of each added, updated and removed track (requires -lib)")
 }
 
 func main() {
 	flag.Parse()
 
-	if itlXML != "" && path != "" || itlXML == "" && path == "" {
-		fmt.Println("must specify either -itlXML or -path, see -help for more details")
+	sources := 0
+	for _, x := range []string{itlXML, path, rhythmboxXML, clementineDB, beetsDB} {
+		if x != "" {
+			sources++
+		}
+	}
+	if sources != 1 {
+		fmt.Println("must specify one of -itlXML, -path, -rhythmbox, -clementine or -beets, see -help for more details")
+		os.Exit(1)
+	}
+
+	if tchLib != "" && path == "" {
+		fmt.Println("must specify -path when using -lib, see -help for more details")
 		os.Exit(1)
 	}
 
@@ -52,13 +110,46 @@ func main() {
 		os.Exit(1)
 	}
 
-	var l index.Library
+	if format != "json" && format != "binary" {
+		fmt.Println("-format must be either json or binary, see -help for more details")
+		os.Exit(1)
+	}
+
 	var err error
+	albumGrouping, err = index.ParseAlbumGrouping(albumGroupingName)
+	if err != nil {
+		fmt.Printf("%v, see -help for more details\n", err)
+		os.Exit(1)
+	}
+
+	if playlistsPath != "" && itlXML == "" {
+		fmt.Println("must specify -itlXML when using -playlists, see -help for more details")
+		os.Exit(1)
+	}
+
+	if (playHistoryPath != "" || ratingsPath != "") && rhythmboxXML == "" && clementineDB == "" && beetsDB == "" {
+		fmt.Println("must specify -rhythmbox, -clementine or -beets when using -play-history or -ratings, see -help for more details")
+		os.Exit(1)
+	}
+
+	var l index.Library
+	var m *migrate.Library
 	switch {
 	case itlXML != "":
 		l, err = importXML(itlXML)
+	case tchLib != "":
+		l, err = updateLibrary(tchLib, path)
 	case path != "":
 		l = walk.NewLibrary(path)
+	case rhythmboxXML != "":
+		m, err = importRhythmbox(rhythmboxXML)
+	case clementineDB != "":
+		m, err = clementine.Open(clementineDB)
+	case beetsDB != "":
+		m, err = beets.Open(beetsDB)
+	}
+	if m != nil {
+		l = m
 	}
 
 	if err != nil {
@@ -66,19 +157,95 @@ func main() {
 		os.Exit(1)
 	}
 
-	err = writeLibrary(index.Convert(l, "ID"))
+	lib := index.Convert(l, "ID")
+	if playlistsPath != "" {
+		err = importPlaylists(l, lib)
+		if err != nil {
+			fmt.Println(err)
+			os.Exit(1)
+		}
+	}
+
+	if m != nil && (playHistoryPath != "" || ratingsPath != "") {
+		err = importStats(m, lib)
+		if err != nil {
+			fmt.Println(err)
+			os.Exit(1)
+		}
+	}
+
+	err = writeLibrary(lib)
 	if err != nil {
 		fmt.Println(err)
 		os.Exit(1)
 	}
 }
 
+// importStats adds the play counts and ratings from m to the play history and ratings files,
+// using the converted library lib to find the tracks.
+func importStats(m *migrate.Library, lib index.Library) error {
+	var h history.Store
+	var r rating.Store
+	var err error
+	if playHistoryPath != "" {
+		h, err = history.NewStore(playHistoryPath)
+		if err != nil {
+			return fmt.Errorf("error loading play history: %v", err)
+		}
+	}
+	if ratingsPath != "" {
+		r, err = rating.NewStore(ratingsPath)
+		if err != nil {
+			return fmt.Errorf("error loading ratings: %v", err)
+		}
+	}
+
+	plays, ratings, err := migrate.Import(m, &index.RootCollection{Collection: index.CollectAlbums(lib, albumGrouping, index.DefaultCollation)}, h, r)
+	if err != nil {
+		return fmt.Errorf("error importing play counts and ratings: %v", err)
+	}
+	if playHistoryPath != "" {
+		fmt.Printf("Added play counts for %d track(s).\n", plays)
+	}
+	if ratingsPath != "" {
+		fmt.Printf("Added ratings for %d track(s).\n", ratings)
+	}
+	return nil
+}
+
+// importPlaylists adds the playlists from the iTunes Library l to the playlist store, using
+// the converted library lib to find the tracks.
+func importPlaylists(l, lib index.Library) error {
+	ps, smart, err := itl.Playlists(l)
+	if err != nil {
+		return err
+	}
+	for _, n := range smart {
+		fmt.Printf("skipping smart playlist: '%v'\n", n)
+	}
+
+	s, err := playlist.NewStore(playlistsPath)
+	if err != nil {
+		return fmt.Errorf("error loading playlists: %v", err)
+	}
+	added, err := itl.ImportPlaylists(ps, s, &index.RootCollection{Collection: index.CollectAlbums(lib, albumGrouping, index.DefaultCollation)})
+	if err != nil {
+		return fmt.Errorf("error adding playlists: %v", err)
+	}
+	fmt.Printf("Added %d playlist(s).\n", len(added))
+	return nil
+}
+
 func writeLibrary(l index.Library) error {
 	f, err := os.Create(out)
 	if err != nil {
 		return err
 	}
 	defer f.Close()
+
+	if format == "binary" {
+		return index.WriteBinaryTo(l, f)
+	}
 	return index.WriteTo(l, f)
 }
 
@@ -95,3 +262,48 @@ func importXML(itlXML string) (index.Library, error) {
 	}
 	return l, nil
 }
+
+func importRhythmbox(rhythmboxXML string) (*migrate.Library, error) {
+	f, err := os.Open(rhythmboxXML)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return rhythmbox.ReadFrom(f)
+}
+
+func updateLibrary(tchLib, path string) (index.Library, error) {
+	f, err := os.Open(tchLib)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	l, err := index.ReadFrom(f)
+	if err != nil {
+		return nil, fmt.Errorf("error parsing Tchaik library file: %v", err)
+	}
+
+	l, c := walk.Update(l, path)
+	printChanges(c)
+	return l, nil
+}
+
+func printChanges(c walk.Changes) {
+	if verbose {
+		for _, x := range []struct {
+			prefix string
+			paths  []string
+		}{
+			{"+", c.Added},
+			{"~", c.Updated},
+			{"-", c.Removed},
+			{"!", c.Failed},
+		} {
+			for _, p := range x.paths {
+				fmt.Println(x.prefix, p)
+			}
+		}
+	}
+	fmt.Printf("Added: %d, Updated: %d, Removed: %d, Failed: %d\n", len(c.Added), len(c.Updated), len(c.Removed), len(c.Failed))
+}
@@ -3,73 +3,157 @@
 // license that can be found in the LICENSE file.
 
 /*
-tchverify is a tool that verifies the "Location" field of tracks in an index by checking that
-the associated media file exists.
+tchverify is a tool that verifies the tracks in an index by checking that the associated media files exist in the
+media store (set using the same store flags as tchaik: -local-store, -remote-store etc), that their metadata can be
+read, and that their duration and tags match the index.
 
-All configuration is done through command line parameters, see --help flag for details.
+	tchverify -lib lib.tch -local-store /music
+
+Use -report to write a JSON report of every problem found.
+
+Files which can't be found can be searched for by name under a directory of the store using -search.  Use -out to
+write a new library with the Location of each track which was found in exactly one place corrected:
 
-NB: this tool cannot verify the content of remote stores, it only supports local stores.
+	tchverify -lib lib.tch -search /music -out fixed.tch
+
+NB: searching requires a store which can list directories (i.e. a local store).
+
+All configuration is done through command line parameters, see --help flag for details.
 */
 package main
 
 import (
+	"encoding/json"
 	"flag"
 	"fmt"
 	"os"
-	"strings"
+	"time"
+
+	"golang.org/x/net/context"
 
 	"github.com/amiforus/tchaik/index"
 	"github.com/amiforus/tchaik/index/itl"
+	"github.com/amiforus/tchaik/store/cmdflag"
 )
 
 var itlXML, tchLib string
-var trimPathPrefix, addPathPrefix string
+var workers int
+var tolerance time.Duration
+var reportPath, searchRoot string
+var out, format string
 
 func init() {
 	flag.StringVar(&itlXML, "itlXML", "", "iTunes Library XML `file`")
 	flag.StringVar(&tchLib, "lib", "", "Tchaik library `file`")
 
-	flag.StringVar(&trimPathPrefix, "trim-path-prefix", "", "remove `prefix` from every path")
-	flag.StringVar(&addPathPrefix, "add-path-prefix", "", "add `prefix` to every path")
+	flag.IntVar(&workers, "workers", 4, "`number` of tracks to verify concurrently")
+	flag.DurationVar(&tolerance, "tolerance", 2*time.Second, "maximum difference between the duration of a file and its track")
+
+	flag.StringVar(&reportPath, "report", "", "write a JSON report of all problems to `file`")
+	flag.StringVar(&searchRoot, "search", "", "search for missing files by name under `directory` of the store")
+	flag.StringVar(&out, "out", "", "write a Tchaik library `file` with corrected locations (requires -search)")
+	flag.StringVar(&format, "format", "json", "output library `format`: json (gzipped-JSON) or binary")
 }
 
 func main() {
 	flag.Parse()
 
+	if out != "" && searchRoot == "" {
+		fmt.Println("must specify -search when using -out, see -help for more details")
+		os.Exit(1)
+	}
+
+	if format != "json" && format != "binary" {
+		fmt.Println("-format must be either json or binary, see -help for more details")
+		os.Exit(1)
+	}
+
+	if workers < 1 {
+		workers = 1
+	}
+
 	l, err := readLibrary()
 	if err != nil {
 		fmt.Println(err)
 		os.Exit(1)
 	}
 
+	mediaFileSystem, _, err := cmdflag.Stores()
+	if err != nil {
+		fmt.Println("error setting up stores:", err)
+		os.Exit(1)
+	}
+
+	ctx := context.Background()
+	v := &verifier{
+		fs:        mediaFileSystem,
+		tolerance: tolerance,
+	}
+
 	tracks := l.Tracks()
 	fmt.Printf("Checking %d tracks...\n", len(tracks))
+	r := v.verifyAll(ctx, tracks, workers)
 
-	var errCount int
-	for _, t := range l.Tracks() {
-		loc := t.GetString("Location")
-		loc = rewritePath(loc)
-
-		if _, err := os.Stat(loc); err != nil {
-			errCount++
-			if os.IsNotExist(err) {
-				fmt.Printf("file not found: '%v'\n", loc)
-				continue
-			}
-			fmt.Printf("could not stat file '%v': %v\n", loc, err)
+	if searchRoot != "" {
+		fmt.Printf("Searching for missing files in %v...\n", searchRoot)
+		err = search(ctx, mediaFileSystem, searchRoot, r.Problems)
+		if err != nil {
+			fmt.Printf("error searching for missing files: %v\n", err)
+			os.Exit(1)
+		}
+	}
+
+	for _, p := range r.Problems {
+		fmt.Println(p)
+	}
+
+	locs := corrections(r.Problems)
+	r.Fixed = len(locs)
+	fmt.Printf("Completed: %d error(s), %d location(s) found.\n", len(r.Problems), r.Fixed)
+
+	if reportPath != "" {
+		err = writeReport(r)
+		if err != nil {
+			fmt.Printf("error writing report: %v\n", err)
+			os.Exit(1)
+		}
+	}
+
+	if out != "" {
+		err = writeLibrary(index.Convert(&locationLibrary{l, locs}, "ID"))
+		if err != nil {
+			fmt.Printf("error writing library: %v\n", err)
+			os.Exit(1)
 		}
 	}
-	fmt.Printf("Completed: %d error(s).\n", errCount)
 }
 
-func rewritePath(path string) string {
-	if trimPathPrefix != "" {
-		path = strings.TrimPrefix(path, trimPathPrefix)
+func writeReport(r *report) error {
+	f, err := os.Create(reportPath)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	b, err := json.MarshalIndent(r, "", "  ")
+	if err != nil {
+		return err
 	}
-	if addPathPrefix != "" {
-		path = addPathPrefix + path
+	_, err = f.Write(b)
+	return err
+}
+
+func writeLibrary(l index.Library) error {
+	f, err := os.Create(out)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	if format == "binary" {
+		return index.WriteBinaryTo(l, f)
 	}
-	return path
+	return index.WriteTo(l, f)
 }
 
 func readLibrary() (index.Library, error) {
@@ -85,7 +169,7 @@ func readLibrary() (index.Library, error) {
 	if itlXML != "" {
 		f, err := os.Open(itlXML)
 		if err != nil {
-			return nil, fmt.Errorf("could open iTunes library file: %v", err)
+			return nil, fmt.Errorf("could not open iTunes library file: %v", err)
 		}
 		defer f.Close()
 		il, err := itl.ReadFrom(f)
@@ -105,7 +189,7 @@ func readLibrary() (index.Library, error) {
 
 	l, err = index.ReadFrom(f)
 	if err != nil {
-		return nil, fmt.Errorf("error parsing Tchaik library file: %v\n", err)
+		return nil, fmt.Errorf("error parsing Tchaik library file: %v", err)
 	}
 	return l, nil
 }
@@ -0,0 +1,256 @@
+// Copyright 2015, David Howden
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"fmt"
+	"os"
+	"path"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/dhowden/tag"
+	"golang.org/x/net/context"
+
+	"github.com/amiforus/tchaik/index"
+	"github.com/amiforus/tchaik/index/walk"
+	"github.com/amiforus/tchaik/store"
+)
+
+// Names of the checks made on each track.
+const (
+	checkMissing  = "missing"  // the file does not exist
+	checkOpen     = "open"     // the file could not be opened
+	checkParse    = "parse"    // the metadata in the file could not be read
+	checkAudio    = "audio"    // the audio properties of the file could not be read
+	checkDuration = "duration" // the duration of the file doesn't match the track
+	checkTag      = "tag"      // a tag in the file doesn't match the track
+)
+
+// problem is a problem found when verifying a track.
+type problem struct {
+	ID       string   `json:"id"`
+	Location string   `json:"location"`
+	Check    string   `json:"check"`
+	Message  string   `json:"message"`
+	Found    []string `json:"found,omitempty"` // locations of files with the same name (see search)
+}
+
+func (p *problem) String() string {
+	s := fmt.Sprintf("%v: '%v': %v", p.Check, p.Location, p.Message)
+	switch len(p.Found) {
+	case 0:
+	case 1:
+		s += fmt.Sprintf(" (found: '%v')", p.Found[0])
+	default:
+		s += fmt.Sprintf(" (found %d files with the same name)", len(p.Found))
+	}
+	return s
+}
+
+// report is the result of verifying a library.
+type report struct {
+	Tracks   int        `json:"tracks"`
+	Problems []*problem `json:"problems"`
+	Fixed    int        `json:"fixed"` // number of corrected locations
+}
+
+// verifier checks tracks against the files in a store.
+type verifier struct {
+	fs        store.FileSystem
+	tolerance time.Duration
+}
+
+// verifyAll verifies tracks using n concurrent workers.  Problems in the returned report
+// are ordered by location.
+func (v *verifier) verifyAll(ctx context.Context, tracks []index.Track, n int) *report {
+	ch := make(chan index.Track)
+	go func() {
+		for _, t := range tracks {
+			ch <- t
+		}
+		close(ch)
+	}()
+
+	r := &report{
+		Tracks:   len(tracks),
+		Problems: []*problem{},
+	}
+	var mu sync.Mutex
+
+	wg := &sync.WaitGroup{}
+	wg.Add(n)
+	for i := 0; i < n; i++ {
+		go func() {
+			defer wg.Done()
+			for t := range ch {
+				ps := v.verify(ctx, t)
+				mu.Lock()
+				r.Problems = append(r.Problems, ps...)
+				mu.Unlock()
+			}
+		}()
+	}
+	wg.Wait()
+
+	sort.Sort(byLocation(r.Problems))
+	return r
+}
+
+type byLocation []*problem
+
+func (p byLocation) Len() int      { return len(p) }
+func (p byLocation) Swap(i, j int) { p[i], p[j] = p[j], p[i] }
+func (p byLocation) Less(i, j int) bool {
+	if p[i].Location == p[j].Location {
+		return p[i].Check < p[j].Check
+	}
+	return p[i].Location < p[j].Location
+}
+
+// verify checks the file of track t, and returns any problems found.
+func (v *verifier) verify(ctx context.Context, t index.Track) []*problem {
+	loc := t.GetString("Location")
+	var ps []*problem
+	add := func(check, format string, args ...interface{}) {
+		ps = append(ps, &problem{
+			ID:       t.GetString("ID"),
+			Location: loc,
+			Check:    check,
+			Message:  fmt.Sprintf(format, args...),
+		})
+	}
+
+	f, err := v.fs.Open(ctx, loc)
+	if err != nil {
+		if os.IsNotExist(err) {
+			add(checkMissing, "file not found")
+			return ps
+		}
+		add(checkOpen, "could not open file: %v", err)
+		return ps
+	}
+	defer f.Close()
+
+	m, err := tag.ReadFrom(f)
+	if err != nil {
+		add(checkParse, "could not read metadata: %v", err)
+		return ps
+	}
+
+	for _, x := range []struct {
+		field, value string
+	}{
+		{"Name", m.Title()},
+		{"Album", m.Album()},
+		{"Artist", m.Artist()},
+	} {
+		got, expected := index.Normalise(x.value), index.Normalise(t.GetString(x.field))
+		if got != "" && expected != "" && got != expected {
+			add(checkTag, "%v is '%v' in file, expected '%v'", x.field, x.value, t.GetString(x.field))
+		}
+	}
+	if n, _ := m.Track(); n != 0 && t.GetInt("TrackNumber") != 0 && n != t.GetInt("TrackNumber") {
+		add(checkTag, "TrackNumber is %d in file, expected %d", n, t.GetInt("TrackNumber"))
+	}
+
+	stat, err := f.Stat()
+	if err != nil {
+		add(checkOpen, "could not stat file: %v", err)
+		return ps
+	}
+
+	d, err := walk.ReadDuration(f, m.FileType(), stat.Size())
+	if err != nil {
+		add(checkAudio, "could not read audio properties: %v", err)
+		return ps
+	}
+	if total := time.Duration(t.GetInt("TotalTime")) * time.Millisecond; total != 0 {
+		if diff := d - total; diff > v.tolerance || -diff > v.tolerance {
+			add(checkDuration, "duration is %v in file, expected %v", d, total)
+		}
+	}
+	return ps
+}
+
+// search looks for files with the same name as missing files in the tree rooted at root in fs,
+// and sets the Found field of their problems.
+func search(ctx context.Context, fs store.FileSystem, root string, problems []*problem) error {
+	missing := make(map[string][]*problem)
+	for _, p := range problems {
+		if p.Check == checkMissing {
+			name := path.Base(p.Location)
+			missing[name] = append(missing[name], p)
+		}
+	}
+	if len(missing) == 0 {
+		return nil
+	}
+
+	return store.Walk(ctx, fs, root, func(p string, info os.FileInfo) error {
+		for _, x := range missing[info.Name()] {
+			x.Found = append(x.Found, p)
+		}
+		return nil
+	})
+}
+
+// corrections returns a map of track ID -> corrected location for each missing file which was
+// found in exactly one place.
+func corrections(problems []*problem) map[string]string {
+	locs := make(map[string]string)
+	for _, p := range problems {
+		if p.Check == checkMissing && len(p.Found) == 1 {
+			locs[p.ID] = p.Found[0]
+		}
+	}
+	return locs
+}
+
+// locationLibrary is an index.Library which replaces the Location of tracks.
+type locationLibrary struct {
+	index.Library
+	locs map[string]string // track ID -> Location
+}
+
+func (l *locationLibrary) track(t index.Track) index.Track {
+	if loc, ok := l.locs[t.GetString("ID")]; ok {
+		return &locationTrack{t, loc}
+	}
+	return t
+}
+
+// Tracks implements index.Library.
+func (l *locationLibrary) Tracks() []index.Track {
+	tracks := l.Library.Tracks()
+	for i, t := range tracks {
+		tracks[i] = l.track(t)
+	}
+	return tracks
+}
+
+// Track implements index.Library.
+func (l *locationLibrary) Track(id string) (index.Track, bool) {
+	t, ok := l.Library.Track(id)
+	if !ok {
+		return nil, false
+	}
+	return l.track(t), true
+}
+
+// locationTrack is an index.Track with a replaced Location.
+type locationTrack struct {
+	index.Track
+	location string
+}
+
+// GetString implements index.Track.
+func (t *locationTrack) GetString(name string) string {
+	if name == "Location" {
+		return t.location
+	}
+	return t.Track.GetString(name)
+}
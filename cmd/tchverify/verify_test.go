@@ -0,0 +1,264 @@
+// Copyright 2015, David Howden
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"bytes"
+	"encoding/binary"
+	"net/http"
+	"os"
+	"path"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	"golang.org/x/net/context"
+
+	"github.com/amiforus/tchaik/index"
+)
+
+// memFileSystem is an in-memory store.FileSystem of path -> file contents.  Directories are
+// implied by the paths of the files.
+type memFileSystem map[string][]byte
+
+// Open implements store.FileSystem.
+func (fs memFileSystem) Open(ctx context.Context, p string) (http.File, error) {
+	if b, ok := fs[p]; ok {
+		return &memFile{Reader: bytes.NewReader(b), info: memFileInfo{name: path.Base(p), size: int64(len(b))}}, nil
+	}
+
+	dir := strings.TrimSuffix(p, "/") + "/"
+	seen := make(map[string]bool)
+	var infos []os.FileInfo
+	for k, b := range fs {
+		if !strings.HasPrefix(k, dir) {
+			continue
+		}
+		name := strings.SplitN(strings.TrimPrefix(k, dir), "/", 2)[0]
+		if seen[name] {
+			continue
+		}
+		seen[name] = true
+		isDir := strings.Contains(strings.TrimPrefix(k, dir), "/")
+		infos = append(infos, memFileInfo{name: name, size: int64(len(b)), dir: isDir})
+	}
+	if infos == nil {
+		return nil, &os.PathError{Op: "open", Path: p, Err: os.ErrNotExist}
+	}
+	return &memFile{Reader: bytes.NewReader(nil), info: memFileInfo{name: path.Base(p), dir: true}, infos: infos}, nil
+}
+
+type memFile struct {
+	*bytes.Reader
+	info  memFileInfo
+	infos []os.FileInfo
+}
+
+func (f *memFile) Close() error                       { return nil }
+func (f *memFile) Stat() (os.FileInfo, error)         { return f.info, nil }
+func (f *memFile) Readdir(int) ([]os.FileInfo, error) { return f.infos, nil }
+
+type memFileInfo struct {
+	name string
+	size int64
+	dir  bool
+}
+
+func (f memFileInfo) Name() string       { return f.name }
+func (f memFileInfo) Size() int64        { return f.size }
+func (f memFileInfo) Mode() os.FileMode  { return 0644 }
+func (f memFileInfo) ModTime() time.Time { return time.Time{} }
+func (f memFileInfo) IsDir() bool        { return f.dir }
+func (f memFileInfo) Sys() interface{}   { return nil }
+
+// flacFile returns a FLAC file with the given duration (in seconds) and Vorbis comments.
+func flacFile(seconds int, comments ...string) []byte {
+	rate := uint32(44100)
+	samples := uint64(seconds) * uint64(rate)
+
+	b := []byte("fLaC")
+	b = append(b, 0, 0, 0, 34) // STREAMINFO
+	info := make([]byte, 34)
+	info[10] = byte(rate >> 12)
+	info[11] = byte(rate >> 4)
+	info[12] = byte(rate<<4) | 0x02 // 2 channels
+	info[13] = 0xf0 | byte(samples>>32)
+	binary.BigEndian.PutUint32(info[14:], uint32(samples))
+	b = append(b, info...)
+
+	var c []byte
+	put := func(s string) {
+		n := make([]byte, 4)
+		binary.LittleEndian.PutUint32(n, uint32(len(s)))
+		c = append(c, n...)
+		c = append(c, s...)
+	}
+	put("vendor")
+	n := make([]byte, 4)
+	binary.LittleEndian.PutUint32(n, uint32(len(comments)))
+	c = append(c, n...)
+	for _, x := range comments {
+		put(x)
+	}
+	b = append(b, 0x84, byte(len(c)>>16), byte(len(c)>>8), byte(len(c))) // last block: VORBIS_COMMENT
+	b = append(b, c...)
+	return append(b, make([]byte, 1000)...)
+}
+
+type testTrack map[string]interface{}
+
+func (t testTrack) GetString(f string) string {
+	s, _ := t[f].(string)
+	return s
+}
+
+func (t testTrack) GetStrings(f string) []string {
+	if s := t.GetString(f); s != "" {
+		return []string{s}
+	}
+	return nil
+}
+
+func (t testTrack) GetInt(f string) int {
+	n, _ := t[f].(int)
+	return n
+}
+
+func (t testTrack) GetTime(f string) time.Time { return time.Time{} }
+
+type testLibrary []index.Track
+
+func (l testLibrary) Tracks() []index.Track {
+	return append([]index.Track(nil), l...)
+}
+
+func (l testLibrary) Track(id string) (index.Track, bool) {
+	for _, t := range l {
+		if t.GetString("ID") == id {
+			return t, true
+		}
+	}
+	return nil, false
+}
+
+func TestVerify(t *testing.T) {
+	fs := memFileSystem{
+		"/music/ok.flac":       flacFile(60, "TITLE=So What", "ALBUM=Kind of Blue", "ARTIST=Miles Davis"),
+		"/music/tag.flac":      flacFile(60, "TITLE=Blue in Green", "ALBUM=Kind of Blue", "ARTIST=Miles Davis"),
+		"/music/duration.flac": flacFile(90, "TITLE=All Blues"),
+		"/music/invalid.flac":  []byte("not a media file"),
+	}
+	v := &verifier{fs: fs, tolerance: 2 * time.Second}
+
+	tests := []struct {
+		track  testTrack
+		checks []string
+	}{
+		{testTrack{"Location": "/music/ok.flac", "Name": "So What", "Album": "Kind Of Blue", "TotalTime": 61000}, nil},
+		{testTrack{"Location": "/music/missing.flac"}, []string{checkMissing}},
+		{testTrack{"Location": "/music/tag.flac", "Name": "Freddie Freeloader", "TotalTime": 60000}, []string{checkTag}},
+		{testTrack{"Location": "/music/duration.flac", "Name": "All Blues", "TotalTime": 60000}, []string{checkDuration}},
+		{testTrack{"Location": "/music/invalid.flac"}, []string{checkParse}},
+	}
+
+	for ii, tt := range tests {
+		var checks []string
+		for _, p := range v.verify(context.Background(), tt.track) {
+			checks = append(checks, p.Check)
+		}
+		if !reflect.DeepEqual(checks, tt.checks) {
+			t.Errorf("[%d] verify(%v) checks = %v, expected %v", ii, tt.track.GetString("Location"), checks, tt.checks)
+		}
+	}
+}
+
+func TestVerifyAll(t *testing.T) {
+	fs := memFileSystem{
+		"/music/a.flac": flacFile(60),
+	}
+	v := &verifier{fs: fs, tolerance: 2 * time.Second}
+
+	tracks := []index.Track{
+		testTrack{"ID": "1", "Location": "/music/c.flac"},
+		testTrack{"ID": "2", "Location": "/music/a.flac"},
+		testTrack{"ID": "3", "Location": "/music/b.flac"},
+	}
+	r := v.verifyAll(context.Background(), tracks, 2)
+
+	if r.Tracks != 3 {
+		t.Errorf("verifyAll() Tracks = %d, expected 3", r.Tracks)
+	}
+	var got []string
+	for _, p := range r.Problems {
+		got = append(got, p.ID+": "+p.Check)
+	}
+	expected := []string{"3: missing", "1: missing"}
+	if !reflect.DeepEqual(got, expected) {
+		t.Errorf("verifyAll() problems = %v, expected %v", got, expected)
+	}
+}
+
+func TestSearchCorrections(t *testing.T) {
+	fs := memFileSystem{
+		"/music/new/a.flac":   nil,
+		"/music/new/b.flac":   nil,
+		"/music/other/b.flac": nil,
+	}
+	problems := []*problem{
+		{ID: "1", Location: "/old/a.flac", Check: checkMissing},
+		{ID: "2", Location: "/old/b.flac", Check: checkMissing},
+		{ID: "3", Location: "/old/c.flac", Check: checkMissing},
+		{ID: "4", Location: "/music/new/a.flac", Check: checkTag},
+	}
+
+	if err := search(context.Background(), fs, "/music", problems); err != nil {
+		t.Fatalf("unexpected error from search: %v", err)
+	}
+
+	found := make(map[string][]string)
+	for _, p := range problems {
+		sort.Strings(p.Found)
+		found[p.ID] = p.Found
+	}
+	expectedFound := map[string][]string{
+		"1": {"/music/new/a.flac"},
+		"2": {"/music/new/b.flac", "/music/other/b.flac"},
+		"3": nil,
+		"4": nil,
+	}
+	if !reflect.DeepEqual(found, expectedFound) {
+		t.Errorf("search() found = %v, expected %v", found, expectedFound)
+	}
+
+	// Only files found in exactly one place are corrected.
+	locs := corrections(problems)
+	expectedLocs := map[string]string{"1": "/music/new/a.flac"}
+	if !reflect.DeepEqual(locs, expectedLocs) {
+		t.Errorf("corrections() = %v, expected %v", locs, expectedLocs)
+	}
+
+	l := &locationLibrary{
+		Library: testLibrary{
+			testTrack{"ID": "1", "Location": "/old/a.flac", "Name": "A"},
+			testTrack{"ID": "2", "Location": "/old/b.flac"},
+		},
+		locs: locs,
+	}
+	var got []string
+	for _, t := range l.Tracks() {
+		got = append(got, t.GetString("Location"))
+	}
+	if expected := []string{"/music/new/a.flac", "/old/b.flac"}; !reflect.DeepEqual(got, expected) {
+		t.Errorf("Tracks() locations = %v, expected %v", got, expected)
+	}
+	if tr, ok := l.Track("1"); !ok || tr.GetString("Location") != "/music/new/a.flac" || tr.GetString("Name") != "A" {
+		t.Errorf("Track(1) = %v, %v, expected track with corrected location", tr, ok)
+	}
+	if _, ok := l.Track("5"); ok {
+		t.Errorf("Track(5) found, expected not found")
+	}
+}
@@ -0,0 +1,208 @@
+// Copyright 2015, David Howden
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package index
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/amiforus/tchaik/index/attr"
+)
+
+// AlbumGrouping is a rule for grouping tracks into albums.
+type AlbumGrouping string
+
+// Album grouping rules.
+const (
+	// GroupByAlbum groups tracks by Album only, so albums which share a title are merged.
+	GroupByAlbum AlbumGrouping = "album"
+
+	// GroupByArtist groups tracks by Album and album artist (see ByAlbum).
+	GroupByArtist AlbumGrouping = "artist"
+
+	// GroupByFolder groups tracks by Album, album artist and directory (see ByAlbum).
+	GroupByFolder AlbumGrouping = "folder"
+)
+
+// ParseAlbumGrouping returns the AlbumGrouping named by s.
+func ParseAlbumGrouping(s string) (AlbumGrouping, error) {
+	switch g := AlbumGrouping(s); g {
+	case GroupByAlbum, GroupByArtist, GroupByFolder:
+		return g, nil
+	}
+	return "", fmt.Errorf("invalid album grouping '%v': must be one of album, artist or folder", s)
+}
+
+// ByAlbum returns a Collector which groups tracks into albums using the rule g.
+//
+// GroupByAlbum groups tracks by Album.  Otherwise tracks are grouped by Album (ignoring disc
+// suffixes such as "(Disc 2)" so that multi-disc sets are kept together) and album artist,
+// and with GroupByFolder also by directory (ignoring disc directories such as "CD2").  The
+// album artist of a track is its AlbumArtist, or if that isn't set:
+//
+//   - tracks marked as Compilation are a compilation,
+//   - tracks with the same Album where most have a different Artist are a compilation,
+//   - otherwise the Artist of the track, except for artists which only appear on one track
+//     (i.e. guest appearances) whose tracks are part of the album of the most common Artist
+//     (unless the Artist is the AlbumArtist of other tracks).
+//
+// Groups are named by Album, and are keyed by Album when no other album has the same title
+// (so keys are the same as for GroupByAlbum).
+func ByAlbum(g AlbumGrouping) Collector {
+	if g == GroupByAlbum {
+		return By(attr.String("Album"))
+	}
+	return groupByAlbum(g)
+}
+
+type groupByAlbum AlbumGrouping
+
+// albumBucket is a list of tracks with the same album title (and directory).
+type albumBucket struct {
+	title, dir string
+	tracks     []Track
+}
+
+// album is a list of tracks which have been grouped into an album.
+type album struct {
+	title, id string
+	tracks    []Track
+}
+
+// Collect implements Collector.
+func (g groupByAlbum) Collect(tracker Tracker) Collection {
+	var bucketKeys []string
+	buckets := make(map[string]*albumBucket)
+	for _, t := range tracker.Tracks() {
+		title := trimDiscSuffix(t.GetString("Album"))
+		var dir string
+		if AlbumGrouping(g) == GroupByFolder {
+			dir = albumDir(t.GetString("Location"))
+		}
+
+		k := title + "\x00" + dir
+		b, ok := buckets[k]
+		if !ok {
+			b = &albumBucket{title: title, dir: dir}
+			buckets[k] = b
+			bucketKeys = append(bucketKeys, k)
+		}
+		b.tracks = append(b.tracks, t)
+	}
+
+	var albums []*album
+	titles := make(map[string]int) // title -> number of albums
+	for _, k := range bucketKeys {
+		b := buckets[k]
+		byArtist := make(map[string]*album)
+		for i, a := range albumArtistKeys(b.tracks) {
+			x, ok := byArtist[a]
+			if !ok {
+				x = &album{
+					title: b.title,
+					id:    k + "\x00" + a,
+				}
+				byArtist[a] = x
+				albums = append(albums, x)
+				titles[b.title]++
+			}
+			x.tracks = append(x.tracks, b.tracks[i])
+		}
+	}
+
+	gg := newCol(collectName(tracker, "Album"))
+	for _, a := range albums {
+		kn := a.title
+		if titles[a.title] > 1 {
+			kn = a.id
+		}
+		for _, t := range a.tracks {
+			gg.addKeyName(a.title, kn, t)
+		}
+	}
+	return gg
+}
+
+// compilationKey is the album artist key used for compilations without an AlbumArtist.
+const compilationKey = "\x00compilation"
+
+// albumArtistKeys returns the (normalised) album artist of each of the tracks, which all
+// have the same album title (see ByAlbum).
+func albumArtistKeys(tracks []Track) []string {
+	keys := make([]string, len(tracks))
+	known := make(map[string]bool)
+	var rest []int
+	for i, t := range tracks {
+		if aa := Normalise(t.GetString("AlbumArtist")); aa != "" {
+			keys[i] = aa
+			known[aa] = true
+			continue
+		}
+		if t.GetInt("Compilation") != 0 {
+			keys[i] = compilationKey
+			continue
+		}
+		keys[i] = Normalise(t.GetString("Artist"))
+		rest = append(rest, i)
+	}
+
+	// Tracks by an artist which is the AlbumArtist of other tracks are part of their album.
+	var unknown []int
+	counts := make(map[string]int)
+	for _, i := range rest {
+		if !known[keys[i]] {
+			counts[keys[i]]++
+			unknown = append(unknown, i)
+		}
+	}
+
+	if len(counts) <= 1 {
+		return keys
+	}
+
+	if len(counts) > len(unknown)/2 {
+		for _, i := range unknown {
+			keys[i] = compilationKey
+		}
+		return keys
+	}
+
+	var top string
+	for a, n := range counts {
+		if n > counts[top] || (n == counts[top] && a < top) {
+			top = a
+		}
+	}
+	for _, i := range unknown {
+		if counts[keys[i]] == 1 {
+			keys[i] = top
+		}
+	}
+	return keys
+}
+
+var (
+	discSuffix = regexp.MustCompile(`(?i)[\s,:-]*[(\[]?\s*(disc|disk|cd)\s*\.?\s*\d+(\s*of\s*\d+)?\s*[)\]]?$`)
+	discDir    = regexp.MustCompile(`(?i)^(disc|disk|cd)\s*\d+$`)
+)
+
+// trimDiscSuffix removes disc suffixes (i.e. " (Disc 2)" or " [CD 1]") from the album title.
+func trimDiscSuffix(title string) string {
+	if t := discSuffix.ReplaceAllString(title, ""); t != "" {
+		return t
+	}
+	return title
+}
+
+// albumDir returns the directory of the path (see splitDir), ignoring disc directories
+// (i.e. "CD2").
+func albumDir(path string) string {
+	dirs := splitDir(path)
+	if n := len(dirs); n > 1 && discDir.MatchString(dirs[n-1]) {
+		dirs = dirs[:n-1]
+	}
+	return strings.Join(dirs, "/")
+}
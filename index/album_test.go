@@ -0,0 +1,171 @@
+// Copyright 2015, David Howden
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package index
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestTrimDiscSuffix(t *testing.T) {
+	tests := []struct {
+		in, out string
+	}{
+		{"Kind of Blue", "Kind of Blue"},
+		{"The Wall (Disc 1)", "The Wall"},
+		{"The Wall [CD 2]", "The Wall"},
+		{"The Wall - Disc 2 of 2", "The Wall"},
+		{"The Wall, disk.2", "The Wall"},
+		{"The Wall CD2", "The Wall"},
+		{"Discovery", "Discovery"},
+		{"Disc 1", "Disc 1"},
+		{"1984", "1984"},
+	}
+
+	for ii, tt := range tests {
+		if got := trimDiscSuffix(tt.in); got != tt.out {
+			t.Errorf("[%d] trimDiscSuffix(%#v) = %#v, expected %#v", ii, tt.in, got, tt.out)
+		}
+	}
+}
+
+func TestAlbumDir(t *testing.T) {
+	tests := []struct {
+		in, out string
+	}{
+		{"/music/Artist/Album/01.mp3", "music/Artist/Album"},
+		{"/music/Artist/Album/CD2/01.mp3", "music/Artist/Album"},
+		{"/music/Artist/Album/Disc 1/01.mp3", "music/Artist/Album"},
+		{"/CD1/01.mp3", "CD1"},
+		{"01.mp3", ""},
+	}
+
+	for ii, tt := range tests {
+		if got := albumDir(tt.in); got != tt.out {
+			t.Errorf("[%d] albumDir(%#v) = %#v, expected %#v", ii, tt.in, got, tt.out)
+		}
+	}
+}
+
+// albumNames returns the group name and track names of each group in c (in key order).
+func albumNames(c Collection) [][]string {
+	var result [][]string
+	for _, k := range c.Keys() {
+		g := c.Get(k)
+		names := []string{g.Name()}
+		for _, t := range g.Tracks() {
+			names = append(names, t.GetString("Name"))
+		}
+		result = append(result, names)
+	}
+	return result
+}
+
+func TestByAlbum(t *testing.T) {
+	tracks := albumTracker{
+		// Albums with the same title by different artists.
+		{Name: "1", Album: "Greatest Hits", Artist: "Queen", Location: "/Queen/Greatest Hits/1.mp3"},
+		{Name: "2", Album: "Greatest Hits", Artist: "Queen", Location: "/Queen/Greatest Hits/2.mp3"},
+		{Name: "3", Album: "Greatest Hits", Artist: "ABBA", Location: "/ABBA/Greatest Hits/3.mp3"},
+		{Name: "4", Album: "Greatest Hits", Artist: "ABBA", AlbumArtist: "ABBA", Location: "/ABBA/Greatest Hits/4.mp3"},
+
+		// Guest appearance.
+		{Name: "5", Album: "Thriller", Artist: "Michael Jackson", Location: "/MJ/Thriller/5.mp3"},
+		{Name: "6", Album: "Thriller", Artist: "Michael Jackson", Location: "/MJ/Thriller/6.mp3"},
+		{Name: "7", Album: "Thriller", Artist: "Michael Jackson & Paul McCartney", Location: "/MJ/Thriller/7.mp3"},
+
+		// Compilation without album artist.
+		{Name: "8", Album: "Now 1", Artist: "A", Location: "/Now 1/8.mp3"},
+		{Name: "9", Album: "Now 1", Artist: "B", Location: "/Now 1/9.mp3"},
+		{Name: "10", Album: "Now 1", Artist: "C", Location: "/Now 1/10.mp3"},
+
+		// Multi-disc set.
+		{Name: "11", Album: "The Wall (Disc 1)", Artist: "Pink Floyd", Location: "/Pink Floyd/The Wall/CD1/11.mp3"},
+		{Name: "12", Album: "The Wall (Disc 2)", Artist: "Pink Floyd", Location: "/Pink Floyd/The Wall/CD2/12.mp3"},
+
+		// Same title, artist and (compilation) album in different directories.
+		{Name: "13", Album: "Symphony No. 5", Compilation: 1, Location: "/Beethoven/Symphony No. 5/13.mp3"},
+		{Name: "14", Album: "Symphony No. 5", Compilation: 1, Location: "/Mahler/Symphony No. 5/14.mp3"},
+	}
+
+	tests := []struct {
+		g        AlbumGrouping
+		expected [][]string
+	}{
+		{
+			GroupByAlbum,
+			[][]string{
+				{"Greatest Hits", "1", "2", "3", "4"},
+				{"Now 1", "8", "9", "10"},
+				{"Symphony No. 5", "13", "14"},
+				{"The Wall (Disc 1)", "11"},
+				{"The Wall (Disc 2)", "12"},
+				{"Thriller", "5", "6", "7"},
+			},
+		},
+		{
+			GroupByArtist,
+			[][]string{
+				{"Greatest Hits", "1", "2"},
+				{"Greatest Hits", "3", "4"},
+				{"Now 1", "8", "9", "10"},
+				{"Symphony No. 5", "13", "14"},
+				{"The Wall", "11", "12"},
+				{"Thriller", "5", "6", "7"},
+			},
+		},
+		{
+			GroupByFolder,
+			[][]string{
+				{"Greatest Hits", "1", "2"},
+				{"Greatest Hits", "3", "4"},
+				{"Now 1", "8", "9", "10"},
+				{"Symphony No. 5", "13"},
+				{"Symphony No. 5", "14"},
+				{"The Wall", "11", "12"},
+				{"Thriller", "5", "6", "7"},
+			},
+		},
+	}
+
+	for ii, tt := range tests {
+		c := Collect(tracks, ByAlbum(tt.g))
+		SortKeysByGroupName(c)
+		got := albumNames(c)
+		sortAlbumNames(got)
+		if !reflect.DeepEqual(got, tt.expected) {
+			t.Errorf("[%d] ByAlbum(%v) = %v, expected %v", ii, tt.g, got, tt.expected)
+		}
+	}
+
+	// Albums with a unique title have the same key as when grouping by title only.
+	byTitle := Collect(tracks, ByAlbum(GroupByAlbum))
+	byArtist := Collect(tracks, ByAlbum(GroupByArtist))
+	k := Key(nameKeyMap(byTitle)["Thriller"])
+	if g := byArtist.Get(k); g == nil || g.Name() != "Thriller" {
+		t.Errorf("Get(%#v) = %v, expected group \"Thriller\"", k, g)
+	}
+}
+
+// sortAlbumNames sorts groups with the same name by their first track (the order of groups
+// with the same name depends on the order of keys).
+func sortAlbumNames(names [][]string) {
+	for i := 1; i < len(names); i++ {
+		for j := i; j > 0 && names[j][0] == names[j-1][0] && names[j][1] < names[j-1][1]; j-- {
+			names[j], names[j-1] = names[j-1], names[j]
+		}
+	}
+}
+
+func TestParseAlbumGrouping(t *testing.T) {
+	for _, s := range []string{"album", "artist", "folder"} {
+		if g, err := ParseAlbumGrouping(s); err != nil || string(g) != s {
+			t.Errorf("ParseAlbumGrouping(%#v) = %#v, %v, expected %#v, nil", s, g, err, s)
+		}
+	}
+	if _, err := ParseAlbumGrouping("title"); err == nil {
+		t.Errorf("ParseAlbumGrouping(\"title\") returned nil error")
+	}
+}
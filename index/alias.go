@@ -0,0 +1,406 @@
+// Copyright 2015, David Howden
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package index
+
+import (
+	"encoding/json"
+	"io"
+	"sort"
+	"strings"
+	"unicode/utf8"
+)
+
+// Aliases maps variants of names (i.e. "J.S. Bach" and "Bach, Johann Sebastian") to their
+// canonical name (i.e. "Johann Sebastian Bach").  Variants are matched ignoring case, accents
+// and punctuation.  A nil *Aliases has no aliases.
+type Aliases struct {
+	names    map[string]string   // aliasKey(variant) -> canonical name
+	variants map[string][]string // canonical name -> variants
+}
+
+// NewAliases creates Aliases from a map of canonical names to their variants (i.e. read from
+// an alias file).
+func NewAliases(m map[string][]string) *Aliases {
+	a := &Aliases{
+		names:    make(map[string]string),
+		variants: make(map[string][]string),
+	}
+	for c, vs := range m {
+		a.add(c, c)
+		for _, v := range vs {
+			a.add(v, c)
+		}
+	}
+	return a
+}
+
+// ReadAliases reads Aliases from r, which is a JSON object mapping canonical names to lists
+// of their variants, i.e.
+//
+//	{"Johann Sebastian Bach": ["Bach", "J. S. Bach"]}
+func ReadAliases(r io.Reader) (*Aliases, error) {
+	var m map[string][]string
+	if err := json.NewDecoder(r).Decode(&m); err != nil {
+		return nil, err
+	}
+	return NewAliases(m), nil
+}
+
+// add adds the variant v of the canonical name c.
+func (a *Aliases) add(v, c string) {
+	k := aliasKey(v)
+	if x, ok := a.names[k]; ok && x == c {
+		return
+	}
+	a.names[k] = c
+	if v != c {
+		a.variants[c] = append(a.variants[c], v)
+	}
+}
+
+// Canonical returns the canonical name of the name, and true if it has one.
+func (a *Aliases) Canonical(name string) (string, bool) {
+	if a == nil || name == "" {
+		return name, false
+	}
+	c, ok := a.names[aliasKey(name)]
+	if !ok {
+		return name, false
+	}
+	return c, true
+}
+
+// Variants returns a map of canonical names to their variants.
+func (a *Aliases) Variants() map[string][]string {
+	if a == nil {
+		return nil
+	}
+	return a.variants
+}
+
+// aliasTokens returns the words of the name, ignoring case, accents and punctuation, and
+// treating initials (i.e. "J.S.") as separate words.
+func aliasTokens(name string) []string {
+	return strings.Fields(Normalise(strings.Replace(name, ".", " ", -1)))
+}
+
+func aliasKey(name string) string {
+	return strings.Join(aliasTokens(name), " ")
+}
+
+// isInitial returns true if the word is an initial.
+func isInitial(w string) bool {
+	return utf8.RuneCountInString(w) == 1
+}
+
+// hasInitials returns true if any of the words, except the last (surname), is an initial.
+func hasInitials(ws []string) bool {
+	for _, w := range ws[:len(ws)-1] {
+		if isInitial(w) {
+			return true
+		}
+	}
+	return false
+}
+
+// initialsMatch returns true if the words of two names match when initials are compared to
+// the first letter of words in the other name (i.e. "J.S. Bach" and "Johann Sebastian Bach").
+// Surnames (the last word) must match exactly.
+func initialsMatch(x, y []string) bool {
+	if len(x) != len(y) || len(x) < 2 || x[len(x)-1] != y[len(y)-1] {
+		return false
+	}
+	for i := range x[:len(x)-1] {
+		switch {
+		case x[i] == y[i]:
+		case isInitial(x[i]) && strings.HasPrefix(y[i], x[i]):
+		case isInitial(y[i]) && strings.HasPrefix(x[i], y[i]):
+		default:
+			return false
+		}
+	}
+	return true
+}
+
+// invertName returns "First Last" for a name of the form "Last, First", and false if the name
+// isn't of that form.
+func invertName(name string) (string, bool) {
+	if strings.Count(name, ",") != 1 {
+		return "", false
+	}
+	parts := splitMultiple(name, ListSeparators)
+	if len(parts) != 2 || !strings.Contains(name, parts[0]+",") {
+		return "", false
+	}
+	return parts[1] + " " + parts[0], true
+}
+
+// AliasSuspect is a list of names which might be variants of the same name, but which don't
+// have the same canonical name.
+type AliasSuspect struct {
+	Names  []string `json:"names"`
+	Reason string   `json:"reason"`
+}
+
+// aliasName is a name (or variant) found in the tracks.
+type aliasName struct {
+	name     string
+	tokens   []string
+	count    int
+	inverted bool
+	parent   *aliasName // the name this is a variant of, nil if none
+}
+
+func (n *aliasName) root() *aliasName {
+	for n.parent != nil {
+		n = n.parent
+	}
+	return n
+}
+
+func (n *aliasName) union(m *aliasName) {
+	if r, s := n.root(), m.root(); r != s {
+		s.parent = r
+	}
+}
+
+// DetectAliases finds the variants of names in the fields (i.e. Artist and Composer) of the
+// tracks, after splitting lists of names using the rules r (see SplitAliasList):
+//
+//   - names which differ only by case, accents or punctuation,
+//   - names of the form "Last, First" (which would otherwise be split) where "First Last" is
+//     another name (or matches one as below),
+//   - names with initials (i.e. "J.S. Bach") which match only one other name without initials
+//     (i.e. "Johann Sebastian Bach").
+//
+// The canonical name of each set of variants is the most common name without initials (that
+// isn't inverted).  Aliases in a (which can be nil, i.e. from an alias file) take precedence:
+// if any variant is in a then all the variants have the canonical name from a.  Names which
+// might be variants but aren't aliased are returned as suspects.
+func DetectAliases(t Tracker, r *SplitRules, fields []string, a *Aliases) (*Aliases, []AliasSuspect) {
+	names := make(map[string]*aliasName)
+	var order []*aliasName
+	addName := func(n string, inverted bool) *aliasName {
+		x, ok := names[n]
+		if !ok {
+			tokens := aliasTokens(n)
+			if inverted {
+				inv, _ := invertName(n)
+				tokens = aliasTokens(inv)
+			}
+			if len(tokens) == 0 {
+				return nil
+			}
+			x = &aliasName{name: n, tokens: tokens, inverted: inverted}
+			names[n] = x
+			order = append(order, x)
+		}
+		x.count++
+		return x
+	}
+
+	var inverted []*aliasName
+	for _, tr := range t.Tracks() {
+		for _, f := range fields {
+			vs, multi := values(tr, f)
+			for _, v := range vs {
+				if _, ok := invertName(v); ok && !r.isException(v) {
+					if x := addName(v, true); x != nil && x.count == 1 {
+						inverted = append(inverted, x)
+					}
+					continue
+				}
+				if multi || r.isException(v) {
+					addName(v, false)
+					continue
+				}
+				for _, p := range r.Split(f, v) {
+					addName(p, false)
+				}
+			}
+		}
+	}
+
+	// Names which are the same, ignoring case, accents and punctuation.
+	byKey := make(map[string]*aliasName)
+	bySurname := make(map[string][]*aliasName) // full names (without initials)
+	for _, n := range order {
+		if n.inverted {
+			continue
+		}
+		k := strings.Join(n.tokens, " ")
+		if x, ok := byKey[k]; ok {
+			x.union(n)
+			continue
+		}
+		byKey[k] = n
+		if !hasInitials(n.tokens) {
+			s := n.tokens[len(n.tokens)-1]
+			bySurname[s] = append(bySurname[s], n)
+		}
+	}
+
+	var suspects []AliasSuspect
+
+	// match returns the names without initials that n matches.
+	match := func(n *aliasName) []*aliasName {
+		if x, ok := byKey[strings.Join(n.tokens, " ")]; ok && !hasInitials(n.tokens) {
+			return []*aliasName{x}
+		}
+		var result []*aliasName
+		for _, x := range bySurname[n.tokens[len(n.tokens)-1]] {
+			if initialsMatch(n.tokens, x.tokens) {
+				result = append(result, x)
+			}
+		}
+		return result
+	}
+
+	for _, n := range order {
+		if n.inverted || !hasInitials(n.tokens) || byKey[strings.Join(n.tokens, " ")] != n {
+			continue
+		}
+		switch m := match(n); len(m) {
+		case 0:
+		case 1:
+			m[0].union(n)
+		default:
+			s := AliasSuspect{Names: []string{n.name}, Reason: "ambiguous initials"}
+			for _, x := range m {
+				s.Names = append(s.Names, x.name)
+			}
+			suspects = append(suspects, s)
+		}
+	}
+
+	for _, n := range inverted {
+		if x, ok := byKey[strings.Join(n.tokens, " ")]; ok {
+			x.union(n)
+			continue
+		}
+		m := match(n)
+		if len(m) == 1 {
+			m[0].union(n)
+			continue
+		}
+
+		// Only names with the same surname as another name are suspects, otherwise every list
+		// of two names (i.e. "Simon, Garfunkel") would be.
+		if len(m) == 0 {
+			m = bySurname[n.tokens[len(n.tokens)-1]]
+		}
+		if len(m) > 0 {
+			s := AliasSuspect{Names: []string{n.name}, Reason: "inverted name without a match"}
+			for _, x := range m {
+				s.Names = append(s.Names, x.name)
+			}
+			suspects = append(suspects, s)
+		}
+	}
+
+	groups := make(map[*aliasName][]*aliasName)
+	var roots []*aliasName
+	for _, n := range order {
+		r := n.root()
+		if _, ok := groups[r]; !ok {
+			roots = append(roots, r)
+		}
+		groups[r] = append(groups[r], n)
+	}
+
+	result := &Aliases{
+		names:    make(map[string]string),
+		variants: make(map[string][]string),
+	}
+	canonical := make(map[*aliasName]string)
+	aliased := make(map[*aliasName]bool) // roots with a canonical name from a
+	for _, r := range roots {
+		g := groups[r]
+		c := canonicalName(g)
+		for _, n := range g {
+			if x, ok := a.Canonical(n.name); ok {
+				c = x
+				aliased[r] = true
+				break
+			}
+		}
+		canonical[r] = c
+		if len(g) == 1 && g[0].name == c {
+			continue
+		}
+		for _, n := range g {
+			result.add(n.name, c)
+		}
+	}
+	if a != nil {
+		for c, vs := range a.variants {
+			result.add(c, c)
+			for _, v := range vs {
+				result.add(v, c)
+			}
+		}
+	}
+
+	// Surnames on their own (i.e. "Bach") which might be one of the names with that surname,
+	// unless they are already aliased.
+	for _, r := range roots {
+		if r.inverted || len(r.tokens) != 1 || aliased[r] {
+			continue
+		}
+		s := AliasSuspect{Names: []string{canonical[r]}, Reason: "surname only"}
+		seen := map[string]bool{canonical[r]: true}
+		for _, x := range bySurname[r.tokens[0]] {
+			if c := canonical[x.root()]; !seen[c] {
+				s.Names = append(s.Names, c)
+				seen[c] = true
+			}
+		}
+		if len(s.Names) > 1 {
+			suspects = append(suspects, s)
+		}
+	}
+
+	for _, s := range suspects {
+		sort.Strings(s.Names[1:])
+	}
+	return result, suspects
+}
+
+// canonicalName returns the canonical name of the variants: the most common name which isn't
+// inverted and doesn't have initials (longest first, then alphabetical).
+func canonicalName(ns []*aliasName) string {
+	var best *aliasName
+	score := func(n *aliasName) int {
+		s := 0
+		if !n.inverted {
+			s += 2
+		}
+		if !hasInitials(n.tokens) {
+			s++
+		}
+		return s
+	}
+	for _, n := range ns {
+		switch {
+		case best == nil:
+		case score(n) != score(best):
+			if score(n) < score(best) {
+				continue
+			}
+		case n.count != best.count:
+			if n.count < best.count {
+				continue
+			}
+		case len(n.name) != len(best.name):
+			if len(n.name) < len(best.name) {
+				continue
+			}
+		case n.name >= best.name:
+			continue
+		}
+		best = n
+	}
+	return best.name
+}
@@ -0,0 +1,156 @@
+// Copyright 2015, David Howden
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package index
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestReadAliases(t *testing.T) {
+	a, err := ReadAliases(strings.NewReader(`{"Johann Sebastian Bach": ["Bach", "J. S. Bach"]}`))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	tests := []struct {
+		in  string
+		out string
+		ok  bool
+	}{
+		{"Johann Sebastian Bach", "Johann Sebastian Bach", true},
+		{"bach", "Johann Sebastian Bach", true},
+		{"J.S. Bach", "Johann Sebastian Bach", true},
+		{"Handel", "Handel", false},
+		{"", "", false},
+	}
+
+	for ii, tt := range tests {
+		c, ok := a.Canonical(tt.in)
+		if c != tt.out || ok != tt.ok {
+			t.Errorf("[%d] Canonical(%q) = (%q, %v), expected (%q, %v)", ii, tt.in, c, ok, tt.out, tt.ok)
+		}
+	}
+
+	if _, err := ReadAliases(strings.NewReader(`["Bach"]`)); err == nil {
+		t.Errorf("expected error reading invalid alias file")
+	}
+}
+
+func TestDetectAliases(t *testing.T) {
+	tracks := testTracker{
+		{Composer: "Johann Sebastian Bach"},
+		{Composer: "Johann Sebastian Bach"},
+		{Composer: "J.S. Bach"},
+		{Composer: "Bach, Johann Sebastian"},
+		{Composer: "Bach"},
+		{Composer: "Carl Philipp Emanuel Bach"},
+		{Composer: "Dvořák"},
+		{Composer: "Antonín Dvořák"},
+		{Composer: "Antonin Dvorak"},
+		{Artist: "R. Schumann"},
+		{Artist: "Robert Schumann"},
+		{Artist: "Clara Schumann"},
+		{Artist: "C. Schumann"},
+		{Artist: "Simon, Garfunkel"},
+		{Artist: "Glenn Gould; Yo-Yo Ma"},
+	}
+
+	a, suspects := DetectAliases(tracks, nil, []string{"Artist", "Composer"}, nil)
+
+	tests := []struct {
+		in  string
+		out string
+	}{
+		{"J.S. Bach", "Johann Sebastian Bach"},
+		{"Bach, Johann Sebastian", "Johann Sebastian Bach"},
+		{"Antonin Dvorak", "Antonín Dvořák"},
+		{"R. Schumann", "Robert Schumann"},
+		{"C. Schumann", "Clara Schumann"},
+		{"Bach", "Bach"},
+		{"Simon", "Simon"},
+		{"Glenn Gould", "Glenn Gould"},
+	}
+	for ii, tt := range tests {
+		if c, _ := a.Canonical(tt.in); c != tt.out {
+			t.Errorf("[%d] Canonical(%q) = %q, expected %q", ii, tt.in, c, tt.out)
+		}
+	}
+
+	expected := []AliasSuspect{
+		{[]string{"Bach", "Carl Philipp Emanuel Bach", "Johann Sebastian Bach"}, "surname only"},
+		{[]string{"Dvořák", "Antonín Dvořák"}, "surname only"},
+	}
+	if !reflect.DeepEqual(suspects, expected) {
+		t.Errorf("DetectAliases() suspects = %#v, expected %#v", suspects, expected)
+	}
+
+	// Aliases from a file take precedence.
+	a, suspects = DetectAliases(tracks, nil, []string{"Artist", "Composer"}, NewAliases(map[string][]string{
+		"Johann Sebastian Bach":  {"Bach"},
+		"Antonín Leopold Dvořák": {"Dvořák", "Antonín Dvořák"},
+	}))
+	for _, n := range []string{"Bach", "J.S. Bach", "Bach, Johann Sebastian"} {
+		if c, _ := a.Canonical(n); c != "Johann Sebastian Bach" {
+			t.Errorf("Canonical(%q) = %q, expected %q", n, c, "Johann Sebastian Bach")
+		}
+	}
+	for _, n := range []string{"Dvořák", "Antonin Dvorak"} {
+		if c, _ := a.Canonical(n); c != "Antonín Leopold Dvořák" {
+			t.Errorf("Canonical(%q) = %q, expected %q", n, c, "Antonín Leopold Dvořák")
+		}
+	}
+	if len(suspects) != 0 {
+		t.Errorf("DetectAliases() suspects = %#v, expected none", suspects)
+	}
+}
+
+func TestDetectAliasesAmbiguous(t *testing.T) {
+	tracks := testTracker{
+		{Artist: "J. Strauss"},
+		{Artist: "Johann Strauss"},
+		{Artist: "Josef Strauss"},
+	}
+
+	a, suspects := DetectAliases(tracks, nil, []string{"Artist"}, nil)
+	if c, ok := a.Canonical("J. Strauss"); ok {
+		t.Errorf("Canonical(%q) = %q, expected no alias", "J. Strauss", c)
+	}
+
+	expected := []AliasSuspect{
+		{[]string{"J. Strauss", "Johann Strauss", "Josef Strauss"}, "ambiguous initials"},
+	}
+	if !reflect.DeepEqual(suspects, expected) {
+		t.Errorf("DetectAliases() suspects = %#v, expected %#v", suspects, expected)
+	}
+}
+
+func TestSplitAliasList(t *testing.T) {
+	a := NewAliases(map[string][]string{
+		"Johann Sebastian Bach": {"Bach, Johann Sebastian", "J.S. Bach"},
+	})
+
+	tests := []struct {
+		in  string
+		out []string
+	}{
+		{"", nil},
+		{"Bach, Johann Sebastian", []string{"Johann Sebastian Bach"}},
+		{"J.S. Bach & Glenn Gould", []string{"Johann Sebastian Bach", "Glenn Gould"}},
+		{"Glenn Gould, Yo-Yo Ma", []string{"Glenn Gould", "Yo-Yo Ma"}},
+	}
+
+	for ii, tt := range tests {
+		g := SplitAliasList(nil, a, "Artist")(group{tracks: []Track{testTrack{Artist: tt.in}}})
+		tr := g.Tracks()[0]
+		if got := tr.GetStrings("Artist"); !reflect.DeepEqual(got, tt.out) {
+			t.Errorf("[%d] GetStrings(Artist) = %#v, expected %#v", ii, got, tt.out)
+		}
+		if got := tr.GetString("Artist"); got != tt.in {
+			t.Errorf("[%d] GetString(Artist) = %q, expected %q", ii, got, tt.in)
+		}
+	}
+}
@@ -0,0 +1,99 @@
+// Copyright 2015, David Howden
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Package beets defines types and methods for creating a Tchaik library representation of
+// a beets library (library.db).
+package beets
+
+import (
+	"database/sql"
+	"fmt"
+
+	_ "github.com/mattn/go-sqlite3" // SQLite driver
+
+	"github.com/amiforus/tchaik/index/migrate"
+)
+
+// Open creates a Tchaik Library implementation from the beets database file at path.
+// Play counts and ratings (which are recorded by the beets mpdstats plugin) are available
+// from the Library Stats method.
+func Open(path string) (*migrate.Library, error) {
+	db, err := sql.Open("sqlite3", path)
+	if err != nil {
+		return nil, err
+	}
+	defer db.Close()
+
+	rows, err := db.Query("SELECT * FROM items")
+	if err != nil {
+		return nil, fmt.Errorf("error reading items from database: %v", err)
+	}
+	items, err := migrate.ReadRows(rows)
+	if err != nil {
+		return nil, fmt.Errorf("error reading items from database: %v", err)
+	}
+
+	rows, err = db.Query("SELECT entity_id, key, value FROM item_attributes WHERE key IN ('play_count', 'last_played', 'rating')")
+	if err != nil {
+		return nil, fmt.Errorf("error reading item attributes from database: %v", err)
+	}
+	attrs, err := migrate.ReadRows(rows)
+	if err != nil {
+		return nil, fmt.Errorf("error reading item attributes from database: %v", err)
+	}
+
+	// Flexible attributes are stored separately, merge them into the item rows.
+	byID := make(map[int64]migrate.Row, len(items))
+	for _, r := range items {
+		byID[r.Int("id")] = r
+	}
+	for _, a := range attrs {
+		if r, ok := byID[a.Int("entity_id")]; ok {
+			r[a.String("key")] = a["value"]
+		}
+	}
+
+	tracks := make([]*migrate.Track, 0, len(items))
+	for _, r := range items {
+		if t := track(r); t != nil {
+			tracks = append(tracks, t)
+		}
+	}
+	return migrate.NewLibrary(tracks), nil
+}
+
+// track creates a Track from a row of the items table (merged with its flexible attributes).
+func track(r migrate.Row) *migrate.Track {
+	loc := r.String("path")
+	if loc == "" {
+		return nil
+	}
+
+	return &migrate.Track{
+		Name:        r.String("title"),
+		Album:       r.String("album"),
+		AlbumArtist: r.String("albumartist"),
+		Artist:      r.String("artist"),
+		Composer:    r.String("composer"),
+		Genre:       r.String("genre"),
+		Location:    loc,
+
+		TotalTime:   int(r.Float("length") * 1000), // seconds
+		Year:        r.PositiveInt("year"),
+		DiscNumber:  r.PositiveInt("disc"),
+		DiscCount:   r.PositiveInt("disctotal"),
+		TrackNumber: r.PositiveInt("track"),
+		TrackCount:  r.PositiveInt("tracktotal"),
+		BitRate:     r.PositiveInt("bitrate") / 1000, // bps
+
+		DateAdded:    migrate.UnixTime(r.Int("added")),
+		DateModified: migrate.UnixTime(r.Int("mtime")),
+
+		Stats: migrate.Stats{
+			PlayCount:  r.PositiveInt("play_count"),
+			LastPlayed: migrate.UnixTime(r.Int("last_played")),
+			Rating:     migrate.RatingFromFraction(r.Float("rating")),
+		},
+	}
+}
@@ -0,0 +1,50 @@
+// Copyright 2015, David Howden
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package beets
+
+import (
+	"testing"
+	"time"
+
+	"github.com/amiforus/tchaik/index/migrate"
+)
+
+func TestTrack(t *testing.T) {
+	if tr := track(migrate.Row{"title": "No Path"}); tr != nil {
+		t.Errorf("track() = %#v, expected nil for item without path", tr)
+	}
+
+	tr := track(migrate.Row{
+		"title":       "So What",
+		"album":       "Kind of Blue",
+		"albumartist": "Miles Davis",
+		"path":        []byte("/music/01 So What.flac"),
+		"length":      float64(562.5),
+		"year":        int64(1959),
+		"track":       int64(1),
+		"tracktotal":  int64(5),
+		"bitrate":     int64(900000),
+		"added":       float64(1420070400.25),
+		"play_count":  "3",
+		"last_played": "1430000000",
+		"rating":      "0.6",
+	})
+
+	if tr.Location != "/music/01 So What.flac" || tr.AlbumArtist != "Miles Davis" {
+		t.Errorf("track() = %#v", tr)
+	}
+	if tr.TotalTime != 562500 || tr.BitRate != 900 {
+		t.Errorf("track() TotalTime, BitRate = %d, %d, expected 562500, 900", tr.TotalTime, tr.BitRate)
+	}
+	if tr.TrackNumber != 1 || tr.TrackCount != 5 {
+		t.Errorf("track() TrackNumber, TrackCount = %d, %d, expected 1, 5", tr.TrackNumber, tr.TrackCount)
+	}
+	if !tr.DateAdded.Equal(time.Unix(1420070400, 0)) {
+		t.Errorf("track().DateAdded = %v, expected %v", tr.DateAdded, time.Unix(1420070400, 0))
+	}
+	if tr.PlayCount != 3 || !tr.LastPlayed.Equal(time.Unix(1430000000, 0)) || tr.Rating != 3 {
+		t.Errorf("track().Stats = %#v", tr.Stats)
+	}
+}
@@ -0,0 +1,370 @@
+// Copyright 2015, David Howden
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package index
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"time"
+)
+
+// The binary library format is a header followed by a stream of length-prefixed track
+// records, so that tracks can be decoded one at a time:
+//
+//	header: "TCHB" uvarint(version)
+//	record: uvarint(length) <length bytes>
+//	end:    uvarint(0)
+//
+// Within a record the string fields, int fields, flags, time fields and (from version 2) list
+// fields of a track are written in the order given by stringFields, intFields, flagFields,
+// timeFields and listFields (fields added in later versions will be appended).  Strings are
+// written as references to a string table which is built as the stream is written:
+//
+//	uvarint(0)                    empty string
+//	uvarint(1) uvarint(n) <bytes> new string (appended to the table)
+//	uvarint(i+2)                  string i of the table
+//
+// Ints are signed varints, flags are a uvarint bit set (see flagFields) and times are
+// uvarint(0) for the zero time, or uvarint(1) varint(seconds) uvarint(nanoseconds).  Lists
+// are uvarint(n) followed by n strings.
+const (
+	binaryMagic   = "TCHB"
+	binaryVersion = 2
+)
+
+// stringFields returns pointers to the string fields of t in binary format order.
+func (t *track) stringFields() []*string {
+	return []*string{
+		&t.ID, &t.Name, &t.Album, &t.AlbumArtist, &t.Artist, &t.Composer, &t.Genre, &t.Location, &t.Kind,
+		&t.Comment, &t.SortName, &t.SortAlbum, &t.SortAlbumArtist, &t.SortArtist, &t.SortComposer,
+		&t.Label, &t.CatalogueNumber, &t.ISRC, &t.MusicBrainzTrackID, &t.MusicBrainzAlbumID,
+		&t.MusicBrainzArtistID, &t.MusicBrainzAlbumArtistID,
+	}
+}
+
+// intFields returns pointers to the int fields of t in binary format order.
+func (t *track) intFields() []*int {
+	return []*int{
+		&t.TotalTime, &t.Year, &t.DiscNumber, &t.TrackNumber, &t.TrackCount, &t.DiscCount, &t.BitRate,
+		&t.BPM, &t.Size, &t.TrackGain, &t.TrackPeak, &t.AlbumGain, &t.AlbumPeak,
+	}
+}
+
+// flagFields returns pointers to the bool fields of t in binary format (bit) order.
+func (t *track) flagFields() []*bool {
+	return []*bool{&t.Compilation, &t.HasLyrics}
+}
+
+// timeFields returns pointers to the time fields of t in binary format order.
+func (t *track) timeFields() []*time.Time {
+	return []*time.Time{&t.DateAdded, &t.DateModified}
+}
+
+// listFields returns pointers to the list fields of t in binary format order.
+func (t *track) listFields() []*[]string {
+	return []*[]string{&t.Artists, &t.AlbumArtists, &t.Composers}
+}
+
+// WriteBinaryTo writes the Library data to the writer using the binary library format, which
+// is more compact and much faster to read than gzipped-JSON (see WriteTo).  Tracks are
+// written in ID order.
+func WriteBinaryTo(l Library, w io.Writer) error {
+	lib, ok := l.(*library)
+	if !ok {
+		lib = Convert(l, "ID")
+	}
+
+	ids := make([]string, 0, len(lib.trks))
+	for id := range lib.trks {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+
+	bw := bufio.NewWriter(w)
+	e := &binaryEncoder{
+		table: make(map[string]uint64),
+	}
+	e.buf = append(e.buf, binaryMagic...)
+	e.uvarint(binaryVersion)
+	if _, err := bw.Write(e.buf); err != nil {
+		return err
+	}
+
+	var rec [binary.MaxVarintLen64]byte
+	for _, id := range ids {
+		e.buf = e.buf[:0]
+		e.track(lib.trks[id])
+		if len(e.buf) > maxRecordSize {
+			return fmt.Errorf("track record too large: %v (%d bytes)", id, len(e.buf))
+		}
+
+		n := binary.PutUvarint(rec[:], uint64(len(e.buf)))
+		if _, err := bw.Write(rec[:n]); err != nil {
+			return err
+		}
+		if _, err := bw.Write(e.buf); err != nil {
+			return err
+		}
+	}
+
+	if err := bw.WriteByte(0); err != nil {
+		return err
+	}
+	return bw.Flush()
+}
+
+// binaryEncoder encodes tracks into buf, maintaining the string table.
+type binaryEncoder struct {
+	buf     []byte
+	table   map[string]uint64
+	scratch [binary.MaxVarintLen64]byte
+}
+
+func (e *binaryEncoder) uvarint(x uint64) {
+	n := binary.PutUvarint(e.scratch[:], x)
+	e.buf = append(e.buf, e.scratch[:n]...)
+}
+
+func (e *binaryEncoder) varint(x int64) {
+	n := binary.PutVarint(e.scratch[:], x)
+	e.buf = append(e.buf, e.scratch[:n]...)
+}
+
+func (e *binaryEncoder) string(s string) {
+	if s == "" {
+		e.uvarint(0)
+		return
+	}
+	if i, ok := e.table[s]; ok {
+		e.uvarint(i + 2)
+		return
+	}
+	e.table[s] = uint64(len(e.table))
+	e.uvarint(1)
+	e.uvarint(uint64(len(s)))
+	e.buf = append(e.buf, s...)
+}
+
+func (e *binaryEncoder) time(t time.Time) {
+	if t.IsZero() {
+		e.uvarint(0)
+		return
+	}
+	e.uvarint(1)
+	e.varint(t.Unix())
+	e.uvarint(uint64(t.Nanosecond()))
+}
+
+func (e *binaryEncoder) track(t *track) {
+	for _, s := range t.stringFields() {
+		e.string(*s)
+	}
+	for _, n := range t.intFields() {
+		e.varint(int64(*n))
+	}
+	var flags uint64
+	for i, b := range t.flagFields() {
+		if *b {
+			flags |= 1 << uint(i)
+		}
+	}
+	e.uvarint(flags)
+	for _, x := range t.timeFields() {
+		e.time(*x)
+	}
+	for _, ss := range t.listFields() {
+		e.uvarint(uint64(len(*ss)))
+		for _, s := range *ss {
+			e.string(s)
+		}
+	}
+}
+
+// maxRecordSize is the largest track record which will be written or read, to guard against
+// allocating huge buffers when reading corrupt files.
+const maxRecordSize = 16 << 20
+
+// errShortRecord is returned when a record ends before all of its fields have been read.
+var errShortRecord = errors.New("unexpected end of track record")
+
+// readBinary reads a library in the binary library format from r, decoding one track
+// record at a time.  Strings are interned: repeated values (i.e. Album, Artist) share
+// the same memory.
+func readBinary(r *bufio.Reader) (*library, error) {
+	magic := make([]byte, len(binaryMagic))
+	if _, err := io.ReadFull(r, magic); err != nil {
+		return nil, err
+	}
+	if string(magic) != binaryMagic {
+		return nil, errors.New("invalid binary library header")
+	}
+	v, err := binary.ReadUvarint(r)
+	if err != nil {
+		return nil, err
+	}
+	if v > binaryVersion {
+		return nil, fmt.Errorf("unsupported binary library version: %d", v)
+	}
+
+	l := &library{
+		trks: make(map[string]*track),
+	}
+	d := &binaryDecoder{version: v}
+	for {
+		n, err := binary.ReadUvarint(r)
+		if err != nil {
+			if err == io.EOF {
+				err = io.ErrUnexpectedEOF
+			}
+			return nil, err
+		}
+		if n == 0 {
+			return l, nil
+		}
+
+		if n > maxRecordSize {
+			return nil, fmt.Errorf("track record too large: %d bytes", n)
+		}
+		if uint64(cap(d.buf)) < n {
+			d.buf = make([]byte, n)
+		}
+		d.buf = d.buf[:n]
+		if _, err := io.ReadFull(r, d.buf); err != nil {
+			return nil, err
+		}
+
+		t, err := d.track()
+		if err != nil {
+			return nil, fmt.Errorf("error reading track %d: %v", len(l.trks)+1, err)
+		}
+		l.trks[t.ID] = t
+	}
+}
+
+// binaryDecoder decodes track records from buf, maintaining the string table.
+type binaryDecoder struct {
+	version uint64
+	buf     []byte
+	table   []string
+}
+
+func (d *binaryDecoder) uvarint() (uint64, error) {
+	x, n := binary.Uvarint(d.buf)
+	if n <= 0 {
+		return 0, errShortRecord
+	}
+	d.buf = d.buf[n:]
+	return x, nil
+}
+
+func (d *binaryDecoder) varint() (int64, error) {
+	x, n := binary.Varint(d.buf)
+	if n <= 0 {
+		return 0, errShortRecord
+	}
+	d.buf = d.buf[n:]
+	return x, nil
+}
+
+func (d *binaryDecoder) string() (string, error) {
+	x, err := d.uvarint()
+	if err != nil {
+		return "", err
+	}
+	switch x {
+	case 0:
+		return "", nil
+	case 1:
+		n, err := d.uvarint()
+		if err != nil {
+			return "", err
+		}
+		if uint64(len(d.buf)) < n {
+			return "", errShortRecord
+		}
+		s := string(d.buf[:n])
+		d.buf = d.buf[n:]
+		d.table = append(d.table, s)
+		return s, nil
+	}
+	if x-2 >= uint64(len(d.table)) {
+		return "", fmt.Errorf("invalid string reference: %d", x-2)
+	}
+	return d.table[x-2], nil
+}
+
+func (d *binaryDecoder) time() (time.Time, error) {
+	x, err := d.uvarint()
+	if err != nil || x == 0 {
+		return time.Time{}, err
+	}
+	sec, err := d.varint()
+	if err != nil {
+		return time.Time{}, err
+	}
+	nsec, err := d.uvarint()
+	if err != nil {
+		return time.Time{}, err
+	}
+	return time.Unix(sec, int64(nsec)), nil
+}
+
+func (d *binaryDecoder) track() (*track, error) {
+	t := &track{}
+	for _, s := range t.stringFields() {
+		x, err := d.string()
+		if err != nil {
+			return nil, err
+		}
+		*s = x
+	}
+	for _, n := range t.intFields() {
+		x, err := d.varint()
+		if err != nil {
+			return nil, err
+		}
+		*n = int(x)
+	}
+	flags, err := d.uvarint()
+	if err != nil {
+		return nil, err
+	}
+	for i, b := range t.flagFields() {
+		*b = flags&(1<<uint(i)) != 0
+	}
+	for _, x := range t.timeFields() {
+		v, err := d.time()
+		if err != nil {
+			return nil, err
+		}
+		*x = v
+	}
+	if d.version < 2 {
+		return t, nil
+	}
+	for _, ss := range t.listFields() {
+		n, err := d.uvarint()
+		if err != nil {
+			return nil, err
+		}
+		if n > uint64(len(d.buf)) {
+			return nil, errShortRecord
+		}
+		if n == 0 {
+			continue
+		}
+		*ss = make([]string, n)
+		for i := range *ss {
+			if (*ss)[i], err = d.string(); err != nil {
+				return nil, err
+			}
+		}
+	}
+	return t, nil
+}
@@ -0,0 +1,132 @@
+// Copyright 2015, David Howden
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package index
+
+import (
+	"bytes"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestLibraryBinaryEncodeDecode(t *testing.T) {
+	tr1 := tr
+	tr1.DateAdded = time.Unix(1420070400, 123456789)
+	tr1.DateModified = time.Time{}
+	tr1.TrackGain = -654
+	tr1.Artists = []string{"Artist A", "Artist B"}
+	tr1.Composers = []string{"Composer A", "Composer B"}
+
+	tr2 := tr1
+	tr2.ID = "ID2"
+	tr2.Name = "Name2"
+	tr2.Compilation = false
+	tr2.Artists = nil
+
+	l := &library{
+		trks: map[string]*track{
+			tr1.ID: &tr1,
+			tr2.ID: &tr2,
+		},
+	}
+
+	buf := &bytes.Buffer{}
+	if err := WriteBinaryTo(l, buf); err != nil {
+		t.Fatalf("unexpected error in WriteBinaryTo: %v", err)
+	}
+
+	got, err := ReadFrom(buf)
+	if err != nil {
+		t.Fatalf("unexpected error in ReadFrom: %v", err)
+	}
+
+	if len(got.Tracks()) != 2 {
+		t.Errorf("expected 2 tracks, got: %d", len(got.Tracks()))
+	}
+	for _, expected := range []*track{&tr1, &tr2} {
+		gotTrack, ok := got.Track(expected.ID)
+		if !ok {
+			t.Errorf("expected track with ID %#v", expected.ID)
+			continue
+		}
+		if !reflect.DeepEqual(gotTrack, expected) {
+			t.Errorf("Encode -> Decode inconsistent, got: %#v, expected: %#v", gotTrack, expected)
+		}
+	}
+}
+
+func TestLibraryBinaryStringTable(t *testing.T) {
+	l := &library{
+		trks: map[string]*track{
+			"1": {ID: "1", Album: "Album", Artist: "Artist"},
+			"2": {ID: "2", Album: "Album", Artist: "Artist"},
+		},
+	}
+
+	buf := &bytes.Buffer{}
+	if err := WriteBinaryTo(l, buf); err != nil {
+		t.Fatalf("unexpected error in WriteBinaryTo: %v", err)
+	}
+	if n := bytes.Count(buf.Bytes(), []byte("Album")); n != 1 {
+		t.Errorf("expected repeated string to be written once, got %d", n)
+	}
+}
+
+func TestLibraryBinaryErrors(t *testing.T) {
+	l := &library{
+		trks: map[string]*track{
+			"1": {ID: "1", Name: "Name"},
+		},
+	}
+	buf := &bytes.Buffer{}
+	if err := WriteBinaryTo(l, buf); err != nil {
+		t.Fatalf("unexpected error in WriteBinaryTo: %v", err)
+	}
+	b := buf.Bytes()
+
+	tests := []struct {
+		desc string
+		b    []byte
+	}{
+		{"truncated", b[:len(b)-5]},
+		{"missing end", b[:len(b)-1]},
+		{"unsupported version", append([]byte(binaryMagic+"\x7f"), b[len(binaryMagic)+1:]...)},
+		{"record too large", append([]byte(binaryMagic+"\x02"), 0x80, 0x80, 0x80, 0x80, 0x80, 0x80, 0x01)},
+	}
+
+	for _, tt := range tests {
+		if _, err := ReadFrom(bytes.NewReader(tt.b)); err == nil {
+			t.Errorf("%v: expected error from ReadFrom", tt.desc)
+		}
+	}
+}
+
+func TestLibraryBinaryReadVersion1(t *testing.T) {
+	expected := &track{ID: "1", Name: "Name", Artist: "Artist"}
+
+	// Version 1 records don't have list fields (which are written last).
+	e := &binaryEncoder{
+		table: make(map[string]uint64),
+	}
+	e.track(expected)
+	rec := e.buf[:len(e.buf)-len(expected.listFields())]
+
+	b := []byte(binaryMagic + "\x01")
+	b = append(b, byte(len(rec)))
+	b = append(b, rec...)
+	b = append(b, 0)
+
+	l, err := ReadFrom(bytes.NewReader(b))
+	if err != nil {
+		t.Fatalf("unexpected error in ReadFrom: %v", err)
+	}
+	got, ok := l.Track("1")
+	if !ok {
+		t.Fatalf("expected track with ID %#v", "1")
+	}
+	if !reflect.DeepEqual(got, expected) {
+		t.Errorf("ReadFrom() track = %#v, expected: %#v", got, expected)
+	}
+}
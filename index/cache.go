@@ -0,0 +1,107 @@
+// Copyright 2015, David Howden
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package index
+
+import (
+	"container/list"
+	"strings"
+	"sync"
+)
+
+// GroupCache is a concurrency-safe LRU cache of groups, used by RootCollection to keep the
+// groups it builds.  A nil *GroupCache doesn't cache anything.
+type GroupCache struct {
+	sync.Mutex // protects ll and m
+
+	size int
+	ll   *list.List               // of *cacheEntry, most recently used first
+	m    map[string]*list.Element // key -> element of ll
+}
+
+type cacheEntry struct {
+	key   string
+	group Group
+}
+
+// NewGroupCache creates a GroupCache which holds at most size groups.
+func NewGroupCache(size int) *GroupCache {
+	return &GroupCache{
+		size: size,
+		ll:   list.New(),
+		m:    make(map[string]*list.Element),
+	}
+}
+
+// Len returns the number of groups in the cache.
+func (c *GroupCache) Len() int {
+	if c == nil {
+		return 0
+	}
+	c.Lock()
+	defer c.Unlock()
+
+	return c.ll.Len()
+}
+
+// Purge removes all the groups from the cache.
+func (c *GroupCache) Purge() {
+	if c == nil {
+		return
+	}
+	c.Lock()
+	defer c.Unlock()
+
+	c.ll.Init()
+	c.m = make(map[string]*list.Element)
+}
+
+// get returns the group for the path, and true if it is in the cache.
+func (c *GroupCache) get(p Path) (Group, bool) {
+	if c == nil {
+		return nil, false
+	}
+	c.Lock()
+	defer c.Unlock()
+
+	e, ok := c.m[cacheKey(p)]
+	if !ok {
+		return nil, false
+	}
+	c.ll.MoveToFront(e)
+	return e.Value.(*cacheEntry).group, true
+}
+
+// add adds the group for the path to the cache, removing the least recently used group if
+// the cache is full, and returns the cached group (which is g unless another has been added
+// for the path since it was built).
+func (c *GroupCache) add(p Path, g Group) Group {
+	if c == nil || c.size <= 0 {
+		return g
+	}
+	c.Lock()
+	defer c.Unlock()
+
+	k := cacheKey(p)
+	if e, ok := c.m[k]; ok {
+		c.ll.MoveToFront(e)
+		return e.Value.(*cacheEntry).group
+	}
+
+	c.m[k] = c.ll.PushFront(&cacheEntry{k, g})
+	if c.ll.Len() > c.size {
+		e := c.ll.Back()
+		c.ll.Remove(e)
+		delete(c.m, e.Value.(*cacheEntry).key)
+	}
+	return g
+}
+
+func cacheKey(p Path) string {
+	ks := make([]string, len(p))
+	for i, k := range p {
+		ks[i] = string(k)
+	}
+	return strings.Join(ks, "\x00")
+}
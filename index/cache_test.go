@@ -0,0 +1,110 @@
+// Copyright 2015, David Howden
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package index
+
+import (
+	"reflect"
+	"sync"
+	"testing"
+)
+
+func TestGroupCache(t *testing.T) {
+	c := NewGroupCache(2)
+	a, b, d := group{name: "A"}, group{name: "B"}, group{name: "D"}
+
+	c.add(Path{"Root", "A"}, a)
+	c.add(Path{"Root", "B"}, b)
+	if _, ok := c.get(Path{"Root", "A"}); !ok {
+		t.Errorf("get(Root, A) not found")
+	}
+
+	// B is the least recently used, and so is removed.
+	c.add(Path{"Root", "D"}, d)
+	if n := c.Len(); n != 2 {
+		t.Errorf("Len() = %d, expected 2", n)
+	}
+	if _, ok := c.get(Path{"Root", "B"}); ok {
+		t.Errorf("get(Root, B) found, expected it to be removed")
+	}
+	for _, k := range []Key{"A", "D"} {
+		if _, ok := c.get(Path{"Root", k}); !ok {
+			t.Errorf("get(Root, %v) not found", k)
+		}
+	}
+
+	// Groups added for a path which is already cached are replaced by the cached group.
+	if g := c.add(Path{"Root", "A"}, b); !reflect.DeepEqual(g, a) {
+		t.Errorf("add(Root, A) = %v, expected %v", g, a)
+	}
+
+	c.Purge()
+	if n := c.Len(); n != 0 {
+		t.Errorf("Len() after Purge() = %d, expected 0", n)
+	}
+	if _, ok := c.get(Path{"Root", "A"}); ok {
+		t.Errorf("get(Root, A) found after Purge()")
+	}
+}
+
+func TestGroupCacheNil(t *testing.T) {
+	var c *GroupCache
+	a := group{name: "A"}
+	if g := c.add(Path{"Root", "A"}, a); !reflect.DeepEqual(g, a) {
+		t.Errorf("add(Root, A) = %v, expected %v", g, a)
+	}
+	if _, ok := c.get(Path{"Root", "A"}); ok {
+		t.Errorf("get(Root, A) found in nil cache")
+	}
+	if n := c.Len(); n != 0 {
+		t.Errorf("Len() = %d, expected 0", n)
+	}
+	c.Purge()
+}
+
+func TestRootCollectionCache(t *testing.T) {
+	l := &library{trks: map[string]*track{
+		"1": {ID: "1", Name: "Freddie Freeloader", Album: "Kind of Blue", TrackNumber: 2},
+		"2": {ID: "2", Name: "So What", Album: "Kind of Blue", TrackNumber: 1},
+	}}
+	c := CollectAlbums(l, GroupByAlbum, DefaultCollation)
+	k := c.Keys()[0]
+	before := append([]Track(nil), c.Get(k).Tracks()...)
+
+	r := &RootCollection{Collection: c, Cache: NewGroupCache(10)}
+
+	var wg sync.WaitGroup
+	groups := make([]Group, 10)
+	for i := range groups {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			groups[i] = r.Get(k)
+		}(i)
+	}
+	wg.Wait()
+
+	for i, g := range groups {
+		if !reflect.DeepEqual(g, groups[0]) {
+			t.Errorf("[%d] Get(%v) = %v, expected %v", i, k, g, groups[0])
+		}
+	}
+	if n := r.Cache.Len(); n != 1 {
+		t.Errorf("Cache.Len() = %d, expected 1", n)
+	}
+
+	var names []string
+	Walk(groups[0], nil, func(t Track, _ Path) error {
+		names = append(names, t.GetString("Name"))
+		return nil
+	})
+	if expected := []string{"So What", "Freddie Freeloader"}; !reflect.DeepEqual(names, expected) {
+		t.Errorf("Get(%v) tracks = %v, expected %v", k, names, expected)
+	}
+
+	// The tracks of the underlying collection are not reordered.
+	if after := c.Get(k).Tracks(); !reflect.DeepEqual(after, before) {
+		t.Errorf("Get(%v) changed the tracks of the collection to %v, expected %v", k, after, before)
+	}
+}
@@ -19,6 +19,12 @@ type Store interface {
 
 	// List retuns a list of paths in the checklist.
 	List() []index.Path
+
+	// Migrate maps the paths in the store using the index.PathMap (i.e. after the library has
+	// been rebuilt), and returns the paths which could not be mapped (which are kept, so
+	// that they can be resolved by a later migration).  Paths which are mapped to the
+	// same path are combined.
+	Migrate(index.PathMap) ([]index.Path, error)
 }
 
 // NewStore creates a basic implementation of a checklist store, using the given path as the
@@ -77,3 +83,13 @@ func (s *store) List() []index.Path {
 	}
 	return result
 }
+
+// Migrate implements Store.
+func (s *store) Migrate(pm index.PathMap) ([]index.Path, error) {
+	s.Lock()
+	defer s.Unlock()
+
+	return s.store.Migrate(&s.m, pm, func(x, y interface{}) interface{} {
+		return x.(bool) || y.(bool)
+	})
+}
@@ -0,0 +1,88 @@
+// Copyright 2015, David Howden
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Package clementine defines types and methods for creating a Tchaik library representation
+// of a Clementine or Strawberry library (SQLite database).
+package clementine
+
+import (
+	"database/sql"
+	"fmt"
+	"strings"
+	"time"
+
+	_ "github.com/mattn/go-sqlite3" // SQLite driver
+
+	"github.com/amiforus/tchaik/index/migrate"
+)
+
+// Open creates a Tchaik Library implementation from the Clementine (clementine.db) or
+// Strawberry (strawberry.db) database file at path.  Play counts and ratings are available
+// from the Library Stats method.
+func Open(path string) (*migrate.Library, error) {
+	db, err := sql.Open("sqlite3", path)
+	if err != nil {
+		return nil, err
+	}
+	defer db.Close()
+
+	rows, err := db.Query("SELECT * FROM songs")
+	if err != nil {
+		return nil, fmt.Errorf("error reading songs from database: %v", err)
+	}
+	songs, err := migrate.ReadRows(rows)
+	if err != nil {
+		return nil, fmt.Errorf("error reading songs from database: %v", err)
+	}
+
+	var tracks []*migrate.Track
+	for _, r := range songs {
+		if t := track(r); t != nil {
+			tracks = append(tracks, t)
+		}
+	}
+	return migrate.NewLibrary(tracks), nil
+}
+
+// track creates a Track from a row of the songs table, or returns nil if the song is
+// unavailable (or isn't a local file).
+func track(r migrate.Row) *migrate.Track {
+	if r.Int("unavailable") != 0 {
+		return nil
+	}
+
+	loc := r.String("filename") // Clementine
+	if r.Has("url") {
+		loc = r.String("url") // Strawberry
+	}
+	if loc == "" || strings.Contains(loc, "://") && !strings.HasPrefix(loc, "file://") {
+		return nil
+	}
+	loc = migrate.FileLocation(loc)
+
+	return &migrate.Track{
+		Name:        r.String("title"),
+		Album:       r.String("album"),
+		AlbumArtist: r.String("albumartist"),
+		Artist:      r.String("artist"),
+		Composer:    r.String("composer"),
+		Genre:       r.String("genre"),
+		Location:    loc,
+
+		TotalTime:   int(r.Int("length") / int64(time.Millisecond)), // nanoseconds
+		Year:        r.PositiveInt("year"),
+		DiscNumber:  r.PositiveInt("disc"),
+		TrackNumber: r.PositiveInt("track"),
+		BitRate:     r.PositiveInt("bitrate"),
+
+		DateAdded:    migrate.UnixTime(r.Int("ctime")),
+		DateModified: migrate.UnixTime(r.Int("mtime")),
+
+		Stats: migrate.Stats{
+			PlayCount:  r.PositiveInt("playcount"),
+			LastPlayed: migrate.UnixTime(r.Int("lastplayed")),
+			Rating:     migrate.RatingFromFraction(r.Float("rating")),
+		},
+	}
+}
@@ -0,0 +1,70 @@
+// Copyright 2015, David Howden
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package clementine
+
+import (
+	"testing"
+
+	"github.com/amiforus/tchaik/index/migrate"
+)
+
+func TestTrack(t *testing.T) {
+	tests := []struct {
+		row      migrate.Row
+		location string
+	}{
+		{migrate.Row{"filename": []byte("file:///music/A%20B/01.flac"), "unavailable": int64(0)}, "/music/A B/01.flac"},
+		{migrate.Row{"url": "file:///music/01.flac", "unavailable": int64(0)}, "/music/01.flac"},
+		{migrate.Row{"filename": []byte("file:///music/01.flac"), "unavailable": int64(1)}, ""},
+		{migrate.Row{"url": "http://example.com/stream", "unavailable": int64(0)}, ""},
+		{migrate.Row{"url": "spotify://track", "unavailable": int64(0)}, ""},
+	}
+
+	for ii, tt := range tests {
+		tr := track(tt.row)
+		if tt.location == "" {
+			if tr != nil {
+				t.Errorf("[%d] track() = %#v, expected nil", ii, tr)
+			}
+			continue
+		}
+		if tr == nil {
+			t.Errorf("[%d] track() = nil, expected track", ii)
+			continue
+		}
+		if tr.Location != tt.location {
+			t.Errorf("[%d] track().Location = %q, expected %q", ii, tr.Location, tt.location)
+		}
+	}
+}
+
+func TestTrackFields(t *testing.T) {
+	tr := track(migrate.Row{
+		"title":      "So What",
+		"album":      "Kind of Blue",
+		"artist":     "Miles Davis",
+		"filename":   []byte("file:///music/01.mp3"),
+		"length":     int64(562000000000),
+		"year":       int64(1959),
+		"track":      int64(1),
+		"disc":       int64(-1),
+		"playcount":  int64(3),
+		"lastplayed": int64(-1),
+		"rating":     float64(0.8),
+	})
+
+	if tr.Name != "So What" || tr.Album != "Kind of Blue" || tr.Artist != "Miles Davis" {
+		t.Errorf("track() = %#v", tr)
+	}
+	if tr.TotalTime != 562000 {
+		t.Errorf("track().TotalTime = %d, expected %d", tr.TotalTime, 562000)
+	}
+	if tr.Year != 1959 || tr.TrackNumber != 1 || tr.DiscNumber != 0 {
+		t.Errorf("track() Year, TrackNumber, DiscNumber = %d, %d, %d, expected 1959, 1, 0", tr.Year, tr.TrackNumber, tr.DiscNumber)
+	}
+	if tr.PlayCount != 3 || !tr.LastPlayed.IsZero() || tr.Rating != 4 {
+		t.Errorf("track().Stats = %#v", tr.Stats)
+	}
+}
@@ -0,0 +1,180 @@
+// Copyright 2015, David Howden
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package index
+
+import (
+	"bytes"
+	"fmt"
+	"sort"
+	"strings"
+
+	"golang.org/x/text/collate"
+	"golang.org/x/text/language"
+)
+
+// DefaultArticles are the leading articles which are ignored when ordering names, keyed
+// by language.
+var DefaultArticles = map[string][]string{
+	"en": {"the", "a", "an"},
+	"de": {"der", "die", "das"},
+	"es": {"el", "la", "los", "las"},
+	"fr": {"le", "la", "les", "l'"},
+	"it": {"il", "lo", "la", "i", "gli", "le", "l'"},
+	"nl": {"de", "het", "een"},
+}
+
+// Collation orders names (i.e. of groups and filter items): by their sort names (i.e. from
+// the SortAlbum and SortArtist fields) when they are set, ignoring leading articles, and
+// using the Unicode collation of a locale.
+type Collation struct {
+	tag      language.Tag
+	articles []string
+}
+
+// DefaultCollation is the Collation for English.
+var DefaultCollation = &Collation{
+	tag:      language.English,
+	articles: DefaultArticles["en"],
+}
+
+// NewCollation creates a Collation for the locale (i.e. "en", "fr-CA") which ignores the
+// leading articles.  If articles is nil then the DefaultArticles of the locale's language
+// are used.
+func NewCollation(locale string, articles []string) (*Collation, error) {
+	tag, err := language.Parse(locale)
+	if err != nil {
+		return nil, fmt.Errorf("invalid locale '%v': %v", locale, err)
+	}
+
+	if articles == nil {
+		base, _ := tag.Base()
+		articles = DefaultArticles[base.String()]
+	}
+	c := &Collation{
+		tag:      tag,
+		articles: make([]string, len(articles)),
+	}
+	for i, a := range articles {
+		c.articles[i] = strings.ToLower(a)
+	}
+	return c, nil
+}
+
+// trimArticle removes a leading article from the name.
+func (c *Collation) trimArticle(name string) string {
+	for _, a := range c.articles {
+		if len(name) <= len(a) || !strings.EqualFold(name[:len(a)], a) {
+			continue
+		}
+		if strings.HasSuffix(a, "'") {
+			return name[len(a):]
+		}
+		if name[len(a)] == ' ' {
+			return strings.TrimSpace(name[len(a):])
+		}
+	}
+	return name
+}
+
+// sortKeys returns the collation keys for the names, where sortNames (if non-nil) are the
+// sort names to use instead when they are set.
+func (c *Collation) sortKeys(names, sortNames []string) [][]byte {
+	col := collate.New(c.tag)
+	buf := &collate.Buffer{}
+	keys := make([][]byte, len(names))
+	for i, n := range names {
+		if sortNames != nil && sortNames[i] != "" {
+			n = sortNames[i]
+		} else {
+			n = c.trimArticle(n)
+		}
+		keys[i] = append([]byte(nil), col.KeyFromString(buf, n)...)
+		buf.Reset()
+	}
+	return keys
+}
+
+// collationSorter orders names by their collation keys, and then by the names.
+type collationSorter struct {
+	names []string
+	keys  [][]byte
+}
+
+func (s collationSorter) Len() int { return len(s.names) }
+
+func (s collationSorter) Swap(i, j int) {
+	s.names[i], s.names[j] = s.names[j], s.names[i]
+	s.keys[i], s.keys[j] = s.keys[j], s.keys[i]
+}
+
+func (s collationSorter) Less(i, j int) bool {
+	if x := bytes.Compare(s.keys[i], s.keys[j]); x != 0 {
+		return x < 0
+	}
+	return s.names[i] < s.names[j]
+}
+
+// sorter returns a sort.Interface which orders the names (see sortKeys).
+func (c *Collation) sorter(names, sortNames []string) collationSorter {
+	return collationSorter{
+		names: names,
+		keys:  c.sortKeys(names, sortNames),
+	}
+}
+
+// SortKeys sorts the keys of the collection (in place) by the sort names of its groups (see
+// GroupSortName).
+func (c *Collation) SortKeys(col Collection) {
+	keys := col.Keys()
+	names := make([]string, len(keys))
+	sortNames := make([]string, len(keys))
+	for i, k := range keys {
+		g := col.Get(k)
+		names[i] = g.Name()
+		sortNames[i] = GroupSortName(g)
+	}
+	sort.Sort(ParallelSort(c.sorter(names, sortNames), keySlice(keys)))
+}
+
+// sortNameFields are the fields which have sort names, and the fields of their sort names.
+var sortNameFields = []struct {
+	field, sort string
+}{
+	{"Album", "SortAlbum"},
+	{"AlbumArtist", "SortAlbumArtist"},
+	{"Artist", "SortArtist"},
+	{"Composer", "SortComposer"},
+	{"Name", "SortName"},
+}
+
+// sortNameField returns the field of the sort name for the field, or the empty string if
+// there isn't one.
+func sortNameField(field string) string {
+	for _, f := range sortNameFields {
+		if f.field == field {
+			return f.sort
+		}
+	}
+	return ""
+}
+
+// GroupSortName returns the sort name of the Group: the sort name of the first field of its
+// first track which is the same as the group name (i.e. SortAlbum for a group named by
+// Album), or the empty string if there isn't one.
+func GroupSortName(g Group) string {
+	t := firstTrack(g)
+	if t == nil {
+		return ""
+	}
+	name := g.Name()
+	for _, f := range sortNameFields {
+		if t.GetString(f.field) == name {
+			if s := t.GetString(f.sort); s != "" {
+				return s
+			}
+		}
+	}
+	return ""
+}
@@ -0,0 +1,105 @@
+// Copyright 2015, David Howden
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package index
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/amiforus/tchaik/index/attr"
+)
+
+func TestNewCollation(t *testing.T) {
+	tests := []struct {
+		locale   string
+		articles []string
+		in, out  string
+	}{
+		{"en", nil, "The Beatles", "Beatles"},
+		{"en", nil, "the the", "the"},
+		{"en", nil, "Theatre", "Theatre"},
+		{"en", nil, "The", "The"},
+		{"en", nil, "A Night at the Opera", "Night at the Opera"},
+		{"en-GB", nil, "An Innocent Man", "Innocent Man"},
+		{"fr", nil, "L'Enfance du Christ", "Enfance du Christ"},
+		{"fr", nil, "Les Misérables", "Misérables"},
+		{"fr", nil, "The Beatles", "The Beatles"},
+		{"en", []string{}, "The Beatles", "The Beatles"},
+		{"en", []string{"Die"}, "Die Zauberflöte", "Zauberflöte"},
+	}
+
+	for ii, tt := range tests {
+		c, err := NewCollation(tt.locale, tt.articles)
+		if err != nil {
+			t.Errorf("[%d] NewCollation(%#v, %#v) returned unexpected error: %v", ii, tt.locale, tt.articles, err)
+			continue
+		}
+		if got := c.trimArticle(tt.in); got != tt.out {
+			t.Errorf("[%d] trimArticle(%#v) = %#v, expected %#v", ii, tt.in, got, tt.out)
+		}
+	}
+
+	if _, err := NewCollation("not a locale", nil); err == nil {
+		t.Errorf("NewCollation(\"not a locale\", nil) returned nil error")
+	}
+}
+
+func TestCollationSortKeys(t *testing.T) {
+	tracks := albumTracker{
+		{Album: "Zappa in New York"},
+		{Album: "Élgar"},
+		{Album: "The Beatles"},
+		{Album: "abbey road"},
+		{Album: "Carmen"},
+		{Album: "Eroica"},
+		{Album: "Led Zeppelin IV", SortAlbum: "Four Symbols"},
+	}
+
+	c := Collect(tracks, By(attr.String("Album")))
+	DefaultCollation.SortKeys(c)
+
+	got := names(c)
+	expected := []string{
+		"abbey road",
+		"The Beatles",
+		"Carmen",
+		"Élgar",
+		"Eroica",
+		"Led Zeppelin IV",
+		"Zappa in New York",
+	}
+	if !reflect.DeepEqual(got, expected) {
+		t.Errorf("SortKeys() = %#v, expected %#v", got, expected)
+	}
+
+	// Keys are sorted along with the names.
+	for i, k := range c.Keys() {
+		if n := c.Get(k).Name(); n != expected[i] {
+			t.Errorf("Get(Keys()[%d]).Name() = %#v, expected %#v", i, n, expected[i])
+		}
+	}
+}
+
+func TestFilterCollection(t *testing.T) {
+	tracks := albumTracker{
+		{Name: "1", Album: "A", Artist: "The Who"},
+		{Name: "2", Album: "B", Artist: "Björk"},
+		{Name: "3", Album: "C", Artist: "Bob Dylan", SortArtist: "Dylan, Bob"},
+		{Name: "4", Album: "D", Artist: "Cream"},
+		{Name: "5", Album: "D", Artist: "Bob Dylan"},
+	}
+
+	c := Collect(tracks, By(attr.String("Album")))
+	f := FilterCollection(c, attr.Strings("Artist"), DefaultCollation)
+
+	var got []string
+	for _, x := range f.Items() {
+		got = append(got, x.Name())
+	}
+	expected := []string{"Björk", "Cream", "Bob Dylan", "The Who"}
+	if !reflect.DeepEqual(got, expected) {
+		t.Errorf("FilterCollection(Artist) = %#v, expected %#v", got, expected)
+	}
+}
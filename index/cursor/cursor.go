@@ -7,7 +7,10 @@ package cursor
 
 import (
 	"fmt"
+	"math/rand"
+	"reflect"
 	"sync"
+	"time"
 
 	"github.com/amiforus/tchaik/index"
 	"github.com/amiforus/tchaik/index/playlist"
@@ -24,16 +27,48 @@ func (p Position) Empty() bool {
 	return len(p.Path) == 0
 }
 
+// Shuffle is a type which represents a shuffle mode.
+type Shuffle string
+
+// Shuffle modes.
+const (
+	ShuffleOff   Shuffle = ""      // play tracks in order
+	ShuffleTrack Shuffle = "track" // play all tracks in a random order
+	ShuffleItem  Shuffle = "item"  // play items in a random order, tracks within items in order
+)
+
+// Repeat is a type which represents a repeat mode.
+type Repeat string
+
+// Repeat modes.
+const (
+	RepeatOff Repeat = ""    // stop at the end of the playlist
+	RepeatOne Repeat = "one" // repeat the current track
+	RepeatAll Repeat = "all" // go back to the start at the end of the playlist
+)
+
 // Cursor is a moveable marker on a playlist.
 type Cursor struct {
-	sync.Mutex // protects Current, Next and Previous
+	sync.Mutex // protects all fields
 
 	Current  Position `json:"current"`
 	Next     Position `json:"next"`
 	Previous Position `json:"previous"`
 
+	Shuffle Shuffle `json:"shuffle,omitempty"`
+	Repeat  Repeat  `json:"repeat,omitempty"`
+
+	// Seed and Start determine the shuffle order: the order is fixed by Seed, and begins
+	// at Start (the position at which shuffle was enabled).
+	Seed  int64    `json:"seed,omitempty"`
+	Start Position `json:"start"`
+
 	p *playlist.Playlist
 	c index.Collection
+
+	// seq is the shuffled sequence of positions (see sequence), which is kept until the
+	// playlist, collection or shuffle order changes.
+	seq []Position
 }
 
 // NewCursor creates a new Cursor for the playlist.Playlist using the index.Collection
@@ -45,27 +80,109 @@ func NewCursor(p *playlist.Playlist, c index.Collection) *Cursor {
 	}
 }
 
+// attach sets the playlist and collection used by the cursor (which aren't persisted).
+func (c *Cursor) attach(p *playlist.Playlist, col index.Collection) {
+	c.Lock()
+	if c.p != p || !sameCollection(c.c, col) {
+		c.seq = nil
+	}
+	c.p = p
+	c.c = col
+	c.Unlock()
+}
+
+// sameCollection returns true if x and y are the same collection.  Only pointers are compared,
+// other collections are treated as different.
+func sameCollection(x, y index.Collection) bool {
+	if x == nil || y == nil || reflect.TypeOf(x).Kind() != reflect.Ptr {
+		return false
+	}
+	return x == y
+}
+
 // Set sets the value of the playlist cursor to the current position and index.Path.
 func (c *Cursor) Set(i int, p index.Path) {
 	c.Lock()
 	c.Current = Position{Index: i, Path: p}
+	c.update()
+	c.Unlock()
+}
+
+// SetShuffle sets the shuffle mode of the cursor.  The shuffle order begins at the current
+// position, and is fixed until the shuffle mode is set again.
+func (c *Cursor) SetShuffle(s Shuffle) error {
+	switch s {
+	case ShuffleOff, ShuffleTrack, ShuffleItem:
+	default:
+		return fmt.Errorf("invalid shuffle mode: %v", s)
+	}
+
+	c.Lock()
+	defer c.Unlock()
+
+	c.Shuffle = s
+	c.Seed = 0
+	c.Start = Position{}
+	c.seq = nil
+	if s != ShuffleOff {
+		c.Seed = time.Now().UnixNano()
+		c.Start = c.Current
+	}
+	c.update()
+	return nil
+}
+
+// SetRepeat sets the repeat mode of the cursor.
+func (c *Cursor) SetRepeat(r Repeat) error {
+	switch r {
+	case RepeatOff, RepeatOne, RepeatAll:
+	default:
+		return fmt.Errorf("invalid repeat mode: %v", r)
+	}
+
+	c.Lock()
+	defer c.Unlock()
+
+	c.Repeat = r
+	c.update()
+	return nil
+}
+
+// update sets Next and Previous from the Current position.
+func (c *Cursor) update() {
+	if c.Current.Empty() {
+		return
+	}
 	c.Next, _ = c.next(c.Current)
+	if c.Repeat == RepeatOne {
+		c.Next = c.Current
+	}
 	c.Previous, _ = c.prev(c.Current)
-	c.Unlock()
 }
 
 // Forward moves the cursor forwards.  Returns an error if the next track could not be found,
-// and sets the Next item to be empty.
+// and sets the Next item to be empty.  With RepeatOne, Next is the current track (which is
+// played again when it ends), but Forward still moves to the next track in the playlist.
 func (c *Cursor) Forward() (err error) {
 	c.Lock()
 	defer c.Unlock()
 
-	if c.Next.Empty() {
+	next := c.Next
+	if c.Repeat == RepeatOne {
+		next, err = c.next(c.Current)
+		if err != nil {
+			return err
+		}
+	}
+	if next.Empty() {
 		return nil
 	}
 	c.Previous = c.Current
-	c.Current = c.Next
+	c.Current = next
 	c.Next, err = c.next(c.Current)
+	if c.Repeat == RepeatOne {
+		c.Next = c.Current
+	}
 	return
 }
 
@@ -81,6 +198,9 @@ func (c *Cursor) Backward() (err error) {
 	c.Next = c.Current
 	c.Current = c.Previous
 	c.Previous, err = c.prev(c.Current)
+	if c.Repeat == RepeatOne {
+		c.Next = c.Current
+	}
 	return
 }
 
@@ -98,7 +218,111 @@ func (c *Cursor) pathIndex(p Position) ([]index.Path, int, error) {
 	return paths, index.IndexOfPath(paths, p.Path), nil
 }
 
+// sequence returns the list of positions in the playlist in shuffled order.  The result is
+// cached, and must not be changed.
+func (c *Cursor) sequence() ([]Position, error) {
+	if c.seq != nil {
+		return c.seq, nil
+	}
+
+	items := c.p.Items()
+	rnd := rand.New(rand.NewSource(c.Seed))
+
+	var seq []Position
+	order := rnd.Perm(len(items))
+	for _, n := range order {
+		paths, err := c.paths(n)
+		if err != nil {
+			return nil, err
+		}
+		for _, p := range paths {
+			seq = append(seq, Position{Path: p, Index: n})
+		}
+	}
+
+	if c.Shuffle == ShuffleTrack {
+		shuffled := make([]Position, len(seq))
+		for i, j := range rnd.Perm(len(seq)) {
+			shuffled[j] = seq[i]
+		}
+		seq = shuffled
+	}
+
+	// Rotate the sequence so that it begins at Start.
+	if i := indexOfPosition(seq, c.Start); i > 0 {
+		if c.Shuffle == ShuffleItem {
+			// Begin at the start of the item which contains Start.
+			for i > 0 && seq[i-1].Index == c.Start.Index {
+				i--
+			}
+		}
+		seq = append(seq[i:], seq[:i]...)
+	}
+	c.seq = seq
+	return seq, nil
+}
+
+func indexOfPosition(seq []Position, p Position) int {
+	for i, x := range seq {
+		if x.Index == p.Index && x.Path.Equal(p.Path) {
+			return i
+		}
+	}
+	return -1
+}
+
+// step returns the position which is n steps from p in the shuffled sequence.
+func (c *Cursor) step(p Position, n int) (Position, error) {
+	seq, err := c.sequence()
+	if err != nil {
+		return Position{}, err
+	}
+
+	i := indexOfPosition(seq, p)
+	if i == -1 {
+		return Position{}, fmt.Errorf("didn't find path: %v", p.Path)
+	}
+
+	i += n
+	if i < 0 || i >= len(seq) {
+		if c.Repeat != RepeatAll {
+			return Position{}, nil
+		}
+		i = (i + len(seq)) % len(seq)
+	}
+	return seq[i], nil
+}
+
+// first returns the first position in the playlist.
+func (c *Cursor) first() (Position, error) {
+	if len(c.p.Items()) == 0 {
+		return Position{}, nil
+	}
+	paths, err := c.paths(0)
+	if err != nil || len(paths) == 0 {
+		return Position{}, err
+	}
+	return Position{Path: paths[0], Index: 0}, nil
+}
+
+// last returns the last position in the playlist.
+func (c *Cursor) last() (Position, error) {
+	n := len(c.p.Items()) - 1
+	if n < 0 {
+		return Position{}, nil
+	}
+	paths, err := c.paths(n)
+	if err != nil || len(paths) == 0 {
+		return Position{}, err
+	}
+	return Position{Path: paths[len(paths)-1], Index: n}, nil
+}
+
 func (c *Cursor) next(p Position) (Position, error) {
+	if c.Shuffle != ShuffleOff {
+		return c.step(p, 1)
+	}
+
 	paths, i, err := c.pathIndex(p)
 	if err != nil {
 		return Position{}, err
@@ -126,10 +350,17 @@ func (c *Cursor) next(p Position) (Position, error) {
 			Index: p.Index + 1,
 		}, nil
 	}
+	if c.Repeat == RepeatAll {
+		return c.first()
+	}
 	return Position{}, nil
 }
 
 func (c *Cursor) prev(p Position) (Position, error) {
+	if c.Shuffle != ShuffleOff {
+		return c.step(p, -1)
+	}
+
 	paths, i, err := c.pathIndex(p)
 	if err != nil {
 		return Position{}, err
@@ -157,5 +388,8 @@ func (c *Cursor) prev(p Position) (Position, error) {
 			Index: p.Index - 1,
 		}, nil
 	}
+	if c.Repeat == RepeatAll {
+		return c.last()
+	}
 	return Position{}, nil
 }
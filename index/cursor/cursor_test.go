@@ -0,0 +1,235 @@
+// Copyright 2015, David Howden
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package cursor
+
+import (
+	"encoding/json"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/amiforus/tchaik/index"
+	"github.com/amiforus/tchaik/index/attr"
+	"github.com/amiforus/tchaik/index/playlist"
+)
+
+type testTrack struct {
+	Name, Album string
+}
+
+func (t testTrack) GetString(f string) string {
+	switch f {
+	case "Name":
+		return t.Name
+	case "Album":
+		return t.Album
+	}
+	return ""
+}
+
+func (testTrack) GetStrings(string) []string { return nil }
+func (testTrack) GetInt(string) int          { return 0 }
+func (testTrack) GetTime(string) time.Time   { return time.Time{} }
+
+type testTracker []testTrack
+
+func (d testTracker) Tracks() []index.Track {
+	result := make([]index.Track, len(d))
+	for i, x := range d {
+		result[i] = x
+	}
+	return result
+}
+
+// testCursor returns a cursor on a playlist containing each of the albums A (3 tracks),
+// B (2 tracks) and C (4 tracks), positioned at the first track.
+func testCursor(t *testing.T) *Cursor {
+	var tracks []testTrack
+	for album, n := range map[string]int{"A": 3, "B": 2, "C": 4} {
+		for i := 0; i < n; i++ {
+			tracks = append(tracks, testTrack{Name: fmt.Sprintf("%v%d", album, i), Album: album})
+		}
+	}
+	c := index.Collect(testTracker(tracks), index.By(attr.String("Album")))
+	index.SortKeysByGroupName(c)
+
+	p := &playlist.Playlist{}
+	for _, k := range c.Keys() {
+		if err := p.Add(index.Path{"Root", k}); err != nil {
+			t.Fatalf("unexpected error adding to playlist: %v", err)
+		}
+	}
+
+	cur := NewCursor(p, c)
+	paths, err := cur.paths(0)
+	if err != nil {
+		t.Fatalf("unexpected error fetching paths: %v", err)
+	}
+	cur.Set(0, paths[0])
+	return cur
+}
+
+// walk moves the cursor forward until the end (or n steps) and returns the visited positions.
+func walk(t *testing.T, c *Cursor, n int) []Position {
+	out := []Position{c.Current}
+	for len(out) < n && !c.Next.Empty() {
+		if err := c.Forward(); err != nil {
+			t.Fatalf("unexpected error from Forward(): %v", err)
+		}
+		out = append(out, c.Current)
+	}
+	return out
+}
+
+func posString(p Position) string {
+	return fmt.Sprintf("%d:%v", p.Index, p.Path)
+}
+
+func TestCursorRepeat(t *testing.T) {
+	c := testCursor(t)
+	if got := walk(t, c, 20); len(got) != 9 {
+		t.Errorf("walk() visited %d tracks, expected 9", len(got))
+	}
+
+	last := c.Current
+	if err := c.SetRepeat(RepeatAll); err != nil {
+		t.Fatalf("unexpected error from SetRepeat(): %v", err)
+	}
+	if c.Next.Index != 0 || len(c.Next.Path) != 3 || c.Next.Path[2] != "0" {
+		t.Errorf("Next = %v, expected first track with repeat all", posString(c.Next))
+	}
+	c.Forward()
+	if posString(c.Previous) != posString(last) {
+		t.Errorf("Previous = %v, expected %v", posString(c.Previous), posString(last))
+	}
+
+	// With repeat one the current track is played again when it ends, but can still be
+	// skipped.
+	c.SetRepeat(RepeatOne)
+	cur := c.Current
+	if posString(c.Next) != posString(cur) {
+		t.Errorf("Next = %v, expected %v with repeat one", posString(c.Next), posString(cur))
+	}
+	if err := c.Forward(); err != nil {
+		t.Fatalf("unexpected error from Forward(): %v", err)
+	}
+	if posString(c.Current) == posString(cur) || posString(c.Previous) != posString(cur) {
+		t.Errorf("Current = %v, Previous = %v, expected to move on from %v with repeat one", posString(c.Current), posString(c.Previous), posString(cur))
+	}
+	if posString(c.Next) != posString(c.Current) {
+		t.Errorf("Next = %v, expected %v with repeat one", posString(c.Next), posString(c.Current))
+	}
+	if err := c.Backward(); err != nil {
+		t.Fatalf("unexpected error from Backward(): %v", err)
+	}
+	if posString(c.Current) != posString(cur) {
+		t.Errorf("Current = %v, expected %v after Backward() with repeat one", posString(c.Current), posString(cur))
+	}
+
+	if err := c.SetRepeat("some"); err == nil {
+		t.Errorf("expected error setting invalid repeat mode")
+	}
+}
+
+func TestCursorShuffle(t *testing.T) {
+	for _, mode := range []Shuffle{ShuffleTrack, ShuffleItem} {
+		c := testCursor(t)
+		start := c.Current
+		if err := c.SetShuffle(mode); err != nil {
+			t.Fatalf("unexpected error from SetShuffle(%q): %v", mode, err)
+		}
+
+		got := walk(t, c, 20)
+		if len(got) != 9 {
+			t.Errorf("[%v] walk() visited %d tracks, expected 9", mode, len(got))
+		}
+		if posString(got[0]) != posString(start) {
+			t.Errorf("[%v] first track = %v, expected %v", mode, posString(got[0]), posString(start))
+		}
+
+		seen := make(map[string]bool)
+		for _, p := range got {
+			seen[posString(p)] = true
+		}
+		if len(seen) != 9 {
+			t.Errorf("[%v] walk() visited %d distinct tracks, expected 9", mode, len(seen))
+		}
+
+		if mode == ShuffleItem {
+			done := make(map[int]bool)
+			for i, p := range got {
+				if i > 0 && got[i-1].Index != p.Index {
+					if done[p.Index] {
+						t.Errorf("[%v] tracks from item %d are not contiguous", mode, p.Index)
+					}
+					done[got[i-1].Index] = true
+				}
+			}
+		}
+
+		// Going back should retrace the tracks which were played.
+		for i := len(got) - 1; i > 0; i-- {
+			if err := c.Backward(); err != nil {
+				t.Fatalf("unexpected error from Backward(): %v", err)
+			}
+			if posString(c.Current) != posString(got[i-1]) {
+				t.Errorf("[%v] Backward() Current = %v, expected %v", mode, posString(c.Current), posString(got[i-1]))
+			}
+		}
+
+		// The shuffle order should be kept when the cursor is persisted.
+		b, err := json.Marshal(c)
+		if err != nil {
+			t.Fatalf("unexpected error marshalling cursor: %v", err)
+		}
+		loaded := &Cursor{}
+		if err := json.Unmarshal(b, loaded); err != nil {
+			t.Fatalf("unexpected error unmarshalling cursor: %v", err)
+		}
+		loaded.attach(c.p, c.c)
+		if got2 := walk(t, loaded, 20); fmt.Sprint(got2) != fmt.Sprint(got) {
+			t.Errorf("[%v] walk() after reload = %v, expected %v", mode, got2, got)
+		}
+	}
+
+	c := testCursor(t)
+	if err := c.SetShuffle("some"); err == nil {
+		t.Errorf("expected error setting invalid shuffle mode")
+	}
+}
+
+func TestCursorSequenceCache(t *testing.T) {
+	c := testCursor(t)
+	c.attach(c.p, &index.RootCollection{Collection: c.c})
+	if err := c.SetShuffle(ShuffleTrack); err != nil {
+		t.Fatalf("unexpected error from SetShuffle(): %v", err)
+	}
+	seq, err := c.sequence()
+	if err != nil {
+		t.Fatalf("unexpected error from sequence(): %v", err)
+	}
+	if len(seq) != 9 {
+		t.Fatalf("len(sequence()) = %d, expected 9", len(seq))
+	}
+
+	// Attaching the same playlist keeps the sequence, a changed playlist replaces it.
+	c.attach(c.p, c.c)
+	if c.seq == nil {
+		t.Errorf("sequence dropped when attaching the same playlist")
+	}
+
+	p := c.p.Copy()
+	if err := p.Remove(2, index.Path{"Root", c.c.Keys()[2]}); err != nil {
+		t.Fatalf("unexpected error from Remove(): %v", err)
+	}
+	c.attach(p, c.c)
+	seq, err = c.sequence()
+	if err != nil {
+		t.Fatalf("unexpected error from sequence(): %v", err)
+	}
+	if len(seq) != 5 {
+		t.Errorf("len(sequence()) = %d, expected 5 after removing an item", len(seq))
+	}
+}
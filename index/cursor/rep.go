@@ -17,6 +17,8 @@ const (
 	ActionSet      Action = "set"
 	ActionNext            = "next"
 	ActionPrevious        = "previous"
+	ActionShuffle         = "shuffle"
+	ActionRepeat          = "repeat"
 )
 
 type RepAction struct {
@@ -24,12 +26,15 @@ type RepAction struct {
 	Action Action     `json:"action"`
 	Path   index.Path `json:"path"`
 	Index  int        `json:"index"`
+	Mode   string     `json:"mode"` // shuffle or repeat mode
 }
 
 var actionToAction = map[string]Action{
-	"SET":  ActionSet,
-	"NEXT": ActionNext,
-	"PREV": ActionPrevious,
+	"SET":     ActionSet,
+	"NEXT":    ActionNext,
+	"PREV":    ActionPrevious,
+	"SHUFFLE": ActionShuffle,
+	"REPEAT":  ActionRepeat,
 }
 
 func (a RepAction) Apply(s Store, ps playlist.Store, collection index.Collection) error {
@@ -38,13 +43,19 @@ func (a RepAction) Apply(s Store, ps playlist.Store, collection index.Collection
 		return fmt.Errorf("unknown action: %v", a.Action)
 	}
 
+	p := ps.Get(a.Name)
 	if action == ActionSet {
-		p := ps.Get(a.Name)
 		if p == nil {
 			return fmt.Errorf("cannot set cursor for invalid playlist name: %v", a.Name)
 		}
 
 		c := NewCursor(p, collection)
+		if old := s.Get(a.Name); old != nil {
+			// Keep the modes (and shuffle order) of the existing cursor.
+			old.Lock()
+			c.Shuffle, c.Repeat, c.Seed, c.Start = old.Shuffle, old.Repeat, old.Seed, old.Start
+			old.Unlock()
+		}
 		c.Set(a.Index, a.Path)
 		return s.Set(a.Name, c)
 	}
@@ -53,6 +64,11 @@ func (a RepAction) Apply(s Store, ps playlist.Store, collection index.Collection
 	if c == nil {
 		return fmt.Errorf("invalid cursor name: %v", a.Name)
 	}
+	if p == nil {
+		return fmt.Errorf("invalid playlist name: %v", a.Name)
+	}
+	// Cursors loaded from the store don't have a playlist or collection.
+	c.attach(p, collection)
 
 	var err error
 	switch action {
@@ -60,6 +76,10 @@ func (a RepAction) Apply(s Store, ps playlist.Store, collection index.Collection
 		err = c.Backward()
 	case ActionNext:
 		err = c.Forward()
+	case ActionShuffle:
+		err = c.SetShuffle(Shuffle(a.Mode))
+	case ActionRepeat:
+		err = c.SetRepeat(Repeat(a.Mode))
 	}
 	err1 := s.Set(a.Name, c)
 	if err == nil {
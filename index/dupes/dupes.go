@@ -0,0 +1,208 @@
+// Copyright 2015, David Howden
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Package dupes defines functionality for finding duplicate tracks in a library (i.e. the
+// same recording ripped more than once in different formats), and choosing which copy
+// to keep.
+package dupes
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/amiforus/tchaik/index"
+)
+
+// Cluster is a list of tracks which are duplicates of each other, ordered by preference:
+// the first track is the suggested copy to keep.
+type Cluster []index.Track
+
+// Keep returns the suggested track to keep.
+func (c Cluster) Keep() index.Track {
+	return c[0]
+}
+
+// Remove returns the tracks which are not the suggested track to keep.
+func (c Cluster) Remove() []index.Track {
+	return c[1:]
+}
+
+// Key returns the normalised metadata key used to find duplicates of the track t (the
+// normalised Name, Album and Artist, and the TrackNumber), or "" if t has no Name.
+func Key(t index.Track) string {
+	name := index.Normalise(t.GetString("Name"))
+	if name == "" {
+		return ""
+	}
+	return strings.Join([]string{
+		name,
+		index.Normalise(t.GetString("Album")),
+		index.Normalise(t.GetString("Artist")),
+		fmt.Sprintf("%d", t.GetInt("TrackNumber")),
+	}, "\x00")
+}
+
+// Find returns the clusters of duplicate tracks in tracks.  Tracks are duplicates if they have
+// the same Key and their TotalTime differs by at most tolerance (tracks with no TotalTime
+// match any), or if they have the same (non-empty) audio hash in sums (which is keyed by
+// track ID, and can be nil).  Clusters are ordered by the location of their suggested keeper.
+func Find(tracks []index.Track, tolerance time.Duration, sums map[string]string) []Cluster {
+	u := newUnion(len(tracks))
+
+	byKey := make(map[string][]int)
+	bySum := make(map[string]int)
+	for i, t := range tracks {
+		if k := Key(t); k != "" {
+			byKey[k] = append(byKey[k], i)
+		}
+		if s := sums[t.GetString("ID")]; s != "" {
+			if j, ok := bySum[s]; ok {
+				u.join(i, j)
+				continue
+			}
+			bySum[s] = i
+		}
+	}
+
+	tol := int(tolerance / time.Millisecond)
+	for _, xs := range byKey {
+		sort.Sort(byTotalTime{xs, tracks})
+		for n := 1; n < len(xs); n++ {
+			a, b := tracks[xs[n-1]].GetInt("TotalTime"), tracks[xs[n]].GetInt("TotalTime")
+			if a == 0 || b-a <= tol {
+				u.join(xs[n-1], xs[n])
+			}
+		}
+	}
+
+	groups := make(map[int]Cluster)
+	for i, t := range tracks {
+		r := u.find(i)
+		groups[r] = append(groups[r], t)
+	}
+
+	var clusters []Cluster
+	for _, c := range groups {
+		if len(c) > 1 {
+			sort.Sort(byPreference(c))
+			clusters = append(clusters, c)
+		}
+	}
+	sort.Sort(byKeeperLocation(clusters))
+	return clusters
+}
+
+// Lossless kinds of audio file, which are always preferred to lossy ones.
+var lossless = map[string]bool{
+	"FLAC audio file":           true,
+	"Apple Lossless audio file": true,
+	"AIFF audio file":           true,
+	"WAV audio file":            true,
+}
+
+// Prefer returns true if the track a is preferable to b: lossless files are preferred, then
+// higher BitRate, then the track added first (which is more likely to have play history).
+func Prefer(a, b index.Track) bool {
+	if la, lb := lossless[a.GetString("Kind")], lossless[b.GetString("Kind")]; la != lb {
+		return la
+	}
+	if ra, rb := a.GetInt("BitRate"), b.GetInt("BitRate"); ra != rb {
+		return ra > rb
+	}
+	if da, db := a.GetTime("DateAdded"), b.GetTime("DateAdded"); !da.Equal(db) {
+		return !da.IsZero() && (db.IsZero() || da.Before(db))
+	}
+	return a.GetString("Location") < b.GetString("Location")
+}
+
+type byPreference Cluster
+
+func (p byPreference) Len() int           { return len(p) }
+func (p byPreference) Swap(i, j int)      { p[i], p[j] = p[j], p[i] }
+func (p byPreference) Less(i, j int) bool { return Prefer(p[i], p[j]) }
+
+type byKeeperLocation []Cluster
+
+func (c byKeeperLocation) Len() int      { return len(c) }
+func (c byKeeperLocation) Swap(i, j int) { c[i], c[j] = c[j], c[i] }
+func (c byKeeperLocation) Less(i, j int) bool {
+	return c[i].Keep().GetString("Location") < c[j].Keep().GetString("Location")
+}
+
+type byTotalTime struct {
+	xs     []int
+	tracks []index.Track
+}
+
+func (t byTotalTime) Len() int      { return len(t.xs) }
+func (t byTotalTime) Swap(i, j int) { t.xs[i], t.xs[j] = t.xs[j], t.xs[i] }
+func (t byTotalTime) Less(i, j int) bool {
+	return t.tracks[t.xs[i]].GetInt("TotalTime") < t.tracks[t.xs[j]].GetInt("TotalTime")
+}
+
+// union is a disjoint-set forest of integers.
+type union []int
+
+func newUnion(n int) union {
+	u := make(union, n)
+	for i := range u {
+		u[i] = i
+	}
+	return u
+}
+
+func (u union) find(i int) int {
+	for u[i] != i {
+		u[i] = u[u[i]]
+		i = u[i]
+	}
+	return i
+}
+
+func (u union) join(i, j int) {
+	u[u.find(i)] = u.find(j)
+}
+
+// Filter returns a Library containing the tracks of l which aren't removed from clusters
+// (that is, all the tracks except the non-preferred copies).
+func Filter(l index.Library, clusters []Cluster) index.Library {
+	removed := make(map[string]bool)
+	for _, c := range clusters {
+		for _, t := range c.Remove() {
+			removed[t.GetString("ID")] = true
+		}
+	}
+	return &filterLibrary{
+		Library: l,
+		removed: removed,
+	}
+}
+
+// filterLibrary is an index.Library which excludes removed tracks.
+type filterLibrary struct {
+	index.Library
+	removed map[string]bool
+}
+
+// Tracks implements index.Library.
+func (l *filterLibrary) Tracks() []index.Track {
+	all := l.Library.Tracks()
+	tracks := make([]index.Track, 0, len(all))
+	for _, t := range all {
+		if !l.removed[t.GetString("ID")] {
+			tracks = append(tracks, t)
+		}
+	}
+	return tracks
+}
+
+// Track implements index.Library.
+func (l *filterLibrary) Track(id string) (index.Track, bool) {
+	if l.removed[id] {
+		return nil, false
+	}
+	return l.Library.Track(id)
+}
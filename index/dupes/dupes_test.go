@@ -0,0 +1,154 @@
+// Copyright 2015, David Howden
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package dupes
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/amiforus/tchaik/index"
+)
+
+type testTrack struct {
+	ID, Name, Album, Artist, Kind, Location string
+	TrackNumber, TotalTime, BitRate         int
+	DateAdded                               time.Time
+}
+
+func (t testTrack) GetString(name string) string {
+	switch name {
+	case "ID":
+		return t.ID
+	case "Name":
+		return t.Name
+	case "Album":
+		return t.Album
+	case "Artist":
+		return t.Artist
+	case "Kind":
+		return t.Kind
+	case "Location":
+		return t.Location
+	}
+	return ""
+}
+
+func (t testTrack) GetStrings(name string) []string { return index.DefaultGetStrings(t, name) }
+
+func (t testTrack) GetInt(name string) int {
+	switch name {
+	case "TrackNumber":
+		return t.TrackNumber
+	case "TotalTime":
+		return t.TotalTime
+	case "BitRate":
+		return t.BitRate
+	}
+	return 0
+}
+
+func (t testTrack) GetTime(name string) time.Time {
+	if name == "DateAdded" {
+		return t.DateAdded
+	}
+	return time.Time{}
+}
+
+type testLibrary []index.Track
+
+func (l testLibrary) Tracks() []index.Track { return l }
+
+func (l testLibrary) Track(id string) (index.Track, bool) {
+	for _, t := range l {
+		if t.GetString("ID") == id {
+			return t, true
+		}
+	}
+	return nil, false
+}
+
+func ids(tracks []index.Track) []string {
+	result := make([]string, len(tracks))
+	for i, t := range tracks {
+		result[i] = t.GetString("ID")
+	}
+	return result
+}
+
+var testTracks = []index.Track{
+	testTrack{ID: "1", Name: "So What", Album: "Kind of Blue", Artist: "Miles Davis", TrackNumber: 1, TotalTime: 562000, Kind: "MPEG audio file", BitRate: 320, Location: "/mp3/01.mp3"},
+	testTrack{ID: "2", Name: "So what", Album: "Kind Of Blue", Artist: "Miles Davis", TrackNumber: 1, TotalTime: 561200, Kind: "FLAC audio file", BitRate: 900, Location: "/flac/01.flac"},
+	testTrack{ID: "3", Name: "So What", Album: "Kind of Blue", Artist: "Miles Davis", TrackNumber: 1, TotalTime: 563000, Kind: "AAC audio file", BitRate: 256, Location: "/aac/01.m4a"},
+	testTrack{ID: "4", Name: "So What", Album: "Kind of Blue (Live)", Artist: "Miles Davis", TrackNumber: 1, TotalTime: 562000, Kind: "MPEG audio file", BitRate: 320, Location: "/live/01.mp3"},
+	testTrack{ID: "5", Name: "So What", Album: "Kind of Blue", Artist: "Miles Davis", TrackNumber: 1, TotalTime: 620000, Kind: "MPEG audio file", BitRate: 320, Location: "/alt/01.mp3"},
+	testTrack{ID: "6", Name: "Freddie Freeloader", Album: "Kind of Blue", Artist: "Miles Davis", TrackNumber: 2, TotalTime: 586000, Kind: "MPEG audio file", BitRate: 128, Location: "/mp3/02.mp3"},
+	testTrack{ID: "7", Name: "Track 02", TrackNumber: 2, Kind: "MPEG audio file", BitRate: 192, Location: "/untagged/02.mp3"},
+}
+
+func TestFind(t *testing.T) {
+	tests := []struct {
+		sums     map[string]string
+		expected [][]string
+	}{
+		{
+			expected: [][]string{{"2", "1", "3"}},
+		},
+		{
+			sums:     map[string]string{"6": "abc", "7": "abc", "1": ""},
+			expected: [][]string{{"2", "1", "3"}, {"7", "6"}},
+		},
+	}
+
+	for ii, tt := range tests {
+		clusters := Find(testTracks, 2*time.Second, tt.sums)
+
+		var got [][]string
+		for _, c := range clusters {
+			got = append(got, ids(c))
+		}
+		if !reflect.DeepEqual(got, tt.expected) {
+			t.Errorf("[%d] Find() = %v, expected %v", ii, got, tt.expected)
+		}
+	}
+}
+
+func TestPrefer(t *testing.T) {
+	added := time.Date(2015, time.January, 1, 0, 0, 0, 0, time.UTC)
+	tests := []struct {
+		a, b testTrack
+	}{
+		{testTrack{Kind: "FLAC audio file", BitRate: 700}, testTrack{Kind: "MPEG audio file", BitRate: 320}},
+		{testTrack{Kind: "MPEG audio file", BitRate: 320}, testTrack{Kind: "AAC audio file", BitRate: 256}},
+		{testTrack{BitRate: 256, DateAdded: added}, testTrack{BitRate: 256, DateAdded: added.Add(time.Hour)}},
+		{testTrack{BitRate: 256, DateAdded: added}, testTrack{BitRate: 256}},
+		{testTrack{Location: "/a"}, testTrack{Location: "/b"}},
+	}
+
+	for ii, tt := range tests {
+		if !Prefer(tt.a, tt.b) {
+			t.Errorf("[%d] Prefer(%v, %v) = false, expected true", ii, tt.a, tt.b)
+		}
+		if Prefer(tt.b, tt.a) {
+			t.Errorf("[%d] Prefer(%v, %v) = true, expected false", ii, tt.b, tt.a)
+		}
+	}
+}
+
+func TestFilter(t *testing.T) {
+	l := testLibrary(testTracks)
+	f := Filter(l, Find(testTracks, 2*time.Second, nil))
+
+	expected := []string{"2", "4", "5", "6", "7"}
+	if got := ids(f.Tracks()); !reflect.DeepEqual(got, expected) {
+		t.Errorf("Filter().Tracks() = %v, expected %v", got, expected)
+	}
+	if _, ok := f.Track("1"); ok {
+		t.Errorf("Filter().Track(\"1\") should not return removed track")
+	}
+	if _, ok := f.Track("2"); !ok {
+		t.Errorf("Filter().Track(\"2\") should return kept track")
+	}
+}
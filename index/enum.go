@@ -6,6 +6,7 @@ package index
 
 import (
 	"errors"
+	"fmt"
 	"sort"
 	"strconv"
 	"strings"
@@ -35,23 +36,73 @@ func parseNumeral(x string) (uint, error) {
 	return uint(n), err
 }
 
-// parser is a pairing of parse functions with "style" description.
+// parseLowerNumeral parses lower case roman numerals (see parseNumeral).
+func parseLowerNumeral(x string) (uint, error) {
+	if x != strings.ToLower(x) {
+		return 0, fmt.Errorf("invalid lower case numeral: %v", x)
+	}
+	return parseNumeral(x)
+}
+
+// parseUpperNumeral parses upper case roman numerals (see parseNumeral).
+func parseUpperNumeral(x string) (uint, error) {
+	if x != strings.ToUpper(x) {
+		return 0, fmt.Errorf("invalid upper case numeral: %v", x)
+	}
+	return parseNumeral(x)
+}
+
+// parseLowerAlpha parses a lower case letter, "a" is 1.
+func parseLowerAlpha(x string) (uint, error) {
+	if len(x) != 1 || x[0] < 'a' || x[0] > 'z' {
+		return 0, fmt.Errorf("invalid lower case letter: %v", x)
+	}
+	return uint(x[0]-'a') + 1, nil
+}
+
+// parseUpperAlpha parses an upper case letter, "A" is 1.
+func parseUpperAlpha(x string) (uint, error) {
+	if len(x) != 1 || x[0] < 'A' || x[0] > 'Z' {
+		return 0, fmt.Errorf("invalid upper case letter: %v", x)
+	}
+	return uint(x[0]-'A') + 1, nil
+}
+
+// parser is a pairing of parse functions with "style" description (a CSS list-style-type).
 type parser struct {
 	Fn    parseFn
 	Style string
+
+	// Marked is true if the enumeration must be marked by punctuation (i.e. "a)", "(a)" or
+	// "a."), as letters on their own are often words (i.e. "A Day in the Life").
+	Marked bool
 }
 
 // parsers is an internal list of numerical representations that can be identified by this
-// package.
+// package, in order of preference.
 var parsers = []parser{
 	{
 		Fn:    parseUInt,
 		Style: "decimal",
 	},
 	{
-		Fn:    parseNumeral,
+		Fn:    parseLowerNumeral,
+		Style: "lower-roman",
+	},
+	{
+		Fn:    parseUpperNumeral,
 		Style: "upper-roman",
 	},
+	{
+		Fn:     parseLowerAlpha,
+		Style:  "lower-alpha",
+		Marked: true,
+	},
+	{
+		Fn:     parseUpperAlpha,
+		Style:  "upper-alpha",
+		Marked: true,
+	},
 }
 
 type isNext struct {
@@ -73,7 +124,11 @@ func (i *isNext) IsNext(s string) bool {
 
 // enumFieldSuffixes is a list of string suffixes which will be trimmed from "enumeration
 // fields"
-const enumFieldSuffixes = ".:-"
+const enumFieldSuffixes = ".:-)"
+
+// enumFieldPrefixes is a list of string prefixes which will be trimmed from "enumeration
+// fields" (i.e. "(1)").
+const enumFieldPrefixes = "("
 
 // enumWordSuffixes is a list of strings which should be removed from string resulting
 // from the prefix removal.
@@ -100,6 +155,20 @@ func trimPrefix(s string, prefixes []string) (string, int) {
 // number of characters that were removed from the beginning of the string to retrieve
 // the value.  If the resulting prefix is the whole string then we don't do anything.
 func enumPrefix(s string) (string, int) {
+	e := parseEnumWord(s)
+	return e.word, e.n
+}
+
+// enumWord is the enumeration prefix of a string.
+type enumWord struct {
+	word   string // the enumeration value, i.e. "1" from "(1) First"
+	n      int    // number of bytes to remove from the string to remove the prefix
+	marked bool   // true if the value was marked by punctuation, i.e. "a)" or "(a)"
+}
+
+// parseEnumWord returns the enumeration prefix of s (see enumPrefix).  Prefixes of the form
+// "n/m" (i.e. "1/4") have the value n.
+func parseEnumWord(s string) enumWord {
 	x := s
 	x, r := trimPrefix(x, enumWordPrefixes)
 	words := strings.SplitN(x, " ", 2)
@@ -111,80 +180,119 @@ func enumPrefix(s string) (string, int) {
 	r += len(w)
 
 	if r == len(s) {
-		return s, 0
-	}
-	w = strings.TrimRight(w, enumFieldSuffixes)
-	return w, r
-}
-
-// trimEnumPrefix trims enumeration prefixes from field on each of the tracks, returning the
-// updated tracks and the determined prefix style.
-func trimEnumPrefix(field string, tracks []Track) ([]Track, string) {
-	if len(tracks) == 0 {
-		return tracks, ""
+		return enumWord{word: s}
 	}
-
-	t0 := tracks[0]
-	name := t0.GetString(field)
-	ep, epl := enumPrefix(name)
-
-	var p parser
-	var n uint
-	for _, x := range parsers {
-		var err error
-		n, err = x.Fn(ep)
-		if err == nil {
-			p = x
-			break
+	t := strings.TrimLeft(strings.TrimRight(w, enumFieldSuffixes), enumFieldPrefixes)
+	if i := strings.IndexByte(t, '/'); i > 0 {
+		if _, err := parseUInt(t[i+1:]); err == nil {
+			t = t[:i]
 		}
 	}
+	return enumWord{
+		word:   t,
+		n:      r,
+		marked: t != w,
+	}
+}
 
-	if p.Fn == nil {
-		return tracks, ""
+// enumNumbers returns the enumeration values of the words using the parser, and true if they
+// are a sequence: each value is one more than the last, except that the sequence can restart
+// (from 1 or its first value) on a new disc, and one value in the middle can be missing.
+func enumNumbers(p parser, words []enumWord, tracks []Track) ([]int, bool) {
+	if p.Marked && !words[0].marked {
+		return nil, false
+	}
+	first, err := p.Fn(words[0].word)
+	if err != nil {
+		return nil, false
 	}
 
 	nexter := &isNext{
 		fn: p.Fn,
-		n:  n,
+		n:  first,
 	}
 
-	epls := make([]int, len(tracks))
-	epls[0] = epl
-	i := 1
-	for i < len(tracks) {
-		ep, epls[i] = enumPrefix(tracks[i].GetString(field))
-		if !nexter.IsNext(ep) {
-			break
+	ns := make([]int, len(words))
+	ns[0] = int(first)
+	missing := false
+	for i := 1; i < len(words); i++ {
+		w := words[i]
+		if p.Marked && !w.marked {
+			return nil, false
 		}
-		i++
+		if nexter.IsNext(w.word) {
+			ns[i] = int(nexter.n)
+			continue
+		}
+
+		n, err := p.Fn(w.word)
+		if err != nil {
+			return nil, false
+		}
+		switch {
+		case (n == 1 || n == first) && tracks[i].GetInt("DiscNumber") != tracks[i-1].GetInt("DiscNumber"):
+		case n == nexter.n+2 && !missing && i < len(words)-1:
+			missing = true
+		default:
+			return nil, false
+		}
+		nexter.n = n
+		ns[i] = int(n)
 	}
+	return ns, true
+}
 
-	if i < len(tracks) {
-		return tracks, ""
+// trimEnumPrefix trims enumeration prefixes from field on each of the tracks, returning the
+// updated tracks, the determined prefix style and the enumeration values.
+func trimEnumPrefix(field string, tracks []Track) ([]Track, string, []int) {
+	if len(tracks) == 0 {
+		return tracks, "", nil
 	}
 
-	result := make([]Track, 0, len(tracks))
+	words := make([]enumWord, len(tracks))
 	for i, t := range tracks {
-		result = append(result, pfxTrack{
-			Track: t,
-			field: field,
-			pfx:   epls[i],
-		})
+		words[i] = parseEnumWord(t.GetString(field))
+	}
+
+	for _, p := range parsers {
+		ns, ok := enumNumbers(p, words, tracks)
+		if !ok {
+			continue
+		}
+
+		result := make([]Track, 0, len(tracks))
+		for i, t := range tracks {
+			result = append(result, pfxTrack{
+				Track: t,
+				field: field,
+				pfx:   words[i].n,
+			})
+		}
+		return result, p.Style, ns
 	}
-	return result, p.Style
+	return tracks, "", nil
 }
 
 // TrimEnumPrefix removes enumeratative prefixes from the Tracks in the Group. The
 // resulting group will have a ListStyle field which will indicate the style of enumeration
-// (if any).
+// (if any) as a CSS list-style-type (decimal, lower-roman, upper-roman, lower-alpha or
+// upper-alpha).  If the enumeration isn't 1, 2, 3... (i.e. it restarts on each disc, or has
+// a missing value) then the group will also have a ListNumbers field of the values.
 func TrimEnumPrefix(g Group) Group {
-	nt, style := trimEnumPrefix("Name", g.Tracks())
+	nt, style, ns := trimEnumPrefix("Name", g.Tracks())
+	flds := map[string]interface{}{
+		"ListStyle": style,
+	}
+	for i, n := range ns {
+		if n != i+1 {
+			flds["ListNumbers"] = ns
+			break
+		}
+	}
 	return subGrpFlds{
 		Group:  g,
 		tracks: nt,
-		flds: map[string]interface{}{
-			"ListStyle": style,
-		},
+		flds:   flds,
 	}
 }
 
@@ -209,14 +209,14 @@ func TestStripEnumPrefix(t *testing.T) {
 		{
 			[]string{"i First", "ii Second"},
 			[]string{"First", "Second"},
-			"upper-roman",
+			"lower-roman",
 		},
 
 		// Lower numeral parsing
 		{
 			[]string{"ii Second", "iii Third"},
 			[]string{"Second", "Third"},
-			"upper-roman",
+			"lower-roman",
 		},
 
 		// Upper-roman, no suffix
@@ -230,14 +230,14 @@ func TestStripEnumPrefix(t *testing.T) {
 		{
 			[]string{"i. First", "ii. Second"},
 			[]string{"First", "Second"},
-			"upper-roman",
+			"lower-roman",
 		},
 
 		// Lower numeral parsing, " - " suffix
 		{
 			[]string{"i - First", "ii - Second"},
 			[]string{"First", "Second"},
-			"upper-roman",
+			"lower-roman",
 		},
 
 		// Roman numeral, and remove suffix 1, 2, 3, 4
@@ -267,6 +267,65 @@ func TestStripEnumPrefix(t *testing.T) {
 			[]string{"First", "Second", "Third", "Fourth"},
 			"decimal",
 		},
+
+		// Letters, marked by punctuation
+		{
+			[]string{"a) First", "b) Second", "c) Third"},
+			[]string{"First", "Second", "Third"},
+			"lower-alpha",
+		},
+		{
+			[]string{"A. First", "B. Second"},
+			[]string{"First", "Second"},
+			"upper-alpha",
+		},
+		{
+			[]string{"(c) Third", "(d) Fourth"},
+			[]string{"Third", "Fourth"},
+			"lower-alpha",
+		},
+
+		// Letters without punctuation are words
+		{
+			[]string{"A Day in the Life"},
+			[]string{"A Day in the Life"},
+			"",
+		},
+
+		// Parenthesised
+		{
+			[]string{"(1) First", "(2) Second"},
+			[]string{"First", "Second"},
+			"decimal",
+		},
+
+		// n/m
+		{
+			[]string{"1/3 First", "2/3 Second", "3/3 Third"},
+			[]string{"First", "Second", "Third"},
+			"decimal",
+		},
+
+		// Missing value in the middle of the sequence
+		{
+			[]string{"1. First", "2. Second", "4. Fourth", "5. Fifth"},
+			[]string{"First", "Second", "Fourth", "Fifth"},
+			"decimal",
+		},
+
+		// Missing value at the end isn't a sequence
+		{
+			[]string{"1 Giant Leap", "3 Doors Down"},
+			[]string{"1 Giant Leap", "3 Doors Down"},
+			"",
+		},
+
+		// More than one missing value
+		{
+			[]string{"1. First", "3. Third", "5. Fifth", "6. Sixth"},
+			[]string{"1. First", "3. Third", "5. Fifth", "6. Sixth"},
+			"",
+		},
 	}
 
 	for _, tt := range table {
@@ -285,6 +344,61 @@ func TestStripEnumPrefix(t *testing.T) {
 	}
 }
 
+func TestTrimEnumPrefixListNumbers(t *testing.T) {
+	tests := []struct {
+		tracks      []testTrack
+		listStyle   string
+		listNumbers interface{}
+	}{
+		{
+			[]testTrack{{Name: "1. First"}, {Name: "2. Second"}},
+			"decimal",
+			nil,
+		},
+		{
+			[]testTrack{{Name: "ii. Second"}, {Name: "iii. Third"}},
+			"lower-roman",
+			[]int{2, 3},
+		},
+		{
+			[]testTrack{{Name: "1. First"}, {Name: "2. Second"}, {Name: "4. Fourth"}, {Name: "5. Fifth"}},
+			"decimal",
+			[]int{1, 2, 4, 5},
+		},
+		// Restarts on each disc.
+		{
+			[]testTrack{
+				{Name: "I. First", DiscNumber: 1},
+				{Name: "II. Second", DiscNumber: 1},
+				{Name: "I. First", DiscNumber: 2},
+				{Name: "II. Second", DiscNumber: 2},
+			},
+			"upper-roman",
+			[]int{1, 2, 1, 2},
+		},
+		// Doesn't restart on the same disc.
+		{
+			[]testTrack{
+				{Name: "1. First", DiscNumber: 1},
+				{Name: "2. Second", DiscNumber: 1},
+				{Name: "1. First", DiscNumber: 1},
+			},
+			"",
+			nil,
+		},
+	}
+
+	for ii, tt := range tests {
+		got := TrimEnumPrefix(group{tracks: testTracker(tt.tracks).Tracks()})
+		if s := got.Field("ListStyle"); s != tt.listStyle {
+			t.Errorf("[%d] Field(\"ListStyle\") = %#v, expected %#v", ii, s, tt.listStyle)
+		}
+		if ns := got.Field("ListNumbers"); !reflect.DeepEqual(ns, tt.listNumbers) {
+			t.Errorf("[%d] Field(\"ListNumbers\") = %#v, expected %#v", ii, ns, tt.listNumbers)
+		}
+	}
+}
+
 type tracks []Track
 
 func (t tracks) Tracks() []Track {
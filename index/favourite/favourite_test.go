@@ -0,0 +1,74 @@
+package favourite
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"testing"
+
+	"github.com/amiforus/tchaik/index"
+)
+
+func TestStoreMigrate(t *testing.T) {
+	dir, err := ioutil.TempDir("", "favourite")
+	if err != nil {
+		t.Fatalf("unexpected error creating temp dir: %v", err)
+	}
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "favourites.json")
+	s, err := NewStore(path)
+	if err != nil {
+		t.Fatalf("unexpected error from NewStore: %v", err)
+	}
+	for _, p := range []string{"Root:a", "Root:b", "Root:c"} {
+		if err := s.Set(index.NewPath(p), true); err != nil {
+			t.Fatalf("unexpected error from Set: %v", err)
+		}
+	}
+
+	// a and b are merged, and c can't be resolved.
+	pm := func(p index.Path) (index.Path, bool) {
+		if p[1] == "c" {
+			return p, false
+		}
+		return index.NewPath("Root:x"), true
+	}
+	unresolved, err := s.Migrate(pm)
+	if err != nil {
+		t.Fatalf("unexpected error from Migrate: %v", err)
+	}
+	if expected := []index.Path{index.NewPath("Root:c")}; !reflect.DeepEqual(unresolved, expected) {
+		t.Errorf("Migrate() unresolved = %v, expected %v", unresolved, expected)
+	}
+	got := s.List()
+	sort.Sort(index.PathSlice(got))
+	if expected := []index.Path{index.NewPath("Root:c"), index.NewPath("Root:x")}; !reflect.DeepEqual(got, expected) {
+		t.Errorf("List() = %v, expected %v", got, expected)
+	}
+
+	// c is kept, and is resolved once it is back in the library.
+	pm = func(p index.Path) (index.Path, bool) {
+		return index.NewPath("Root:y"), true
+	}
+	unresolved, err = s.Migrate(pm)
+	if err != nil {
+		t.Fatalf("unexpected error from Migrate: %v", err)
+	}
+	if len(unresolved) != 0 {
+		t.Errorf("Migrate() unresolved = %v, expected none", unresolved)
+	}
+	if got, expected := s.List(), []index.Path{index.NewPath("Root:y")}; !reflect.DeepEqual(got, expected) {
+		t.Errorf("List() = %v, expected %v", got, expected)
+	}
+
+	s, err = NewStore(path)
+	if err != nil {
+		t.Fatalf("unexpected error from NewStore: %v", err)
+	}
+	if !s.Get(index.NewPath("Root:y")) {
+		t.Errorf("Get(%v) = false after reload, expected true", index.NewPath("Root:y"))
+	}
+}
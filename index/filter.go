@@ -56,9 +56,13 @@ func (f filter) Items() []FilterItem {
 }
 
 // FilterCollection creates Filter of the Collection using fields to partition
-// Tracks in a collection.
-func FilterCollection(c Collection, field attr.Interface) Filter {
+// Tracks in a collection.  Items are ordered using the Collation, where the sort name
+// of an item is taken from tracks where the field (i.e. Artist) has only the item value
+// and the corresponding sort name field (i.e. SortArtist) is set.
+func FilterCollection(c Collection, field attr.Interface, col *Collation) Filter {
+	sortField := sortNameField(field.Name())
 	m := make(map[string][]Path)
+	sortNames := make(map[string]string)
 	walkfn := func(t Track, p Path) error {
 		f := field.Value(t)
 		switch f := f.(type) {
@@ -69,18 +73,35 @@ func FilterCollection(c Collection, field attr.Interface) Filter {
 				m[x] = append(m[x], p)
 			}
 		}
+
+		if sortField != "" {
+			if s := t.GetString(sortField); s != "" {
+				if n := t.GetString(field.Name()); n != "" && sortNames[n] == "" {
+					sortNames[n] = s
+				}
+			}
+		}
 		return nil
 	}
 	Walk(c, Path([]Key{"Root"}), walkfn)
 
+	names := make([]string, 0, len(m))
+	for k := range m {
+		names = append(names, k)
+	}
+	sns := make([]string, len(names))
+	for i, n := range names {
+		sns[i] = sortNames[n]
+	}
+	sort.Sort(col.sorter(names, sns))
+
 	items := make([]FilterItem, 0, len(m))
-	for k, v := range m {
+	for _, k := range names {
 		items = append(items, &filterItem{
 			name:   k,
 			fields: make(map[string]interface{}),
-			paths:  Union(v),
+			paths:  Union(m[k]),
 		})
 	}
-	sort.Sort(FilterItemSlice(items))
 	return filter{items}
 }
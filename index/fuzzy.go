@@ -0,0 +1,252 @@
+// Copyright 2015, David Howden
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package index
+
+import (
+	"sort"
+	"strings"
+)
+
+// levenshtein returns the edit distance between the strings a and b.
+func levenshtein(a, b string) int {
+	s, t := []rune(a), []rune(b)
+	if len(s) < len(t) {
+		s, t = t, s
+	}
+
+	row := make([]int, len(t)+1)
+	for j := range row {
+		row[j] = j
+	}
+	for i := 1; i <= len(s); i++ {
+		prev := row[0]
+		row[0] = i
+		for j := 1; j <= len(t); j++ {
+			cost := 1
+			if s[i-1] == t[j-1] {
+				cost = 0
+			}
+			cur := row[j]
+			row[j] = minInt(row[j]+1, row[j-1]+1, prev+cost)
+			prev = cur
+		}
+	}
+	return row[len(t)]
+}
+
+func minInt(x int, ys ...int) int {
+	for _, y := range ys {
+		if y < x {
+			x = y
+		}
+	}
+	return x
+}
+
+// bkTree is a BK-tree (Burkhard-Keller tree) of words, which can be efficiently queried
+// for all words within an edit distance of a given word.
+type bkTree struct {
+	root *bkNode
+}
+
+type bkNode struct {
+	word     string
+	children map[int]*bkNode
+}
+
+// add adds the word to the tree.
+func (t *bkTree) add(w string) {
+	if t.root == nil {
+		t.root = &bkNode{word: w}
+		return
+	}
+
+	n := t.root
+	for {
+		d := levenshtein(n.word, w)
+		if d == 0 {
+			return
+		}
+		c, ok := n.children[d]
+		if !ok {
+			if n.children == nil {
+				n.children = make(map[int]*bkNode)
+			}
+			n.children[d] = &bkNode{word: w}
+			return
+		}
+		n = c
+	}
+}
+
+// find returns all the words in the tree within edit distance d of w.
+func (t *bkTree) find(w string, d int) []string {
+	if t.root == nil {
+		return nil
+	}
+
+	var result []string
+	stack := []*bkNode{t.root}
+	for len(stack) > 0 {
+		n := stack[len(stack)-1]
+		stack = stack[:len(stack)-1]
+
+		k := levenshtein(n.word, w)
+		if k <= d {
+			result = append(result, n.word)
+		}
+		for i := k - d; i <= k+d; i++ {
+			if c, ok := n.children[i]; ok {
+				stack = append(stack, c)
+			}
+		}
+	}
+	return result
+}
+
+// maxEditDistance returns the maximum edit distance to use when matching the word w:
+// short words must match exactly, longer words can have more typos.
+func maxEditDistance(w string) int {
+	switch n := len([]rune(w)); {
+	case n < 5:
+		return 0
+	case n < 9:
+		return 1
+	}
+	return 2
+}
+
+// translitReplacer maps common transliterations (i.e. of Russian or Czech names into
+// English, German or French) onto a common form.  The input is assumed to have already
+// been normalised by removeNonAlphaNumeric (so "č" has become "c").
+var translitReplacer = strings.NewReplacer(
+	"tsch", "c",
+	"sch", "s",
+	"tch", "c",
+	"ch", "c",
+	"kh", "c",
+	"sh", "s",
+	"ph", "f",
+	"ff", "v",
+	"w", "v",
+	"y", "i",
+	"j", "i",
+)
+
+// transliterate returns the transliteration key of the (normalised) word w, so that
+// Tchaikovsky, Tschaikowsky and Čajkovskij all have the same key.
+func transliterate(w string) string {
+	w = translitReplacer.Replace(w)
+
+	// Collapse repeated letters
+	r := []rune(w)
+	k := 0
+	for i, x := range r {
+		if i > 0 && x == r[i-1] {
+			continue
+		}
+		r[k] = x
+		k++
+	}
+	return string(r[:k])
+}
+
+// Match weights used to rank expanded words.
+const (
+	matchFuzzy = 1 + iota
+	matchPrefix
+	matchTranslit
+	matchExact
+)
+
+// FuzzyExpand is a type which implements Expander, expanding words to all words within
+// an edit distance (see maxEditDistance), words with the same transliteration and words
+// with the same prefix.
+type FuzzyExpand struct {
+	words    []string // sorted
+	tree     *bkTree
+	translit map[string][]string
+}
+
+// BuildFuzzyExpander builds an Expander for the given list of words.
+func BuildFuzzyExpander(words []string) FuzzyExpand {
+	f := FuzzyExpand{
+		tree:     &bkTree{},
+		translit: make(map[string][]string),
+	}
+
+	seen := make(map[string]bool, len(words))
+	for _, w := range words {
+		if seen[w] {
+			continue
+		}
+		seen[w] = true
+		f.words = append(f.words, w)
+		f.tree.add(w)
+		if k := transliterate(w); len(k) >= MinPrefix {
+			f.translit[k] = append(f.translit[k], w)
+		}
+	}
+	sort.Strings(f.words)
+	return f
+}
+
+// expand returns a map of words expanded from s, with the weight of each match.
+func (f FuzzyExpand) expand(s string) map[string]int {
+	m := map[string]int{s: matchExact}
+	add := func(w string, x int) {
+		if m[w] < x {
+			m[w] = x
+		}
+	}
+
+	if len(s) >= MinPrefix {
+		for i := sort.SearchStrings(f.words, s); i < len(f.words) && strings.HasPrefix(f.words[i], s); i++ {
+			add(f.words[i], matchPrefix)
+		}
+		for _, w := range f.translit[transliterate(s)] {
+			add(w, matchTranslit)
+		}
+	}
+	if d := maxEditDistance(s); d > 0 {
+		for _, w := range f.tree.find(s, d) {
+			add(w, matchFuzzy)
+		}
+	}
+	return m
+}
+
+// Expand implements Expander.
+func (f FuzzyExpand) Expand(s string) []string {
+	m := f.expand(s)
+	words := make([]string, 0, len(m))
+	for w := range m {
+		words = append(words, w)
+	}
+	sort.Sort(weightedWords{words, m})
+	return words
+}
+
+// weightedWords sorts words by descending weight, and then alphabetically.
+type weightedWords struct {
+	words   []string
+	weights map[string]int
+}
+
+func (w weightedWords) Len() int      { return len(w.words) }
+func (w weightedWords) Swap(i, j int) { w.words[i], w.words[j] = w.words[j], w.words[i] }
+func (w weightedWords) Less(i, j int) bool {
+	x, y := w.weights[w.words[i]], w.weights[w.words[j]]
+	if x != y {
+		return x > y
+	}
+	return w.words[i] < w.words[j]
+}
+
+// BuildFuzzyExpandSearcher constructs a fuzzy expander which wraps the given Searcher
+// by expanding each word in the search input using the WordIndex.
+func BuildFuzzyExpandSearcher(s Searcher, w WordIndex) Searcher {
+	return &expandSearcher{BuildFuzzyExpander(w.Words()), s}
+}
@@ -0,0 +1,145 @@
+// Copyright 2015, David Howden
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package index
+
+import (
+	"reflect"
+	"sort"
+	"testing"
+
+	"github.com/amiforus/tchaik/index/attr"
+)
+
+func TestLevenshtein(t *testing.T) {
+	tests := []struct {
+		a, b string
+		d    int
+	}{
+		{"", "", 0},
+		{"", "abc", 3},
+		{"abc", "", 3},
+		{"bach", "bach", 0},
+		{"bach", "bath", 1},
+		{"kitten", "sitting", 3},
+		{"shostakovitch", "shostakovich", 1},
+		{"rachmaninov", "rachmaninoff", 2},
+		{"dvořák", "dvorak", 2},
+	}
+
+	for ii, tt := range tests {
+		if got := levenshtein(tt.a, tt.b); got != tt.d {
+			t.Errorf("[%d] levenshtein(%q, %q) = %d, expected %d", ii, tt.a, tt.b, got, tt.d)
+		}
+	}
+}
+
+func TestBKTree(t *testing.T) {
+	words := []string{"bach", "bath", "back", "beethoven", "brahms", "bruckner", "berg", "bartok", "barber", "shostakovich", "stravinsky"}
+	tree := &bkTree{}
+	for _, w := range words {
+		tree.add(w)
+	}
+
+	for _, q := range []string{"bach", "bahc", "berg", "bartock", "shostakovitch", "xyz"} {
+		for d := 0; d < 4; d++ {
+			var expected []string
+			for _, w := range words {
+				if levenshtein(q, w) <= d {
+					expected = append(expected, w)
+				}
+			}
+			got := tree.find(q, d)
+			sort.Strings(got)
+			sort.Strings(expected)
+			if !reflect.DeepEqual(got, expected) {
+				t.Errorf("find(%q, %d) = %v, expected %v", q, d, got, expected)
+			}
+		}
+	}
+}
+
+func TestTransliterate(t *testing.T) {
+	tests := [][]string{
+		{"tchaikovsky", "tschaikowsky", "cajkovskij", "tchaikowsky"},
+		{"shostakovich", "schostakowitsch", "sostakovic"},
+		{"rachmaninov", "rachmaninoff", "rakhmaninov"},
+		{"prokofiev", "prokofieff", "prokofjew"},
+		{"stravinsky", "strawinski"},
+	}
+
+	for ii, tt := range tests {
+		k := transliterate(removeNonAlphaNumeric(tt[0]))
+		for _, w := range tt[1:] {
+			if got := transliterate(removeNonAlphaNumeric(w)); got != k {
+				t.Errorf("[%d] transliterate(%q) = %q, expected %q", ii, w, got, k)
+			}
+		}
+	}
+}
+
+func TestFuzzyExpand(t *testing.T) {
+	e := BuildFuzzyExpander([]string{"tchaikovsky", "tchaikovskys", "shostakovich", "bach", "bath", "rachmaninoff", "rachmaninov", "bachianas"})
+
+	tests := []struct {
+		in  string
+		out []string
+	}{
+		{"ba", []string{"ba"}},
+		{"bach", []string{"bach", "bachianas"}},
+		{"shostakovitch", []string{"shostakovitch", "shostakovich"}},
+		{"tschaikowsky", []string{"tschaikowsky", "tchaikovsky"}},
+		{"rachmaninov", []string{"rachmaninov", "rachmaninoff"}},
+		{"tchaikovsky", []string{"tchaikovsky", "tchaikovskys"}},
+	}
+
+	for ii, tt := range tests {
+		got := e.Expand(tt.in)
+		if !reflect.DeepEqual(got, tt.out) {
+			t.Errorf("[%d] Expand(%q) = %v, expected %v", ii, tt.in, got, tt.out)
+		}
+	}
+}
+
+func TestQuerySearcherFuzzy(t *testing.T) {
+	tracks := []testTrack{
+		{Name: "Symphony No. 6", Album: "Pathétique", Composer: "Pyotr Ilyich Tchaikovsky"},
+		{Name: "Symphony No. 5", Album: "Symphony No. 5", Composer: "Dmitri Shostakovich"},
+		{Name: "Piano Concerto No. 2", Album: "Concertos", Composer: "Sergei Rachmaninoff"},
+		{Name: "Prélude", Album: "Bachianas Brasileiras", Composer: "Heitor Villa-Lobos"},
+		{Name: "Aria", Album: "Goldberg Variations", Composer: "Johann Sebastian Bach"},
+	}
+	c := By(attr.String("Album")).Collect(testTracker(tracks))
+	s := BuildQuerySearcher(c, []attr.Interface{
+		attr.String("Composer"),
+		attr.String("Album"),
+		attr.String("Name"),
+	}, []string{"Composer", "Album", "Name"})
+
+	tests := []struct {
+		in  string
+		out []string
+	}{
+		{"shostakovitch", []string{"Symphony No. 5"}},
+		{"rachmaninov", []string{"Concertos"}},
+		{"Tschaikowsky", []string{"Pathétique"}},
+		{"Čajkovskij", []string{"Pathétique"}},
+		{"composer:tschaikowsky", []string{"Pathétique"}},
+		{"album:tschaikowsky", nil},
+		{`"tschaikowsky"`, nil},
+		// Exact matches come before prefix matches.
+		{"bach", []string{"Goldberg Variations", "Bachianas Brasileiras"}},
+		{"pathetique", []string{"Pathétique"}},
+	}
+
+	for ii, tt := range tests {
+		var got []string
+		for _, p := range s.Search(tt.in) {
+			got = append(got, c.Get(p[1]).Name())
+		}
+		if !reflect.DeepEqual(got, tt.out) {
+			t.Errorf("[%d] Search(%q) = %#v, expected %#v", ii, tt.in, got, tt.out)
+		}
+	}
+}
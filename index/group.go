@@ -120,19 +120,21 @@ func (c pathsCollection) Tracks() []Track {
 }
 
 // col is a basic implementation of Collection. It assumes that all Groups have unique names
-// and so uses Group names for the keys.
+// and so uses Group names for the keys (see groupKeys).
 type col struct {
-	keys []Key
-	name string
-	grps map[Key]group
-	flds map[string]interface{}
+	keys  []Key
+	name  string
+	grps  map[Key]group
+	flds  map[string]interface{}
+	names groupKeys
 }
 
 func newCol(name string) col {
 	return col{
-		name: name,
-		grps: make(map[Key]group),
-		flds: make(map[string]interface{}),
+		name:  name,
+		grps:  make(map[Key]group),
+		flds:  make(map[string]interface{}),
+		names: make(groupKeys),
 	}
 }
 
@@ -163,12 +165,127 @@ func (c *col) addTrack(n string, k Key, t Track) {
 	c.keys = append(c.keys, k)
 }
 
-// add adds the track t to the collection, using the name n as the key.
+// add adds the track t to the collection, using the name n to create the key.
 func (c *col) add(n string, t Track) {
-	k := Key(fmt.Sprintf("%x", sha1.Sum([]byte(n)))[:6])
+	c.addKeyName(n, n, t)
+}
+
+// addKeyName adds the track t to the collection in the group with name n, using the name kn
+// to create the key (i.e. when groups can have the same name).
+func (c *col) addKeyName(n, kn string, t Track) {
+	k, renamed := c.names.add(kn)
+	c.rekey(renamed)
 	c.addTrack(n, k, t)
 }
 
+// rekey changes the keys of the groups which have been renamed (see groupKeys.add).
+func (c *col) rekey(renamed map[Key]Key) {
+	renameKeys(c.keys, renamed, func(old, k Key) {
+		c.grps[k] = c.grps[old]
+		delete(c.grps, old)
+	})
+}
+
+// renameKeys replaces the keys which have been renamed (old key -> new key, see
+// groupKeys.add) in the list of keys, calling move for each so that the group with the old
+// key can be moved to the new key.
+func renameKeys(keys []Key, renamed map[Key]Key, move func(old, k Key)) {
+	for old, k := range renamed {
+		move(old, k)
+		for i, x := range keys {
+			if x == old {
+				keys[i] = k
+			}
+		}
+	}
+}
+
+// keyLen is the minimum length (in hex digits) of keys created from group names.
+const keyLen = 6
+
+// groupKeys creates keys for the names of groups in a collection.  The key for a name is
+// the shortest prefix (of at least keyLen hex digits) of the SHA-1 hash of the name which
+// isn't shared with any other name in the collection.  Keys only depend on the names in
+// the collection, so are stable when groups are renamed, added or removed (unless their
+// hashes collide), and never collide.
+type groupKeys map[Key][]string // prefix of length keyLen -> names
+
+// add adds the name n and returns its key, along with a map of old key -> new key for
+// any names already added whose keys have changed.
+func (gk groupKeys) add(n string) (Key, map[Key]Key) {
+	h := nameHash(n)
+	p := Key(h[:keyLen])
+	names := gk[p]
+	for _, x := range names {
+		if x == n {
+			return gk.key(p, h, n), nil
+		}
+	}
+
+	old := make([]Key, len(names))
+	for i, x := range names {
+		old[i] = gk.key(p, nameHash(x), x)
+	}
+	gk[p] = append(names, n)
+
+	renamed := make(map[Key]Key)
+	for i, x := range names {
+		if k := gk.key(p, nameHash(x), x); k != old[i] {
+			renamed[old[i]] = k
+		}
+	}
+	return gk.key(p, h, n), renamed
+}
+
+// unique returns a name for a new group with name n which isn't already in gk: n if it
+// isn't, otherwise n with an occurrence number appended.
+func (gk groupKeys) unique(n string) string {
+	x := n
+	for i := 2; gk.has(x); i++ {
+		x = fmt.Sprintf("%v\x00%d", n, i)
+	}
+	return x
+}
+
+// has returns true if the name n is in gk.
+func (gk groupKeys) has(n string) bool {
+	for _, x := range gk[Key(nameHash(n)[:keyLen])] {
+		if x == n {
+			return true
+		}
+	}
+	return false
+}
+
+// key returns the key for name n, which has hash h and prefix p.
+func (gk groupKeys) key(p Key, h, n string) Key {
+	l := keyLen
+	for _, x := range gk[p] {
+		if x == n {
+			continue
+		}
+		if m := sharedPrefixLen(h, nameHash(x)) + 1; m > l {
+			l = m
+		}
+	}
+	if l > len(h) {
+		l = len(h)
+	}
+	return Key(h[:l])
+}
+
+func nameHash(n string) string {
+	return fmt.Sprintf("%x", sha1.Sum([]byte(n)))
+}
+
+func sharedPrefixLen(s, t string) int {
+	n := 0
+	for n < len(s) && n < len(t) && s[n] == t[n] {
+		n++
+	}
+	return n
+}
+
 // collectionTracks iterates over all the tracks in all the groups of the collection to construct a
 // slice of Tracks.
 func collectionTracks(c Collection) []Track {
@@ -223,7 +340,7 @@ func (sg subCol) Field(f string) interface{} {
 }
 
 // SubCollect applies the given Collector to each of the "leaf" Groups
-// in the Collection.
+// in the Collection.  Fields of the leaf Groups are kept by the resulting Collections.
 func SubCollect(c Collection, r Collector) Collection {
 	keys := c.Keys()
 	nc := subCol{
@@ -237,11 +354,26 @@ func SubCollect(c Collection, r Collector) Collection {
 			nc.grps[k] = SubCollect(gc, r)
 			continue
 		}
-		nc.grps[k] = r.Collect(g)
+		nc.grps[k] = leafCol{r.Collect(g), g}
 	}
 	return nc
 }
 
+// leafCol is a Collection created from a leaf Group, which falls back to the fields of
+// the Group.
+type leafCol struct {
+	Collection
+	leaf Group
+}
+
+// Field implements Group.
+func (c leafCol) Field(f string) interface{} {
+	if x := c.Collection.Field(f); x != nil {
+		return x
+	}
+	return c.leaf.Field(f)
+}
+
 // WalkFn is the type of the function called for each Track visited by Walk.  Return
 // non-nil error from Walk to stop the trasversal, and return the error from Walk.
 type WalkFn func(Track, Path) error
@@ -158,3 +158,48 @@ func TestSubCollect(t *testing.T) {
 		t.Errorf("prefixCollection.Names() = %#v, expected %#v", pfxColNames, expectedPrefixGroupNames)
 	}
 }
+
+func TestGroupKeys(t *testing.T) {
+	// "Album 719" and "Album 7070" have SHA-1 hashes which share the first 6 hex digits.
+	tests := []struct {
+		names    []string
+		expected []Key
+	}{
+		{
+			[]string{"Album A", "Album B", "Album A"},
+			[]Key{"e847a6", "96a4ff", "e847a6"},
+		},
+		{
+			[]string{"Album 719", "Album A", "Album 7070"},
+			[]Key{"812de86", "e847a6", "812de87"},
+		},
+		{
+			[]string{"Album 7070", "Album 719"},
+			[]Key{"812de87", "812de86"},
+		},
+	}
+
+	for ii, tt := range tests {
+		var tracks []testTrack
+		for _, n := range tt.names {
+			tracks = append(tracks, testTrack{Album: n})
+		}
+		c := By(attr.String("Album")).Collect(testTracker(tracks))
+
+		var got []Key
+		for _, n := range tt.names {
+			got = append(got, Key(nameKeyMap(c)[n]))
+		}
+		if !reflect.DeepEqual(got, tt.expected) {
+			t.Errorf("[%d] keys = %v, expected %v", ii, got, tt.expected)
+		}
+		if len(c.Keys()) != len(nameKeyMap(c)) {
+			t.Errorf("[%d] len(Keys()) = %d, expected %d", ii, len(c.Keys()), len(nameKeyMap(c)))
+		}
+		for _, k := range c.Keys() {
+			if c.Get(k) == nil {
+				t.Errorf("[%d] Get(%#v) = nil, expected non-nil", ii, k)
+			}
+		}
+	}
+}
@@ -0,0 +1,297 @@
+// Copyright 2015, David Howden
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package index
+
+import (
+	"fmt"
+	"net/url"
+	"path/filepath"
+	"strings"
+
+	"github.com/amiforus/tchaik/index/attr"
+)
+
+// ByEach returns a Collector which groups tracks by each of the values of the attribute
+// (i.e. a track with multiple artists is added to the group of each artist).  Tracks with
+// an empty value are not included in the resulting Collection.
+func ByEach(a attr.Interface) Collector {
+	return groupByEach{a}
+}
+
+type groupByEach struct {
+	attr.Interface
+}
+
+// Collect implements Collector.
+func (a groupByEach) Collect(tracker Tracker) Collection {
+	gg := newCol(collectName(tracker, a.Name()))
+	for _, t := range tracker.Tracks() {
+		v := a.Value(t)
+		if a.IsEmpty(v) {
+			continue
+		}
+
+		switch v := v.(type) {
+		case []string:
+			seen := make(map[string]bool, len(v))
+			for _, x := range v {
+				if !seen[x] {
+					gg.add(x, t)
+					seen[x] = true
+				}
+			}
+		default:
+			gg.add(fmt.Sprintf("%v", v), t)
+		}
+	}
+	return gg
+}
+
+// collectName returns the name to use for a Collection created from the Tracker.
+func collectName(tracker Tracker, field string) string {
+	if tg, ok := tracker.(Group); ok {
+		return tg.Name()
+	}
+	return "by " + field
+}
+
+// ByDecade returns a Collector which groups tracks by the decade of the int field (i.e.
+// "1970s").  Tracks where the field is zero are not included in the resulting Collection.
+func ByDecade(field string) Collector {
+	return groupByDecade(field)
+}
+
+type groupByDecade string
+
+// Collect implements Collector.
+func (f groupByDecade) Collect(tracker Tracker) Collection {
+	gg := newCol(collectName(tracker, string(f)))
+	for _, t := range tracker.Tracks() {
+		if y := t.GetInt(string(f)); y > 0 {
+			gg.add(fmt.Sprintf("%ds", y-y%10), t)
+		}
+	}
+	return gg
+}
+
+// workName returns the name of the work which the track name is part of, or the empty
+// string if there isn't one.  Works are identified by the convention of naming tracks
+// "<Work>: <Movement>" (i.e. "Symphony No. 5 in C minor, Op. 67: I. Allegro con brio"),
+// see parseWork.
+func workName(name string) string {
+	return parseWork(name).title
+}
+
+// ByWork returns a Collector which groups tracks by the work they are part of (see
+// workName).  Works with the same catalogue number (see parseWork) are grouped together
+// using the first name seen for the work, and their groups have a Catalogue field.  Tracks
+// which don't have a work name are grouped by the field fallback.
+func ByWork(name, fallback string) Collector {
+	return groupByWork{name, fallback}
+}
+
+type groupByWork struct {
+	name, fallback string
+}
+
+// Collect implements Collector.
+func (w groupByWork) Collect(tracker Tracker) Collection {
+	tracks := tracker.Tracks()
+	works := make([]work, len(tracks))
+	titles := make(map[string]string) // catalogue -> first title
+	cats := make(map[string]string)   // title -> first catalogue
+	for i, t := range tracks {
+		x := parseWork(t.GetString(w.name))
+		works[i] = x
+		if x.catalogue == "" || x.title == "" {
+			continue
+		}
+		if _, ok := titles[x.catalogue]; !ok {
+			titles[x.catalogue] = x.title
+		}
+		if _, ok := cats[x.title]; !ok {
+			cats[x.title] = x.catalogue
+		}
+	}
+
+	gg := newCol(collectName(tracker, "Work"))
+	for i, t := range tracks {
+		n := works[i].title
+		if c := works[i].catalogue; c != "" && titles[c] != "" {
+			n = titles[c]
+		}
+		if n == "" {
+			n = t.GetString(w.fallback)
+		}
+		gg.add(n, t)
+	}
+
+	for _, k := range gg.keys {
+		g := gg.grps[k]
+		if c, ok := cats[g.name]; ok {
+			g.fields = map[string]interface{}{"Catalogue": c}
+			gg.grps[k] = g
+		}
+	}
+	return gg
+}
+
+// treeCol is an implementation of Collection which can contain Collections as well as
+// Groups.
+type treeCol struct {
+	name  string
+	keys  []Key
+	grps  map[Key]Group
+	names groupKeys
+}
+
+func newTreeCol(name string) *treeCol {
+	return &treeCol{
+		name:  name,
+		grps:  make(map[Key]Group),
+		names: make(groupKeys),
+	}
+}
+
+func (c *treeCol) Keys() []Key              { return c.keys }
+func (c *treeCol) Name() string             { return c.name }
+func (c *treeCol) Get(k Key) Group          { return c.grps[k] }
+func (c *treeCol) Field(string) interface{} { return nil }
+func (c *treeCol) Tracks() []Track          { return collectionTracks(c) }
+
+// add adds the Group g to the collection, using the Group name to create the key (see
+// groupKeys).  Groups with the same name are given different keys.
+func (c *treeCol) add(g Group) {
+	k, renamed := c.names.add(c.names.unique(g.Name()))
+	renameKeys(c.keys, renamed, func(old, k Key) {
+		c.grps[k] = c.grps[old]
+		delete(c.grps, old)
+	})
+	c.grps[k] = g
+	c.keys = append(c.keys, k)
+}
+
+// ByFolder returns a Collector which groups tracks by the directory tree of the path
+// in the field (usually "Location").  Directories which contain tracks become Groups,
+// and the directories above them become Collections.  Tracks in a directory which also
+// has sub-directories are put into a Group with the same name as the directory.  The
+// directories common to all tracks are removed from the top of the tree.
+func ByFolder(field string) Collector {
+	return groupByFolder(field)
+}
+
+type groupByFolder string
+
+// folderTrack is a Track along with the (remaining) directories of its path.
+type folderTrack struct {
+	Track
+	dirs []string
+}
+
+// Collect implements Collector.
+func (f groupByFolder) Collect(tracker Tracker) Collection {
+	var fts []folderTrack
+	for _, t := range tracker.Tracks() {
+		if dirs := splitDir(t.GetString(string(f))); len(dirs) > 0 {
+			fts = append(fts, folderTrack{t, dirs})
+		}
+	}
+
+	// Remove common directories, but leave at least one directory for each track.
+	n := 0
+	if len(fts) > 0 {
+		n = len(fts[0].dirs)
+		for _, t := range fts {
+			n = commonPrefixLen(fts[0].dirs[:n], t.dirs)
+			if n >= len(t.dirs) {
+				n = len(t.dirs) - 1
+			}
+		}
+	}
+
+	var names []string
+	sub := make(map[string][]folderTrack)
+	for _, t := range fts {
+		t.dirs = t.dirs[n:]
+		d := t.dirs[0]
+		if _, ok := sub[d]; !ok {
+			names = append(names, d)
+		}
+		sub[d] = append(sub[d], t)
+	}
+
+	c := newTreeCol(collectName(tracker, "Folder"))
+	for _, d := range names {
+		c.add(folderGroup(sub[d]))
+	}
+	return c
+}
+
+// folderGroup creates a Group for the tracks, which are all in the directory (or a
+// sub-directory of) given by the first of the remaining directories.
+func folderGroup(tracks []folderTrack) Group {
+	name := tracks[0].dirs[0]
+
+	var here []Track
+	var names []string
+	sub := make(map[string][]folderTrack)
+	for _, t := range tracks {
+		if len(t.dirs) == 1 {
+			here = append(here, t.Track)
+			continue
+		}
+		d := t.dirs[1]
+		if _, ok := sub[d]; !ok {
+			names = append(names, d)
+		}
+		sub[d] = append(sub[d], folderTrack{t.Track, t.dirs[1:]})
+	}
+
+	g := group{name: name, tracks: here}
+	if len(sub) == 0 {
+		return g
+	}
+
+	c := newTreeCol(name)
+	if len(here) > 0 {
+		c.add(g)
+	}
+	for _, d := range names {
+		c.add(folderGroup(sub[d]))
+	}
+	return c
+}
+
+// splitDir splits the directory of the path (which can also be a file:// URL) into
+// its components.
+func splitDir(path string) []string {
+	if strings.HasPrefix(path, "file://") {
+		if u, err := url.Parse(path); err == nil {
+			path = u.Path
+		}
+	}
+	path = filepath.ToSlash(path)
+	i := strings.LastIndex(path, "/")
+	if i < 0 {
+		return nil
+	}
+	path = path[:i]
+
+	var dirs []string
+	for _, d := range strings.Split(path, "/") {
+		if d != "" {
+			dirs = append(dirs, d)
+		}
+	}
+	return dirs
+}
+
+func commonPrefixLen(s, t []string) int {
+	n := 0
+	for n < len(s) && n < len(t) && s[n] == t[n] {
+		n++
+	}
+	return n
+}
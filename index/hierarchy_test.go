@@ -0,0 +1,267 @@
+// Copyright 2015, David Howden
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package index
+
+import (
+	"fmt"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/amiforus/tchaik/index/attr"
+)
+
+// albumTrack is a Track with the attributes used to group tracks by album and folder, and to
+// order them.
+type albumTrack struct {
+	Name, Album, AlbumArtist, Artist, Location string
+	SortAlbum, SortArtist                      string
+	Compilation                                int
+}
+
+func (f albumTrack) GetString(k string) string {
+	switch k {
+	case "Name":
+		return f.Name
+	case "Album":
+		return f.Album
+	case "AlbumArtist":
+		return f.AlbumArtist
+	case "Artist":
+		return f.Artist
+	case "Location":
+		return f.Location
+	case "SortAlbum":
+		return f.SortAlbum
+	case "SortArtist":
+		return f.SortArtist
+	}
+	return ""
+}
+
+func (f albumTrack) GetStrings(k string) []string {
+	switch k {
+	case "Artist", "AlbumArtist":
+		return DefaultGetStrings(f, k)
+	}
+	return nil
+}
+
+func (f albumTrack) GetInt(k string) int {
+	if k == "Compilation" {
+		return f.Compilation
+	}
+	return 0
+}
+
+func (f albumTrack) GetTime(string) time.Time {
+	return time.Time{}
+}
+
+type albumTracker []albumTrack
+
+func (d albumTracker) Tracks() []Track {
+	result := make([]Track, len(d))
+	for i, x := range d {
+		result[i] = x
+	}
+	return result
+}
+
+// describe returns a listing of the Group names in the Collection, indented by depth, with
+// the number of tracks in each leaf Group.
+func describe(c Collection, indent string) []string {
+	var out []string
+	for _, k := range c.Keys() {
+		x := c.Get(k)
+		if xc, ok := x.(Collection); ok {
+			out = append(out, indent+x.Name())
+			out = append(out, describe(xc, indent+"  ")...)
+			continue
+		}
+		out = append(out, fmt.Sprintf("%v%v (%d)", indent, x.Name(), len(x.Tracks())))
+	}
+	return out
+}
+
+func TestByEach(t *testing.T) {
+	tracks := []testTrack{
+		{Name: "1", Album: "A", Artist: "X"},
+		{Name: "2", Album: "B", Artist: "X"},
+		{Name: "3", Album: "B", Artist: "Y"},
+		{Name: "4", Album: "C"},
+		{Name: "5", Album: "C", stringsMap: map[string][]string{"Artist": {"Y", "Z", "Y"}}},
+	}
+
+	c := Collect(testTracker(tracks), ByEach(attr.Strings("Artist")))
+	c = SubCollect(c, By(attr.String("Album")))
+
+	got := describe(c, "")
+	expected := []string{
+		"X",
+		"  A (1)",
+		"  B (1)",
+		"Y",
+		"  B (1)",
+		"  C (1)",
+		"Z",
+		"  C (1)",
+	}
+	if !reflect.DeepEqual(got, expected) {
+		t.Errorf("ByEach(Artist) = %#v, expected %#v", got, expected)
+	}
+}
+
+func TestByDecade(t *testing.T) {
+	tracks := []testTrack{
+		{Name: "1", Year: 1972},
+		{Name: "2", Year: 1979},
+		{Name: "3", Year: 1980},
+		{Name: "4"},
+		{Name: "5", Year: 1972},
+	}
+
+	c := Collect(testTracker(tracks), ByDecade("Year"))
+	c = SubCollect(c, By(attr.Int("Year")))
+
+	got := describe(c, "")
+	expected := []string{
+		"1970s",
+		"  1972 (2)",
+		"  1979 (1)",
+		"1980s",
+		"  1980 (1)",
+	}
+	if !reflect.DeepEqual(got, expected) {
+		t.Errorf("ByDecade(Year) = %#v, expected %#v", got, expected)
+	}
+}
+
+func TestWorkName(t *testing.T) {
+	tests := []struct {
+		in, out string
+	}{
+		{"", ""},
+		{"Aria", ""},
+		{": Aria", ""},
+		{"Symphony No. 5 in C minor, Op. 67: I. Allegro con brio", "Symphony No. 5 in C minor, Op. 67"},
+		{"Goldberg Variations, BWV 988 : Aria", "Goldberg Variations, BWV 988"},
+		{"Piano Sonata, Hob. XVI:52: I. Allegro", "Piano Sonata, Hob. XVI:52"},
+	}
+
+	for ii, tt := range tests {
+		if got := workName(tt.in); got != tt.out {
+			t.Errorf("[%d] workName(%q) = %q, expected %q", ii, tt.in, got, tt.out)
+		}
+	}
+}
+
+func TestByWork(t *testing.T) {
+	tracks := []testTrack{
+		{Name: "Symphony No. 5: I. Allegro con brio", Album: "Karajan 1963", Composer: "Beethoven"},
+		{Name: "Symphony No. 5: II. Andante con moto", Album: "Karajan 1963", Composer: "Beethoven"},
+		{Name: "Symphony No. 5: I. Allegro con brio", Album: "Kleiber 1975", Composer: "Beethoven"},
+		{Name: "Für Elise", Album: "Bagatelles", Composer: "Beethoven"},
+		{Name: "Symphony No. 3 in E-flat major, Op. 55: I. Allegro con brio", Album: "Karajan 1963", Composer: "Beethoven"},
+		{Name: "Symphony No. 3 \"Eroica\", Op. 55: I. Allegro con brio", Album: "Kleiber 1975", Composer: "Beethoven"},
+	}
+
+	c := Collect(testTracker(tracks), ByEach(attr.Strings("Composer")))
+	c = SubCollect(c, ByWork("Name", "Album"))
+	c = SubCollect(c, By(attr.String("Album")))
+
+	got := describe(c, "")
+	expected := []string{
+		"Beethoven",
+		"  Symphony No. 5",
+		"    Karajan 1963 (2)",
+		"    Kleiber 1975 (1)",
+		"  Bagatelles",
+		"    Bagatelles (1)",
+		"  Symphony No. 3 in E-flat major, Op. 55",
+		"    Karajan 1963 (1)",
+		"    Kleiber 1975 (1)",
+	}
+	if !reflect.DeepEqual(got, expected) {
+		t.Errorf("ByWork(Name, Album) = %#v, expected %#v", got, expected)
+	}
+
+	w := c.Get(c.Keys()[0]).(Collection)
+	for _, k := range w.Keys() {
+		g := w.Get(k)
+		var expected interface{}
+		if g.Name() == "Symphony No. 3 in E-flat major, Op. 55" {
+			expected = "Op. 55"
+		}
+		if got := g.Field("Catalogue"); got != expected {
+			t.Errorf("%q Field(\"Catalogue\") = %#v, expected %#v", g.Name(), got, expected)
+		}
+	}
+}
+
+func TestSplitDir(t *testing.T) {
+	tests := []struct {
+		in  string
+		out []string
+	}{
+		{"", nil},
+		{"track.mp3", nil},
+		{"/track.mp3", nil},
+		{"/music/A/track.mp3", []string{"music", "A"}},
+		{"music//A/track.mp3", []string{"music", "A"}},
+		{"file:///Users/x/Music/My%20Album/01%20Track.m4a", []string{"Users", "x", "Music", "My Album"}},
+	}
+
+	for ii, tt := range tests {
+		if got := splitDir(tt.in); !reflect.DeepEqual(got, tt.out) {
+			t.Errorf("[%d] splitDir(%q) = %#v, expected %#v", ii, tt.in, got, tt.out)
+		}
+	}
+}
+
+func TestByFolder(t *testing.T) {
+	tests := []struct {
+		locations []string
+		out       []string
+	}{
+		{
+			nil,
+			nil,
+		},
+		{
+			[]string{"/music/A/1.mp3", "/music/A/2.mp3"},
+			[]string{"A (2)"},
+		},
+		{
+			[]string{
+				"/music/A/1.mp3",
+				"/music/B/CD1/1.mp3",
+				"/music/B/CD2/1.mp3",
+				"/music/B/CD2/2.mp3",
+				"/music/B/cover.mp3",
+				"/music/A/2.mp3",
+				"track.mp3",
+			},
+			[]string{
+				"A (2)",
+				"B",
+				"  B (1)",
+				"  CD1 (1)",
+				"  CD2 (2)",
+			},
+		},
+	}
+
+	for ii, tt := range tests {
+		tracks := make(albumTracker, len(tt.locations))
+		for i, l := range tt.locations {
+			tracks[i] = albumTrack{Location: l}
+		}
+		got := describe(Collect(tracks, ByFolder("Location")), "")
+		if !reflect.DeepEqual(got, tt.out) {
+			t.Errorf("[%d] ByFolder(Location) = %#v, expected %#v", ii, got, tt.out)
+		}
+	}
+}
@@ -3,6 +3,7 @@ package history
 
 import (
 	"fmt"
+	"sort"
 	"sync"
 	"time"
 
@@ -16,6 +17,20 @@ type Store interface {
 	Add(index.Path) error
 	// Get the play events associated to a path.
 	Get(index.Path) []time.Time
+	// Import adds play events which happened in the past (i.e. recorded by another media
+	// player) to the store.
+	Import([]Plays) error
+	// Migrate maps the paths in the store using the index.PathMap (i.e. after the library has
+	// been rebuilt), and returns the paths which could not be mapped (which are kept, so
+	// that they can be resolved by a later migration).
+	// Play events of paths which are mapped to the same path are combined.
+	Migrate(index.PathMap) ([]index.Path, error)
+}
+
+// Plays is a list of times at which a path was played.
+type Plays struct {
+	Path  index.Path
+	Times []time.Time
 }
 
 // NewStore creates a basic implementation of a play history store, using the given path as the
@@ -50,6 +65,20 @@ func (s *store) Add(p index.Path) error {
 	return s.store.Persist(&s.m)
 }
 
+// Import implements Store.
+func (s *store) Import(ps []Plays) error {
+	s.Lock()
+	defer s.Unlock()
+
+	for _, p := range ps {
+		k := fmt.Sprintf("%v", p.Path)
+		for _, t := range p.Times {
+			s.m[k] = append(s.m[k], t.UTC())
+		}
+	}
+	return s.store.Persist(&s.m)
+}
+
 // Get implements Store.
 func (s *store) Get(p index.Path) []time.Time {
 	s.RLock()
@@ -57,3 +86,21 @@ func (s *store) Get(p index.Path) []time.Time {
 
 	return s.m[fmt.Sprintf("%v", p)]
 }
+
+// Migrate implements Store.
+func (s *store) Migrate(pm index.PathMap) ([]index.Path, error) {
+	s.Lock()
+	defer s.Unlock()
+
+	return s.store.Migrate(&s.m, pm, func(x, y interface{}) interface{} {
+		v := append(append([]time.Time(nil), x.([]time.Time)...), y.([]time.Time)...)
+		sort.Sort(byTime(v))
+		return v
+	})
+}
+
+type byTime []time.Time
+
+func (t byTime) Len() int           { return len(t) }
+func (t byTime) Swap(i, j int)      { t[i], t[j] = t[j], t[i] }
+func (t byTime) Less(i, j int) bool { return t[i].Before(t[j]) }
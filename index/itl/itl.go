@@ -102,6 +102,22 @@ func (t *itlTrack) GetString(name string) string {
 		return html.UnescapeString(t.Genre)
 	case "Kind":
 		return html.UnescapeString(t.Kind)
+	case "Comment":
+		return html.UnescapeString(t.Comments)
+	case "SortName":
+		return html.UnescapeString(t.SortName)
+	case "SortAlbum":
+		return html.UnescapeString(t.SortAlbum)
+	case "SortAlbumArtist":
+		return html.UnescapeString(t.SortAlbumArtist)
+	case "SortArtist":
+		return html.UnescapeString(t.SortArtist)
+	case "SortComposer":
+		return html.UnescapeString(t.SortComposer)
+	case "Label", "CatalogueNumber", "ISRC", "MusicBrainzTrackID", "MusicBrainzAlbumID",
+		"MusicBrainzArtistID", "MusicBrainzAlbumArtistID":
+		// Not available in iTunes Library files.
+		return ""
 	}
 
 	tt := reflect.TypeOf(t)
@@ -147,6 +163,18 @@ func (t *itlTrack) GetInt(name string) int {
 		return t.TotalTime
 	case "BitRate":
 		return t.BitRate
+	case "BPM":
+		return t.BPM
+	case "Size":
+		return t.Size
+	case "Compilation":
+		if t.Compilation {
+			return 1
+		}
+		return 0
+	case "HasLyrics", "TrackGain", "TrackPeak", "AlbumGain", "AlbumPeak":
+		// Not available in iTunes Library files.
+		return 0
 	}
 
 	tt := reflect.TypeOf(t)
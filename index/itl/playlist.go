@@ -0,0 +1,98 @@
+// Copyright 2015, David Howden
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package itl
+
+import (
+	"fmt"
+	"html"
+	"strconv"
+
+	rawitl "github.com/dhowden/itl"
+
+	"github.com/amiforus/tchaik/index"
+	"github.com/amiforus/tchaik/index/playlist"
+)
+
+// Playlist is a playlist from an iTunes Library.
+type Playlist struct {
+	// Name of the playlist, prefixed by the names of the folders which contain it
+	// (separated by "/").
+	Name string
+
+	// TrackIDs are the IDs of the tracks in the playlist.
+	TrackIDs []string
+}
+
+// Playlists returns the playlists in the iTunes Library l, which must have been created by
+// ReadFrom.  Folders, system playlists (i.e. "Library", "Music", "Purchased") and smart
+// playlists (whose rules are stored in an undocumented binary format) are skipped: the
+// names of skipped smart playlists are also returned.
+func Playlists(l index.Library) ([]Playlist, []string, error) {
+	il, ok := l.(*itlLibrary)
+	if !ok {
+		return nil, nil, fmt.Errorf("expected iTunes Library, got %T", l)
+	}
+
+	folders := make(map[string]rawitl.Playlist)
+	for _, p := range il.Playlists {
+		if p.Folder {
+			folders[p.PlaylistPersistentID] = p
+		}
+	}
+
+	var result []Playlist
+	var smart []string
+	for _, p := range il.Playlists {
+		if p.Folder || p.Master || p.DistinguishedKind != 0 {
+			continue
+		}
+
+		name := html.UnescapeString(p.Name)
+		seen := make(map[string]bool)
+		for id := p.ParentPersistentID; id != "" && !seen[id]; {
+			seen[id] = true
+			f, ok := folders[id]
+			if !ok {
+				break
+			}
+			name = html.UnescapeString(f.Name) + "/" + name
+			id = f.ParentPersistentID
+		}
+
+		if len(p.SmartInfo) > 0 || len(p.SmartCriteria) > 0 {
+			smart = append(smart, name)
+			continue
+		}
+
+		ids := make([]string, len(p.PlaylistItems))
+		for i, x := range p.PlaylistItems {
+			ids[i] = strconv.Itoa(x.TrackID)
+		}
+		result = append(result, Playlist{
+			Name:     name,
+			TrackIDs: ids,
+		})
+	}
+	return result, smart, nil
+}
+
+// ImportPlaylists adds the playlists to the store, using the "Root" collection c to find
+// the tracks.  Playlists which already exist in the store are not changed.  Returns the
+// names of the playlists which were added.
+func ImportPlaylists(ps []Playlist, s playlist.Store, c index.Collection) ([]string, error) {
+	var added []string
+	for _, p := range ps {
+		if s.Get(p.Name) != nil {
+			continue
+		}
+		// Tracks which aren't audio files (i.e. videos) are not in the library.
+		np, _ := playlist.ImportIDs(p.TrackIDs, c)
+		if err := s.Set(p.Name, np); err != nil {
+			return added, err
+		}
+		added = append(added, p.Name)
+	}
+	return added, nil
+}
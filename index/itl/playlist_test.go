@@ -0,0 +1,42 @@
+// Copyright 2015, David Howden
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package itl
+
+import (
+	"reflect"
+	"testing"
+
+	rawitl "github.com/dhowden/itl"
+)
+
+func TestPlaylists(t *testing.T) {
+	l := &itlLibrary{&rawitl.Library{
+		Playlists: []rawitl.Playlist{
+			{Name: "Library", Master: true, PlaylistItems: []rawitl.PlaylistItem{{TrackID: 1}}},
+			{Name: "Music", DistinguishedKind: 4, PlaylistItems: []rawitl.PlaylistItem{{TrackID: 1}}},
+			{Name: "Jazz", Folder: true, PlaylistPersistentID: "A"},
+			{Name: "Miles &#38; Trane", Folder: true, PlaylistPersistentID: "B", ParentPersistentID: "A"},
+			{Name: "Best", ParentPersistentID: "B", PlaylistItems: []rawitl.PlaylistItem{{TrackID: 2}, {TrackID: 1}}},
+			{Name: "Recent", ParentPersistentID: "A", SmartCriteria: []byte{1}},
+			{Name: "Road Trip"},
+		},
+	}}
+
+	got, smart, err := Playlists(l)
+	if err != nil {
+		t.Fatalf("unexpected error from Playlists(): %v", err)
+	}
+
+	expected := []Playlist{
+		{Name: "Jazz/Miles & Trane/Best", TrackIDs: []string{"2", "1"}},
+		{Name: "Road Trip", TrackIDs: []string{}},
+	}
+	if !reflect.DeepEqual(got, expected) {
+		t.Errorf("Playlists() = %#v, expected %#v", got, expected)
+	}
+	if expectedSmart := []string{"Jazz/Recent"}; !reflect.DeepEqual(smart, expectedSmart) {
+		t.Errorf("Playlists() smart = %#v, expected %#v", smart, expectedSmart)
+	}
+}
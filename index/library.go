@@ -6,6 +6,8 @@
 package index
 
 import (
+	"bufio"
+	"bytes"
 	"compress/gzip"
 	"encoding/json"
 	"fmt"
@@ -47,6 +49,18 @@ type Track interface {
 // tchaik Library implementation.
 // NB: The identifier field is set to be the value of ID on every track, regardless
 // of whether this value has already been set in the input Library.
+//
+// As well as the core attributes, the following optional attributes are kept (Library
+// implementations return zero values for any they don't support):
+//
+//	string:  Comment, SortName, SortAlbum, SortAlbumArtist, SortArtist, SortComposer,
+//	         Label, CatalogueNumber, ISRC, MusicBrainzTrackID, MusicBrainzAlbumID,
+//	         MusicBrainzArtistID, MusicBrainzAlbumArtistID
+//	strings: Artist, AlbumArtist, Composer (when there are multiple values, i.e. from
+//	         multi-valued tags)
+//	int:    BPM, Size (bytes), Compilation, HasLyrics (1 if true, 0 otherwise),
+//	        TrackGain, AlbumGain (ReplayGain, hundredths of a dB), TrackPeak,
+//	        AlbumPeak (ReplayGain, millionths of full scale)
 func Convert(l Library, id string) *library {
 	allTracks := l.Tracks()
 	tracks := make(map[string]*track, len(allTracks))
@@ -65,6 +79,26 @@ func Convert(l Library, id string) *library {
 			Location:    t.GetString("Location"),
 			Kind:        t.GetString("Kind"),
 
+			// optional string fields
+			Comment:                  t.GetString("Comment"),
+			SortName:                 t.GetString("SortName"),
+			SortAlbum:                t.GetString("SortAlbum"),
+			SortAlbumArtist:          t.GetString("SortAlbumArtist"),
+			SortArtist:               t.GetString("SortArtist"),
+			SortComposer:             t.GetString("SortComposer"),
+			Label:                    t.GetString("Label"),
+			CatalogueNumber:          t.GetString("CatalogueNumber"),
+			ISRC:                     t.GetString("ISRC"),
+			MusicBrainzTrackID:       t.GetString("MusicBrainzTrackID"),
+			MusicBrainzAlbumID:       t.GetString("MusicBrainzAlbumID"),
+			MusicBrainzArtistID:      t.GetString("MusicBrainzArtistID"),
+			MusicBrainzAlbumArtistID: t.GetString("MusicBrainzAlbumArtistID"),
+
+			// optional strings fields
+			Artists:      multiValues(t, "Artist"),
+			AlbumArtists: multiValues(t, "AlbumArtist"),
+			Composers:    multiValues(t, "Composer"),
+
 			// integer fields
 			TotalTime:   t.GetInt("TotalTime"),
 			Year:        t.GetInt("Year"),
@@ -74,6 +108,16 @@ func Convert(l Library, id string) *library {
 			DiscCount:   t.GetInt("DiscCount"),
 			BitRate:     t.GetInt("BitRate"),
 
+			// optional integer fields
+			BPM:         t.GetInt("BPM"),
+			Size:        t.GetInt("Size"),
+			Compilation: t.GetInt("Compilation") != 0,
+			HasLyrics:   t.GetInt("HasLyrics") != 0,
+			TrackGain:   t.GetInt("TrackGain"),
+			TrackPeak:   t.GetInt("TrackPeak"),
+			AlbumGain:   t.GetInt("AlbumGain"),
+			AlbumPeak:   t.GetInt("AlbumPeak"),
+
 			// date fields
 			DateAdded:    t.GetTime("DateAdded"),
 			DateModified: t.GetTime("DateModified"),
@@ -84,6 +128,15 @@ func Convert(l Library, id string) *library {
 	}
 }
 
+// multiValues returns the values of the strings field of the track if it has more than one
+// (i.e. from a multi-valued tag), otherwise nil.
+func multiValues(t Track, f string) []string {
+	if v := t.GetStrings(f); len(v) > 1 {
+		return v
+	}
+	return nil
+}
+
 // library is the default internal implementation Library which acts as the data
 // source for all media tracks.
 type library struct {
@@ -105,13 +158,49 @@ func (l *library) Track(id string) (Track, bool) {
 	return t, ok
 }
 
+// libraryVersion is the version of the library format written by WriteTo.  Version 1 (a JSON
+// object of tracks keyed by ID, without the optional track fields) can still be read.
+const libraryVersion = 2
+
+// libraryJSON is the JSON representation of a library (from version 2).
+type libraryJSON struct {
+	Version int               `json:"version"`
+	Tracks  map[string]*track `json:"tracks"`
+}
+
 func (l *library) MarshalJSON() ([]byte, error) {
-	return json.Marshal(l.trks)
+	return json.Marshal(libraryJSON{
+		Version: libraryVersion,
+		Tracks:  l.trks,
+	})
 }
 
 func (l *library) UnmarshalJSON(b []byte) error {
-	l.trks = make(map[string]*track)
-	return json.Unmarshal(b, &l.trks)
+	var m map[string]json.RawMessage
+	if err := json.Unmarshal(b, &m); err != nil {
+		return err
+	}
+
+	// In version 1 every value is a track object, so a numeric version field can't
+	// be confused with a track ID.
+	v, ok := m["version"]
+	if !ok || bytes.HasPrefix(bytes.TrimSpace(v), []byte("{")) {
+		l.trks = make(map[string]*track, len(m))
+		return json.Unmarshal(b, &l.trks)
+	}
+
+	var x libraryJSON
+	if err := json.Unmarshal(b, &x); err != nil {
+		return err
+	}
+	if x.Version > libraryVersion {
+		return fmt.Errorf("unsupported library version: %d", x.Version)
+	}
+	l.trks = x.Tracks
+	if l.trks == nil {
+		l.trks = make(map[string]*track)
+	}
+	return nil
 }
 
 // WriteTo writes the Library data to the writer, currently using gzipped-JSON.
@@ -125,9 +214,15 @@ func WriteTo(l Library, w io.Writer) error {
 	return enc.Encode(l)
 }
 
-// ReadFrom reads the gzipped-JSON representation of a Library.
+// ReadFrom reads a Library written by WriteTo (gzipped-JSON) or WriteBinaryTo (binary), the
+// format is detected automatically.
 func ReadFrom(r io.Reader) (Library, error) {
-	gzr, err := gzip.NewReader(r)
+	br := bufio.NewReader(r)
+	if b, err := br.Peek(len(binaryMagic)); err == nil && string(b) == binaryMagic {
+		return readBinary(br)
+	}
+
+	gzr, err := gzip.NewReader(br)
 	if err != nil {
 		return nil, err
 	}
@@ -135,7 +230,35 @@ func ReadFrom(r io.Reader) (Library, error) {
 	dec := json.NewDecoder(gzr)
 	l := &library{}
 	err = dec.Decode(l)
-	return l, err
+	if err != nil {
+		return nil, err
+	}
+	l.intern()
+	return l, nil
+}
+
+// intern replaces repeated string values in the tracks of l (i.e. Album, Artist) with a single
+// copy, to reduce the memory used by a decoded library.
+func (l *library) intern() {
+	m := make(map[string]string)
+	for _, t := range l.trks {
+		for _, s := range t.stringFields() {
+			if x, ok := m[*s]; ok {
+				*s = x
+				continue
+			}
+			m[*s] = *s
+		}
+		for _, ss := range t.listFields() {
+			for i, s := range *ss {
+				if x, ok := m[s]; ok {
+					(*ss)[i] = x
+					continue
+				}
+				m[s] = s
+			}
+		}
+	}
 }
 
 // track is the default implementation of the Track interface.
@@ -150,6 +273,25 @@ type track struct {
 	Location    string `json:"location,omitempty"`
 	Kind        string `json:"kind"`
 
+	Comment                  string `json:"comment,omitempty"`
+	SortName                 string `json:"sortName,omitempty"`
+	SortAlbum                string `json:"sortAlbum,omitempty"`
+	SortAlbumArtist          string `json:"sortAlbumArtist,omitempty"`
+	SortArtist               string `json:"sortArtist,omitempty"`
+	SortComposer             string `json:"sortComposer,omitempty"`
+	Label                    string `json:"label,omitempty"`
+	CatalogueNumber          string `json:"catalogueNumber,omitempty"`
+	ISRC                     string `json:"isrc,omitempty"`
+	MusicBrainzTrackID       string `json:"musicBrainzTrackID,omitempty"`
+	MusicBrainzAlbumID       string `json:"musicBrainzAlbumID,omitempty"`
+	MusicBrainzArtistID      string `json:"musicBrainzArtistID,omitempty"`
+	MusicBrainzAlbumArtistID string `json:"musicBrainzAlbumArtistID,omitempty"`
+
+	// Values of multi-valued tags, nil unless there is more than one value.
+	Artists      []string `json:"artists,omitempty"`
+	AlbumArtists []string `json:"albumArtists,omitempty"`
+	Composers    []string `json:"composers,omitempty"`
+
 	TotalTime   int `json:"totalTime,omitempty"`
 	Year        int `json:"year,omitempty"`
 	DiscNumber  int `json:"discNumber,omitempty"`
@@ -158,6 +300,15 @@ type track struct {
 	DiscCount   int `json:"discCount,omitempty"`
 	BitRate     int `json:"bitRate,omitempty"`
 
+	BPM         int  `json:"bpm,omitempty"`
+	Size        int  `json:"size,omitempty"`
+	Compilation bool `json:"compilation,omitempty"`
+	HasLyrics   bool `json:"hasLyrics,omitempty"`
+	TrackGain   int  `json:"trackGain,omitempty"`
+	TrackPeak   int  `json:"trackPeak,omitempty"`
+	AlbumGain   int  `json:"albumGain,omitempty"`
+	AlbumPeak   int  `json:"albumPeak,omitempty"`
+
 	DateAdded    time.Time `json:"dateAdded,omitempty"`
 	DateModified time.Time `json:"dateModified,omitempty"`
 }
@@ -183,6 +334,32 @@ func (t *track) GetString(name string) string {
 		return t.Location
 	case "Kind":
 		return t.Kind
+	case "Comment":
+		return t.Comment
+	case "SortName":
+		return t.SortName
+	case "SortAlbum":
+		return t.SortAlbum
+	case "SortAlbumArtist":
+		return t.SortAlbumArtist
+	case "SortArtist":
+		return t.SortArtist
+	case "SortComposer":
+		return t.SortComposer
+	case "Label":
+		return t.Label
+	case "CatalogueNumber":
+		return t.CatalogueNumber
+	case "ISRC":
+		return t.ISRC
+	case "MusicBrainzTrackID":
+		return t.MusicBrainzTrackID
+	case "MusicBrainzAlbumID":
+		return t.MusicBrainzAlbumID
+	case "MusicBrainzArtistID":
+		return t.MusicBrainzArtistID
+	case "MusicBrainzAlbumArtistID":
+		return t.MusicBrainzAlbumArtistID
 	}
 	panic(fmt.Sprintf("unknown string field '%v'", name))
 }
@@ -201,7 +378,20 @@ func DefaultGetStrings(t Track, f string) []string {
 // GetStrings implements Track.
 func (t *track) GetStrings(name string) []string {
 	switch name {
-	case "Artist", "AlbumArtist", "Composer":
+	case "Artist":
+		if t.Artists != nil {
+			return t.Artists
+		}
+		return DefaultGetStrings(t, name)
+	case "AlbumArtist":
+		if t.AlbumArtists != nil {
+			return t.AlbumArtists
+		}
+		return DefaultGetStrings(t, name)
+	case "Composer":
+		if t.Composers != nil {
+			return t.Composers
+		}
 		return DefaultGetStrings(t, name)
 	}
 	panic(fmt.Sprintf("unknown strings field '%v", name))
@@ -224,10 +414,34 @@ func (t *track) GetInt(name string) int {
 		return t.DiscCount
 	case "BitRate":
 		return t.BitRate
+	case "BPM":
+		return t.BPM
+	case "Size":
+		return t.Size
+	case "Compilation":
+		return boolInt(t.Compilation)
+	case "HasLyrics":
+		return boolInt(t.HasLyrics)
+	case "TrackGain":
+		return t.TrackGain
+	case "TrackPeak":
+		return t.TrackPeak
+	case "AlbumGain":
+		return t.AlbumGain
+	case "AlbumPeak":
+		return t.AlbumPeak
 	}
 	panic(fmt.Sprintf("unknown int field '%v'", name))
 }
 
+// boolInt returns 1 if b is true, 0 otherwise (boolean attributes are accessed using GetInt).
+func boolInt(b bool) int {
+	if b {
+		return 1
+	}
+	return 0
+}
+
 // GetTime implements Track.
 func (t *track) GetTime(name string) time.Time {
 	switch name {
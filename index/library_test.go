@@ -2,6 +2,7 @@ package index
 
 import (
 	"bytes"
+	"compress/gzip"
 	"reflect"
 	"testing"
 	"time"
@@ -18,6 +19,20 @@ var tr = track{
 	Location:    "Location",
 	Kind:        "Kind",
 
+	Comment:                  "Comment",
+	SortName:                 "SortName",
+	SortAlbum:                "SortAlbum",
+	SortAlbumArtist:          "SortAlbumArtist",
+	SortArtist:               "SortArtist",
+	SortComposer:             "SortComposer",
+	Label:                    "Label",
+	CatalogueNumber:          "CatalogueNumber",
+	ISRC:                     "ISRC",
+	MusicBrainzTrackID:       "MusicBrainzTrackID",
+	MusicBrainzAlbumID:       "MusicBrainzAlbumID",
+	MusicBrainzArtistID:      "MusicBrainzArtistID",
+	MusicBrainzAlbumArtistID: "MusicBrainzAlbumArtistID",
+
 	TotalTime:   1,
 	Year:        2,
 	DiscNumber:  3,
@@ -26,12 +41,23 @@ var tr = track{
 	DiscCount:   6,
 	BitRate:     7,
 
+	BPM:         8,
+	Size:        9,
+	TrackGain:   10,
+	TrackPeak:   11,
+	AlbumGain:   12,
+	AlbumPeak:   13,
+	Compilation: true,
+	HasLyrics:   true,
+
 	DateAdded:    time.Now(),
 	DateModified: time.Now(),
 }
 
 func TestTrack(t *testing.T) {
-	stringFields := []string{"ID", "Name", "Album", "AlbumArtist", "Artist", "Composer", "Genre", "Location", "Kind"}
+	stringFields := []string{"ID", "Name", "Album", "AlbumArtist", "Artist", "Composer", "Genre", "Location", "Kind",
+		"Comment", "SortName", "SortAlbum", "SortAlbumArtist", "SortArtist", "SortComposer", "Label", "CatalogueNumber",
+		"ISRC", "MusicBrainzTrackID", "MusicBrainzAlbumID", "MusicBrainzArtistID", "MusicBrainzAlbumArtistID"}
 	for _, f := range stringFields {
 		got := tr.GetString(f)
 		if got != f {
@@ -59,6 +85,11 @@ func TestTrack(t *testing.T) {
 		}
 	}
 
+	multi := track{Artist: "A; B", Artists: []string{"A", "B"}}
+	if got, expected := multi.GetStrings("Artist"), []string{"A", "B"}; !reflect.DeepEqual(got, expected) {
+		t.Errorf("multi.GetStrings(\"Artist\") = %#v, expected %#v", got, expected)
+	}
+
 	func() {
 		defer func() {
 			if r := recover(); r == nil {
@@ -70,7 +101,8 @@ func TestTrack(t *testing.T) {
 		t.Errorf("expected panic from GetStrings, got: %v", y)
 	}()
 
-	intFields := []string{"TotalTime", "Year", "DiscNumber", "TrackNumber", "TrackCount", "DiscCount", "BitRate"}
+	intFields := []string{"TotalTime", "Year", "DiscNumber", "TrackNumber", "TrackCount", "DiscCount", "BitRate",
+		"BPM", "Size", "TrackGain", "TrackPeak", "AlbumGain", "AlbumPeak"}
 	for i, f := range intFields {
 		got := tr.GetInt(f)
 		expected := i + 1
@@ -79,6 +111,12 @@ func TestTrack(t *testing.T) {
 		}
 	}
 
+	for _, f := range []string{"Compilation", "HasLyrics"} {
+		if got := tr.GetInt(f); got != 1 {
+			t.Errorf("tr.GetInt(%#v) = %d, expected 1", f, got)
+		}
+	}
+
 	func() {
 		defer func() {
 			if r := recover(); r == nil {
@@ -164,10 +202,45 @@ func TestLibraryEncodeDecode(t *testing.T) {
 	gotTrack := gotTracks[0].(*track)
 	expectedTrack := expectedTracks[0].(*track)
 
+	// Local also strips the monotonic clock reading from the expected times.
 	gotTrack.DateAdded = gotTrack.DateAdded.Local()
 	gotTrack.DateModified = gotTrack.DateModified.Local()
+	expectedTrack.DateAdded = expectedTrack.DateAdded.Local()
+	expectedTrack.DateModified = expectedTrack.DateModified.Local()
 
 	if !reflect.DeepEqual(expectedTrack, gotTrack) {
 		t.Errorf("Encode -> Decode inconsistent, got: %#v, expected: %#v", gotTrack, expectedTrack)
 	}
 }
+
+func TestLibraryReadVersion1(t *testing.T) {
+	buf := &bytes.Buffer{}
+	gzw := gzip.NewWriter(buf)
+	gzw.Write([]byte(`{"1":{"id":"1","name":"Name","album":"Album","kind":"Kind","trackNumber":2}}`))
+	gzw.Close()
+
+	l, err := ReadFrom(buf)
+	if err != nil {
+		t.Fatalf("unexpected error in ReadFrom: %v", err)
+	}
+
+	got, ok := l.Track("1")
+	if !ok {
+		t.Fatalf("expected track with ID \"1\"")
+	}
+	expected := &track{ID: "1", Name: "Name", Album: "Album", Kind: "Kind", TrackNumber: 2}
+	if !reflect.DeepEqual(got, expected) {
+		t.Errorf("l.Track(\"1\") = %#v, expected %#v", got, expected)
+	}
+}
+
+func TestLibraryReadUnsupportedVersion(t *testing.T) {
+	buf := &bytes.Buffer{}
+	gzw := gzip.NewWriter(buf)
+	gzw.Write([]byte(`{"version":1000,"tracks":{}}`))
+	gzw.Close()
+
+	if _, err := ReadFrom(buf); err == nil {
+		t.Errorf("expected error reading unsupported library version")
+	}
+}
@@ -0,0 +1,363 @@
+// Copyright 2015, David Howden
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Package merge defines functionality for comparing two libraries (i.e. built from different
+// sources) and merging them into one.
+package merge
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/amiforus/tchaik/index"
+	"github.com/amiforus/tchaik/index/dupes"
+)
+
+// Attributes which are compared and merged (ID is handled separately).
+var (
+	stringFields = []string{
+		"Name", "Album", "AlbumArtist", "Artist", "Composer", "Genre", "Location", "Kind",
+		"Comment", "SortName", "SortAlbum", "SortAlbumArtist", "SortArtist", "SortComposer",
+		"Label", "CatalogueNumber", "ISRC", "MusicBrainzTrackID", "MusicBrainzAlbumID",
+		"MusicBrainzArtistID", "MusicBrainzAlbumArtistID",
+	}
+
+	intFields = []string{
+		"TotalTime", "Year", "DiscNumber", "TrackNumber", "TrackCount", "DiscCount", "BitRate",
+		"BPM", "Size", "Compilation", "HasLyrics", "TrackGain", "TrackPeak", "AlbumGain", "AlbumPeak",
+	}
+
+	timeFields = []string{"DateAdded", "DateModified"}
+
+	// listFields are the string attributes which can have multiple values (see
+	// index.Track.GetStrings).
+	listFields = []string{"AlbumArtist", "Artist", "Composer"}
+
+	// flagFields are the int attributes which are either 0 or 1, and so are always set.
+	flagFields = []string{"Compilation", "HasLyrics"}
+)
+
+// contains returns true if name is in fields.
+func contains(fields []string, name string) bool {
+	for _, f := range fields {
+		if f == name {
+			return true
+		}
+	}
+	return false
+}
+
+// isField returns true if name is an attribute which is compared and merged.
+func isField(name string) bool {
+	return contains(stringFields, name) || contains(intFields, name) || contains(timeFields, name)
+}
+
+// equalStrings returns true if a and b contain the same values in the same order.
+func equalStrings(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// Change is a track which is in both libraries being compared, but with different attributes.
+type Change struct {
+	A, B   index.Track
+	Fields []string // names of the attributes which differ
+}
+
+// Diff is the difference between two libraries a and b.
+type Diff struct {
+	Added   []index.Track // tracks in b but not a
+	Removed []index.Track // tracks in a but not b
+	Changed []Change
+
+	pairs []pair // all matching tracks (changed or not)
+}
+
+// pair is a track in a and its match in b.
+type pair struct {
+	a, b index.Track
+}
+
+// Compare returns the Diff between the libraries a and b.  Tracks are matched by ID, and then
+// remaining tracks are matched by their normalised metadata (see dupes.Key) so that tracks
+// which have different IDs in each library (i.e. because they were imported from different
+// sources) are still matched.  Tracks in the Diff are ordered by Location.
+func Compare(a, b index.Library) *Diff {
+	d := &Diff{}
+
+	unmatched := make(map[string]index.Track) // ID -> track in b
+	for _, t := range b.Tracks() {
+		unmatched[t.GetString("ID")] = t
+	}
+
+	var rest []index.Track
+	for _, t := range a.Tracks() {
+		id := t.GetString("ID")
+		if u, ok := unmatched[id]; ok {
+			d.pairs = append(d.pairs, pair{t, u})
+			delete(unmatched, id)
+			continue
+		}
+		rest = append(rest, t)
+	}
+
+	byKey := make(map[string][]index.Track)
+	for _, t := range sortByLocation(unmatched) {
+		if k := dupes.Key(t); k != "" {
+			byKey[k] = append(byKey[k], t)
+		}
+	}
+
+	for _, t := range rest {
+		k := dupes.Key(t)
+		if us := byKey[k]; k != "" && len(us) > 0 {
+			d.pairs = append(d.pairs, pair{t, us[0]})
+			byKey[k] = us[1:]
+			delete(unmatched, us[0].GetString("ID"))
+			continue
+		}
+		d.Removed = append(d.Removed, t)
+	}
+	d.Added = sortByLocation(unmatched)
+	sort.Sort(byLocation(d.Removed))
+
+	sort.Sort(pairsByLocation(d.pairs))
+	for _, p := range d.pairs {
+		if fields := differ(p.a, p.b); len(fields) > 0 {
+			d.Changed = append(d.Changed, Change{A: p.a, B: p.b, Fields: fields})
+		}
+	}
+	return d
+}
+
+// differ returns the names of the attributes which differ between a and b.
+func differ(a, b index.Track) []string {
+	var fields []string
+	for _, f := range stringFields {
+		if a.GetString(f) != b.GetString(f) {
+			fields = append(fields, f)
+			continue
+		}
+		if contains(listFields, f) && !equalStrings(a.GetStrings(f), b.GetStrings(f)) {
+			fields = append(fields, f)
+		}
+	}
+	for _, f := range intFields {
+		if a.GetInt(f) != b.GetInt(f) {
+			fields = append(fields, f)
+		}
+	}
+	for _, f := range timeFields {
+		if !a.GetTime(f).Equal(b.GetTime(f)) {
+			fields = append(fields, f)
+		}
+	}
+	return fields
+}
+
+func sortByLocation(m map[string]index.Track) []index.Track {
+	tracks := make([]index.Track, 0, len(m))
+	for _, t := range m {
+		tracks = append(tracks, t)
+	}
+	sort.Sort(byLocation(tracks))
+	return tracks
+}
+
+type byLocation []index.Track
+
+func (l byLocation) Len() int      { return len(l) }
+func (l byLocation) Swap(i, j int) { l[i], l[j] = l[j], l[i] }
+func (l byLocation) Less(i, j int) bool {
+	if x, y := l[i].GetString("Location"), l[j].GetString("Location"); x != y {
+		return x < y
+	}
+	return l[i].GetString("ID") < l[j].GetString("ID")
+}
+
+type pairsByLocation []pair
+
+func (p pairsByLocation) Len() int      { return len(p) }
+func (p pairsByLocation) Swap(i, j int) { p[i], p[j] = p[j], p[i] }
+func (p pairsByLocation) Less(i, j int) bool {
+	return byLocation{p[i].a, p[j].a}.Less(0, 1)
+}
+
+// Side is one of the libraries being merged.
+type Side int
+
+// Sides of a merge.
+const (
+	A Side = iota
+	B
+)
+
+// String implements fmt.Stringer.
+func (s Side) String() string {
+	if s == B {
+		return "b"
+	}
+	return "a"
+}
+
+// ParseSide returns the Side named by s ("a" or "b").
+func ParseSide(s string) (Side, error) {
+	switch s {
+	case "a":
+		return A, nil
+	case "b":
+		return B, nil
+	}
+	return A, fmt.Errorf("invalid side '%v': must be either a or b", s)
+}
+
+// Precedence determines which library the attributes of merged tracks are taken from.
+type Precedence struct {
+	// Default is the library which takes precedence for attributes not in Fields.
+	Default Side
+
+	// Fields is a map of attribute name -> the library which takes precedence.
+	Fields map[string]Side
+}
+
+// Side returns the library which takes precedence for the attribute name.
+func (p Precedence) Side(name string) Side {
+	if s, ok := p.Fields[name]; ok {
+		return s
+	}
+	return p.Default
+}
+
+// ParsePrecedence parses a comma separated list of field=side pairs (i.e. "Location=b,BitRate=b")
+// into a Precedence with default d.
+func ParsePrecedence(d Side, s string) (Precedence, error) {
+	p := Precedence{
+		Default: d,
+		Fields:  make(map[string]Side),
+	}
+	if s == "" {
+		return p, nil
+	}
+
+	for _, x := range strings.Split(s, ",") {
+		kv := strings.SplitN(strings.TrimSpace(x), "=", 2)
+		if len(kv) != 2 {
+			return p, fmt.Errorf("invalid field precedence '%v': expected field=side", x)
+		}
+		if !isField(kv[0]) {
+			return p, fmt.Errorf("invalid field precedence '%v': unknown field '%v'", x, kv[0])
+		}
+		side, err := ParseSide(kv[1])
+		if err != nil {
+			return p, fmt.Errorf("invalid field precedence '%v': %v", x, err)
+		}
+		p.Fields[kv[0]] = side
+	}
+	return p, nil
+}
+
+// Merge returns a Library containing the tracks of both a and b, where tracks matched by d
+// (see Compare) are merged: each attribute is taken from the library which takes precedence
+// in p, unless it is unset there, in which case it is taken from the other (flags such as
+// Compilation and HasLyrics are always set, so 0 is never overridden).  Merged tracks always
+// keep their ID from a, so that references to them (i.e. in playlists) still work.
+func Merge(d *Diff, p Precedence) index.Library {
+	tracks := make(map[string]index.Track, len(d.pairs)+len(d.Added)+len(d.Removed))
+	for _, x := range d.pairs {
+		tracks[x.a.GetString("ID")] = &track{
+			a: x.a,
+			b: x.b,
+			p: p,
+		}
+	}
+	for _, t := range d.Removed {
+		tracks[t.GetString("ID")] = t
+	}
+	for _, t := range d.Added {
+		// Tracks in b which have the ID of a track in a are always matched to it, so
+		// there are no clashes here.
+		tracks[t.GetString("ID")] = t
+	}
+	return library(tracks)
+}
+
+// library is an index.Library of merged tracks.
+type library map[string]index.Track
+
+// Tracks implements index.Library.
+func (l library) Tracks() []index.Track {
+	tracks := make([]index.Track, 0, len(l))
+	for _, t := range l {
+		tracks = append(tracks, t)
+	}
+	return tracks
+}
+
+// Track implements index.Library.
+func (l library) Track(id string) (index.Track, bool) {
+	t, ok := l[id]
+	return t, ok
+}
+
+// track is an index.Track merged from a track in a and a track in b.
+type track struct {
+	a, b index.Track
+	p    Precedence
+}
+
+// order returns the tracks in order of precedence for the attribute name.
+func (t *track) order(name string) (index.Track, index.Track) {
+	if t.p.Side(name) == B {
+		return t.b, t.a
+	}
+	return t.a, t.b
+}
+
+// GetString implements index.Track.
+func (t *track) GetString(name string) string {
+	if name == "ID" {
+		return t.a.GetString(name)
+	}
+	x, y := t.order(name)
+	if v := x.GetString(name); v != "" {
+		return v
+	}
+	return y.GetString(name)
+}
+
+// GetStrings implements index.Track.
+func (t *track) GetStrings(name string) []string {
+	x, y := t.order(name)
+	if v := x.GetStrings(name); len(v) > 0 {
+		return v
+	}
+	return y.GetStrings(name)
+}
+
+// GetInt implements index.Track.
+func (t *track) GetInt(name string) int {
+	x, y := t.order(name)
+	if v := x.GetInt(name); v != 0 || contains(flagFields, name) {
+		return v
+	}
+	return y.GetInt(name)
+}
+
+// GetTime implements index.Track.
+func (t *track) GetTime(name string) time.Time {
+	x, y := t.order(name)
+	if v := x.GetTime(name); !v.IsZero() {
+		return v
+	}
+	return y.GetTime(name)
+}
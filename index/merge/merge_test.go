@@ -0,0 +1,255 @@
+// Copyright 2015, David Howden
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package merge
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/amiforus/tchaik/index"
+)
+
+type testTrack struct {
+	ID, Name, Album, Artist, Genre, Location string
+	Artists                                  []string
+	TrackNumber, BitRate, Compilation        int
+	DateAdded                                time.Time
+}
+
+func (t testTrack) GetString(name string) string {
+	switch name {
+	case "ID":
+		return t.ID
+	case "Name":
+		return t.Name
+	case "Album":
+		return t.Album
+	case "Artist":
+		return t.Artist
+	case "Genre":
+		return t.Genre
+	case "Location":
+		return t.Location
+	}
+	return ""
+}
+
+func (t testTrack) GetStrings(name string) []string {
+	if name == "Artist" && len(t.Artists) > 0 {
+		return t.Artists
+	}
+	return index.DefaultGetStrings(t, name)
+}
+
+func (t testTrack) GetInt(name string) int {
+	switch name {
+	case "TrackNumber":
+		return t.TrackNumber
+	case "BitRate":
+		return t.BitRate
+	case "Compilation":
+		return t.Compilation
+	}
+	return 0
+}
+
+func (t testTrack) GetTime(name string) time.Time {
+	if name == "DateAdded" {
+		return t.DateAdded
+	}
+	return time.Time{}
+}
+
+type testLibrary []index.Track
+
+func (l testLibrary) Tracks() []index.Track { return l }
+
+func (l testLibrary) Track(id string) (index.Track, bool) {
+	for _, t := range l {
+		if t.GetString("ID") == id {
+			return t, true
+		}
+	}
+	return nil, false
+}
+
+func ids(tracks []index.Track) []string {
+	result := make([]string, len(tracks))
+	for i, t := range tracks {
+		result[i] = t.GetString("ID")
+	}
+	return result
+}
+
+var added = time.Date(2015, time.January, 1, 0, 0, 0, 0, time.UTC)
+
+var testA = testLibrary{
+	testTrack{ID: "1", Name: "So What", Album: "Kind of Blue", Artist: "Miles Davis", TrackNumber: 1, Genre: "Jazz", Location: "/itunes/01.mp3", BitRate: 320, DateAdded: added},
+	testTrack{ID: "2", Name: "Freddie Freeloader", Album: "Kind of Blue", Artist: "Miles Davis", TrackNumber: 2, Location: "/itunes/02.mp3", BitRate: 320, DateAdded: added},
+	testTrack{ID: "3", Name: "Blue in Green", Album: "Kind of Blue", Artist: "Miles Davis", TrackNumber: 3, Location: "/itunes/03.mp3", BitRate: 320, DateAdded: added},
+	testTrack{ID: "4", Name: "All Blues", Album: "Kind of Blue", Artist: "Miles Davis", TrackNumber: 4, Location: "/itunes/04.mp3"},
+}
+
+var testB = testLibrary{
+	testTrack{ID: "1", Name: "So What", Album: "Kind of Blue", Artist: "Miles Davis", TrackNumber: 1, Location: "/nas/01.flac", BitRate: 900},
+	testTrack{ID: "2", Name: "Freddie Freeloader", Album: "Kind of Blue", Artist: "Miles Davis", TrackNumber: 2, Location: "/itunes/02.mp3", BitRate: 320, DateAdded: added},
+	testTrack{ID: "x3", Name: "Blue In Green", Album: "Kind Of Blue", Artist: "Miles Davis", TrackNumber: 3, Location: "/nas/03.flac", BitRate: 900},
+	testTrack{ID: "x5", Name: "Flamenco Sketches", Album: "Kind of Blue", Artist: "Miles Davis", TrackNumber: 5, Location: "/nas/05.flac", BitRate: 900},
+}
+
+func TestCompare(t *testing.T) {
+	d := Compare(testA, testB)
+
+	if got, expected := ids(d.Added), []string{"x5"}; !reflect.DeepEqual(got, expected) {
+		t.Errorf("Added = %v, expected %v", got, expected)
+	}
+	if got, expected := ids(d.Removed), []string{"4"}; !reflect.DeepEqual(got, expected) {
+		t.Errorf("Removed = %v, expected %v", got, expected)
+	}
+
+	expected := []struct {
+		a, b   string
+		fields []string
+	}{
+		{"1", "1", []string{"Genre", "Location", "BitRate", "DateAdded"}},
+		{"3", "x3", []string{"Name", "Album", "Location", "BitRate", "DateAdded"}},
+	}
+	if len(d.Changed) != len(expected) {
+		t.Fatalf("len(Changed) = %d, expected %d", len(d.Changed), len(expected))
+	}
+	for ii, c := range d.Changed {
+		tt := expected[ii]
+		if c.A.GetString("ID") != tt.a || c.B.GetString("ID") != tt.b {
+			t.Errorf("[%d] Changed = %v -> %v, expected %v -> %v", ii, c.A.GetString("ID"), c.B.GetString("ID"), tt.a, tt.b)
+		}
+		if !reflect.DeepEqual(c.Fields, tt.fields) {
+			t.Errorf("[%d] Fields = %v, expected %v", ii, c.Fields, tt.fields)
+		}
+	}
+}
+
+func TestParsePrecedence(t *testing.T) {
+	tests := []struct {
+		in       string
+		expected map[string]Side
+		err      bool
+	}{
+		{"", map[string]Side{}, false},
+		{"Location=b", map[string]Side{"Location": B}, false},
+		{"Location=b, Genre=a", map[string]Side{"Location": B, "Genre": A}, false},
+		{"Location", nil, true},
+		{"Location=c", nil, true},
+		{"Unknown=a", nil, true},
+	}
+
+	for ii, tt := range tests {
+		p, err := ParsePrecedence(B, tt.in)
+		if tt.err {
+			if err == nil {
+				t.Errorf("[%d] ParsePrecedence(%#v) returned nil error", ii, tt.in)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("[%d] ParsePrecedence(%#v) returned error: %v", ii, tt.in, err)
+			continue
+		}
+		if p.Default != B || !reflect.DeepEqual(p.Fields, tt.expected) {
+			t.Errorf("[%d] ParsePrecedence(%#v) = %v, expected %v", ii, tt.in, p.Fields, tt.expected)
+		}
+	}
+}
+
+func TestMerge(t *testing.T) {
+	p := Precedence{
+		Default: A,
+		Fields: map[string]Side{
+			"Location": B,
+			"BitRate":  B,
+		},
+	}
+	l := Merge(Compare(testA, testB), p)
+
+	if got, expected := len(l.Tracks()), 5; got != expected {
+		t.Errorf("len(Tracks()) = %d, expected %d", got, expected)
+	}
+
+	tests := []struct {
+		id, name, genre, location string
+		bitRate                   int
+		dateAdded                 time.Time
+	}{
+		{"1", "So What", "Jazz", "/nas/01.flac", 900, added},
+		{"2", "Freddie Freeloader", "", "/itunes/02.mp3", 320, added},
+		{"3", "Blue in Green", "", "/nas/03.flac", 900, added},
+		{"4", "All Blues", "", "/itunes/04.mp3", 0, time.Time{}},
+		{"x5", "Flamenco Sketches", "", "/nas/05.flac", 900, time.Time{}},
+	}
+
+	for ii, tt := range tests {
+		x, ok := l.Track(tt.id)
+		if !ok {
+			t.Errorf("[%d] Track(%#v) not found", ii, tt.id)
+			continue
+		}
+		if got := x.GetString("ID"); got != tt.id {
+			t.Errorf("[%d] ID = %#v, expected %#v", ii, got, tt.id)
+		}
+		if got := x.GetString("Name"); got != tt.name {
+			t.Errorf("[%d] Name = %#v, expected %#v", ii, got, tt.name)
+		}
+		if got := x.GetString("Genre"); got != tt.genre {
+			t.Errorf("[%d] Genre = %#v, expected %#v", ii, got, tt.genre)
+		}
+		if got := x.GetString("Location"); got != tt.location {
+			t.Errorf("[%d] Location = %#v, expected %#v", ii, got, tt.location)
+		}
+		if got := x.GetInt("BitRate"); got != tt.bitRate {
+			t.Errorf("[%d] BitRate = %d, expected %d", ii, got, tt.bitRate)
+		}
+		if got := x.GetTime("DateAdded"); !got.Equal(tt.dateAdded) {
+			t.Errorf("[%d] DateAdded = %v, expected %v", ii, got, tt.dateAdded)
+		}
+	}
+}
+
+func TestCompareLists(t *testing.T) {
+	a := testLibrary{
+		testTrack{ID: "1", Name: "Summertime", Artist: "Ella Fitzgerald, Louis Armstrong", Artists: []string{"Ella Fitzgerald", "Louis Armstrong"}},
+	}
+	b := testLibrary{
+		testTrack{ID: "1", Name: "Summertime", Artist: "Ella Fitzgerald, Louis Armstrong", Artists: []string{"Ella Fitzgerald"}},
+	}
+
+	d := Compare(a, b)
+	if len(d.Changed) != 1 {
+		t.Fatalf("len(Changed) = %d, expected 1", len(d.Changed))
+	}
+	if got, expected := d.Changed[0].Fields, []string{"Artist"}; !reflect.DeepEqual(got, expected) {
+		t.Errorf("Fields = %v, expected %v", got, expected)
+	}
+}
+
+func TestMergeFlags(t *testing.T) {
+	a := testLibrary{
+		testTrack{ID: "1", Name: "So What", Compilation: 0, TrackNumber: 0},
+	}
+	b := testLibrary{
+		testTrack{ID: "1", Name: "So What", Compilation: 1, TrackNumber: 1},
+	}
+
+	l := Merge(Compare(a, b), Precedence{Default: A})
+	x, ok := l.Track("1")
+	if !ok {
+		t.Fatalf("Track(%#v) not found", "1")
+	}
+	if got := x.GetInt("Compilation"); got != 0 {
+		t.Errorf("Compilation = %d, expected 0", got)
+	}
+	if got := x.GetInt("TrackNumber"); got != 1 {
+		t.Errorf("TrackNumber = %d, expected 1", got)
+	}
+}
@@ -0,0 +1,251 @@
+// Copyright 2015, David Howden
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Package migrate defines types and methods used when creating Tchaik libraries from the
+// libraries of other media players, including carrying over play counts and ratings.
+package migrate
+
+import (
+	"crypto/sha1"
+	"fmt"
+	"net/url"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/amiforus/tchaik/index"
+	"github.com/amiforus/tchaik/index/history"
+	"github.com/amiforus/tchaik/index/rating"
+)
+
+// Stats are the play statistics of a track recorded by another media player.
+type Stats struct {
+	PlayCount  int
+	LastPlayed time.Time
+	Rating     rating.Value
+}
+
+// Track is an implementation of index.Track for tracks read from the library of another
+// media player.  As with tracks read from the filesystem, the ID is the SHA1 sum of the
+// Location.
+type Track struct {
+	Name, Album, AlbumArtist, Artist, Composer, Genre string
+	Location                                          string
+
+	TotalTime               int // milliseconds
+	Year                    int
+	DiscNumber, DiscCount   int
+	TrackNumber, TrackCount int
+	BitRate                 int // kbps
+
+	DateAdded, DateModified time.Time
+
+	Stats
+}
+
+// GetString implements index.Track.
+func (t *Track) GetString(name string) string {
+	switch name {
+	case "Name":
+		if t.Name == "" {
+			base := filepath.Base(t.Location)
+			return strings.TrimSuffix(base, filepath.Ext(base))
+		}
+		return t.Name
+	case "Album":
+		return t.Album
+	case "AlbumArtist":
+		return t.AlbumArtist
+	case "Artist":
+		return t.Artist
+	case "Composer":
+		return t.Composer
+	case "Genre":
+		return t.Genre
+	case "Location":
+		return t.Location
+	case "Kind":
+		return Kind(t.Location)
+	case "ID":
+		return fmt.Sprintf("%x", sha1.Sum([]byte(t.Location)))
+	}
+	return ""
+}
+
+// GetStrings implements index.Track.
+func (t *Track) GetStrings(name string) []string {
+	switch name {
+	case "Artist", "AlbumArtist", "Composer":
+		return index.DefaultGetStrings(t, name)
+	}
+	return nil
+}
+
+// GetInt implements index.Track.
+func (t *Track) GetInt(name string) int {
+	switch name {
+	case "TotalTime":
+		return t.TotalTime
+	case "Year":
+		return t.Year
+	case "DiscNumber":
+		return t.DiscNumber
+	case "DiscCount":
+		return t.DiscCount
+	case "TrackNumber":
+		return t.TrackNumber
+	case "TrackCount":
+		return t.TrackCount
+	case "BitRate":
+		return t.BitRate
+	}
+	return 0
+}
+
+// GetTime implements index.Track.
+func (t *Track) GetTime(name string) time.Time {
+	switch name {
+	case "DateAdded":
+		return t.DateAdded
+	case "DateModified":
+		return t.DateModified
+	}
+	return time.Time{}
+}
+
+// Kind returns the kind of audio file (as used by iTunes) from the extension of the path.
+func Kind(path string) string {
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".mp3":
+		return "MPEG audio file"
+	case ".m4a", ".aac":
+		return "AAC audio file"
+	case ".flac":
+		return "FLAC audio file"
+	case ".ogg", ".oga":
+		return "OGG audio file"
+	}
+	return ""
+}
+
+// FileLocation returns the path of the file:// URL loc.  Any other location is returned
+// unchanged.
+func FileLocation(loc string) string {
+	if strings.HasPrefix(loc, "file://") {
+		if u, err := url.Parse(loc); err == nil {
+			return filepath.FromSlash(u.Path)
+		}
+	}
+	return loc
+}
+
+// Library is an implementation of index.Library which also has the play statistics
+// of its tracks.
+type Library struct {
+	tracks map[string]*Track
+}
+
+// NewLibrary creates a Library from the tracks.
+func NewLibrary(tracks []*Track) *Library {
+	m := make(map[string]*Track, len(tracks))
+	for _, t := range tracks {
+		m[t.GetString("ID")] = t
+	}
+	return &Library{m}
+}
+
+// Tracks implements index.Library.
+func (l *Library) Tracks() []index.Track {
+	tracks := make([]index.Track, 0, len(l.tracks))
+	for _, t := range l.tracks {
+		tracks = append(tracks, t)
+	}
+	return tracks
+}
+
+// Track implements index.Library.
+func (l *Library) Track(id string) (index.Track, bool) {
+	t, ok := l.tracks[id]
+	if !ok {
+		return nil, false
+	}
+	return t, true
+}
+
+// Stats returns the play statistics for the track with the given ID.
+func (l *Library) Stats(id string) (Stats, bool) {
+	t, ok := l.tracks[id]
+	if !ok {
+		return Stats{}, false
+	}
+	return t.Stats, true
+}
+
+// Import adds the play counts and ratings of the tracks in l to the history and rating
+// stores, using the "Root" collection c (created from l) to find the paths of the tracks.
+// As only the time of the last play is known, each play is recorded at that time (or
+// at the time the track was added if it isn't known).  Tracks which already have play
+// history or a rating are not changed, so importing a library again doesn't add plays
+// twice.  Either store can be nil, in which case nothing is imported into it.  Returns the
+// number of tracks with imported play counts and ratings.
+func Import(l *Library, c index.Collection, h history.Store, r rating.Store) (plays, ratings int, err error) {
+	var ps []history.Plays
+	var rs []rating.Rating
+	index.Walk(c, index.Path{"Root"}, func(t index.Track, p index.Path) error {
+		s, ok := l.Stats(t.GetString("ID"))
+		if !ok {
+			return nil
+		}
+
+		if h != nil && s.PlayCount > 0 && len(h.Get(p)) == 0 {
+			last := s.LastPlayed
+			if last.IsZero() {
+				last = t.GetTime("DateAdded")
+			}
+			times := make([]time.Time, s.PlayCount)
+			for i := range times {
+				times[i] = last
+			}
+			ps = append(ps, history.Plays{Path: p, Times: times})
+		}
+
+		if r != nil && s.Rating != rating.None && s.Rating.IsValid() && r.Get(p) == rating.None {
+			rs = append(rs, rating.Rating{Path: p, Value: s.Rating})
+		}
+		return nil
+	})
+
+	if len(ps) > 0 {
+		if err := h.Import(ps); err != nil {
+			return 0, 0, err
+		}
+	}
+	if len(rs) > 0 {
+		if err := r.Import(rs); err != nil {
+			return len(ps), 0, err
+		}
+	}
+	return len(ps), len(rs), nil
+}
+
+// RatingFromFraction converts a rating between 0 and 1 (as used by Clementine and beets)
+// into a Value.  Negative fractions (no rating) are converted to None.
+func RatingFromFraction(f float64) rating.Value {
+	if f <= 0 {
+		return rating.None
+	}
+	if f > 1 {
+		f = 1
+	}
+	return rating.Value(f*5 + 0.5)
+}
+
+// UnixTime returns the time.Time for the Unix time in seconds, or the zero time.Time if
+// it is not positive (used by many players to mean "never").
+func UnixTime(sec int64) time.Time {
+	if sec <= 0 {
+		return time.Time{}
+	}
+	return time.Unix(sec, 0)
+}
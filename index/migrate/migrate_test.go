@@ -0,0 +1,153 @@
+// Copyright 2015, David Howden
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package migrate
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/amiforus/tchaik/index"
+	"github.com/amiforus/tchaik/index/history"
+	"github.com/amiforus/tchaik/index/rating"
+)
+
+type testHistory map[string][]time.Time
+
+func (h testHistory) Add(p index.Path) error       { return nil }
+func (h testHistory) Get(p index.Path) []time.Time { return h[fmt.Sprintf("%v", p)] }
+func (h testHistory) Import(ps []history.Plays) error {
+	for _, x := range ps {
+		k := fmt.Sprintf("%v", x.Path)
+		h[k] = append(h[k], x.Times...)
+	}
+	return nil
+}
+
+func (h testHistory) Migrate(index.PathMap) ([]index.Path, error) { return nil, nil }
+
+type testRatings map[string]rating.Value
+
+func (r testRatings) Set(p index.Path, v rating.Value) error { return nil }
+func (r testRatings) Get(p index.Path) rating.Value          { return r[fmt.Sprintf("%v", p)] }
+func (r testRatings) Import(rs []rating.Rating) error {
+	for _, x := range rs {
+		r[fmt.Sprintf("%v", x.Path)] = x.Value
+	}
+	return nil
+}
+
+func (r testRatings) Migrate(index.PathMap) ([]index.Path, error) { return nil, nil }
+
+func TestTrack(t *testing.T) {
+	tr := &Track{Location: "/music/Artist/Album/01 Song.flac", Artist: "Artist"}
+	if got := tr.GetString("Name"); got != "01 Song" {
+		t.Errorf("GetString(\"Name\") = %q, expected %q", got, "01 Song")
+	}
+	if got := tr.GetString("Kind"); got != "FLAC audio file" {
+		t.Errorf("GetString(\"Kind\") = %q, expected %q", got, "FLAC audio file")
+	}
+	if got := tr.GetString("ID"); len(got) != 40 {
+		t.Errorf("GetString(\"ID\") = %q, expected SHA1 hex", got)
+	}
+	if got := tr.GetStrings("Artist"); len(got) != 1 || got[0] != "Artist" {
+		t.Errorf("GetStrings(\"Artist\") = %#v, expected []string{\"Artist\"}", got)
+	}
+	if got := tr.GetStrings("Composer"); got != nil {
+		t.Errorf("GetStrings(\"Composer\") = %#v, expected nil", got)
+	}
+}
+
+func TestFileLocation(t *testing.T) {
+	tests := []struct {
+		in, out string
+	}{
+		{"file:///music/A%20B/c.mp3", "/music/A B/c.mp3"},
+		{"/music/c.mp3", "/music/c.mp3"},
+		{"http://example.com/c.mp3", "http://example.com/c.mp3"},
+	}
+
+	for ii, tt := range tests {
+		if got := FileLocation(tt.in); got != tt.out {
+			t.Errorf("[%d] FileLocation(%q) = %q, expected %q", ii, tt.in, got, tt.out)
+		}
+	}
+}
+
+func TestRatingFromFraction(t *testing.T) {
+	tests := []struct {
+		in  float64
+		out rating.Value
+	}{
+		{-1, rating.None},
+		{0, rating.None},
+		{0.2, 1},
+		{0.5, 3},
+		{0.8, 4},
+		{1, 5},
+		{1.5, 5},
+	}
+
+	for ii, tt := range tests {
+		if got := RatingFromFraction(tt.in); got != tt.out {
+			t.Errorf("[%d] RatingFromFraction(%v) = %v, expected %v", ii, tt.in, got, tt.out)
+		}
+	}
+}
+
+func TestImport(t *testing.T) {
+	added := time.Date(2015, time.March, 1, 0, 0, 0, 0, time.UTC)
+	last := time.Date(2015, time.June, 1, 0, 0, 0, 0, time.UTC)
+	l := NewLibrary([]*Track{
+		{Name: "One", Album: "Album", Location: "/a/1.mp3", TrackNumber: 1, DateAdded: added, Stats: Stats{PlayCount: 2, LastPlayed: last, Rating: 4}},
+		{Name: "Two", Album: "Album", Location: "/a/2.mp3", TrackNumber: 2, DateAdded: added, Stats: Stats{PlayCount: 1}},
+		{Name: "Three", Album: "Album", Location: "/a/3.mp3", TrackNumber: 3, DateAdded: added, Stats: Stats{Rating: 2}},
+	})
+	c := &index.RootCollection{Collection: index.CollectAlbums(l, index.GroupByAlbum, index.DefaultCollation)}
+
+	h, r := testHistory{}, testRatings{}
+	var paths []string
+	index.Walk(c, index.Path{"Root"}, func(t index.Track, p index.Path) error {
+		paths = append(paths, fmt.Sprintf("%v", p))
+		return nil
+	})
+	if len(paths) != 3 {
+		t.Fatalf("expected 3 tracks in collection, got %d", len(paths))
+	}
+	// An existing rating should not be replaced.
+	r[paths[2]] = 5
+
+	plays, ratings, err := Import(l, c, h, r)
+	if err != nil {
+		t.Fatalf("unexpected error from Import(): %v", err)
+	}
+	if plays != 2 || ratings != 1 {
+		t.Errorf("Import() = %d, %d, expected 2, 1", plays, ratings)
+	}
+
+	byName := make(map[string]string)
+	index.Walk(c, index.Path{"Root"}, func(t index.Track, p index.Path) error {
+		byName[t.GetString("Name")] = fmt.Sprintf("%v", p)
+		return nil
+	})
+	if got := h[byName["One"]]; len(got) != 2 || !got[0].Equal(last) {
+		t.Errorf("history for One = %v, expected 2 plays at %v", got, last)
+	}
+	if got := h[byName["Two"]]; len(got) != 1 || !got[0].Equal(added) {
+		t.Errorf("history for Two = %v, expected 1 play at %v", got, added)
+	}
+	if got := r[byName["One"]]; got != 4 {
+		t.Errorf("rating for One = %v, expected 4", got)
+	}
+	if got := r[byName["Three"]]; got != 5 {
+		t.Errorf("rating for Three = %v, expected existing rating 5", got)
+	}
+
+	// Importing again shouldn't add anything.
+	plays, ratings, err = Import(l, c, h, r)
+	if err != nil || plays != 0 || ratings != 0 {
+		t.Errorf("second Import() = %d, %d, %v, expected 0, 0, <nil>", plays, ratings, err)
+	}
+}
@@ -0,0 +1,95 @@
+// Copyright 2015, David Howden
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package migrate
+
+import (
+	"database/sql"
+	"strconv"
+)
+
+// Row is a row read from an SQL table, indexed by column name.  SQLite columns are loosely
+// typed, so values are converted by the accessor methods.
+type Row map[string]interface{}
+
+// ReadRows reads all the rows, and then closes them.
+func ReadRows(rows *sql.Rows) ([]Row, error) {
+	defer rows.Close()
+
+	cols, err := rows.Columns()
+	if err != nil {
+		return nil, err
+	}
+
+	var result []Row
+	for rows.Next() {
+		values := make([]interface{}, len(cols))
+		ptrs := make([]interface{}, len(cols))
+		for i := range values {
+			ptrs[i] = &values[i]
+		}
+		if err := rows.Scan(ptrs...); err != nil {
+			return nil, err
+		}
+
+		r := make(Row, len(cols))
+		for i, c := range cols {
+			r[c] = values[i]
+		}
+		result = append(result, r)
+	}
+	return result, rows.Err()
+}
+
+// Has returns true if the row has the column.
+func (r Row) Has(col string) bool {
+	_, ok := r[col]
+	return ok
+}
+
+// String returns the value of the column as a string.
+func (r Row) String(col string) string {
+	switch v := r[col].(type) {
+	case string:
+		return v
+	case []byte:
+		return string(v)
+	case int64:
+		return strconv.FormatInt(v, 10)
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	}
+	return ""
+}
+
+// Float returns the value of the column as a float64, or zero if it isn't a number.
+func (r Row) Float(col string) float64 {
+	switch v := r[col].(type) {
+	case int64:
+		return float64(v)
+	case float64:
+		return v
+	case string, []byte:
+		f, _ := strconv.ParseFloat(r.String(col), 64)
+		return f
+	}
+	return 0
+}
+
+// Int returns the value of the column as an int64, or zero if it isn't a number.
+func (r Row) Int(col string) int64 {
+	if v, ok := r[col].(int64); ok {
+		return v
+	}
+	return int64(r.Float(col))
+}
+
+// PositiveInt returns the value of the column as an int, or zero if it isn't positive
+// (used by many players to mean "unknown").
+func (r Row) PositiveInt(col string) int {
+	if v := r.Int(col); v > 0 {
+		return int(v)
+	}
+	return 0
+}
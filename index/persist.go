@@ -8,6 +8,8 @@ import (
 	"encoding/json"
 	"io"
 	"os"
+	"reflect"
+	"sort"
 )
 
 // PersistStore is a type which defines a simple persistence store.
@@ -52,3 +54,38 @@ func (p PersistStore) Persist(data interface{}) error {
 	_, err = f.Write(b)
 	return err
 }
+
+// Migrate maps the keys of the map pointed to by data (i.e. a *map[string]bool whose keys are
+// paths) using pm, and writes the result to the data store if any of them changed.  Values
+// whose keys are mapped to the same path are combined using merge.  Keys which could not be
+// mapped are kept as they are, so that they can be resolved by a later migration (i.e. once
+// their tracks are back in the library), and are returned.
+func (p PersistStore) Migrate(data interface{}, pm PathMap, merge func(x, y interface{}) interface{}) ([]Path, error) {
+	v := reflect.ValueOf(data).Elem()
+	m := reflect.MakeMap(v.Type())
+
+	var unresolved []Path
+	changed := false
+	for _, k := range v.MapKeys() {
+		x := v.MapIndex(k)
+		nk := k
+		np, ok := pm(NewPath(k.String()))
+		if !ok {
+			unresolved = append(unresolved, NewPath(k.String()))
+		} else if s := np.String(); s != k.String() {
+			nk = reflect.ValueOf(s).Convert(k.Type())
+			changed = true
+		}
+		if y := m.MapIndex(nk); y.IsValid() {
+			x = reflect.ValueOf(merge(y.Interface(), x.Interface()))
+		}
+		m.SetMapIndex(nk, x)
+	}
+	sort.Sort(PathSlice(unresolved))
+
+	if !changed {
+		return unresolved, nil
+	}
+	v.Set(m)
+	return unresolved, p.Persist(data)
+}
@@ -0,0 +1,149 @@
+// Copyright 2015, David Howden
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package playlist
+
+import (
+	"strings"
+	"time"
+
+	"github.com/amiforus/tchaik/index"
+)
+
+// PathRewrite describes how track locations in the library map to locations in playlist
+// files: TrimPrefix is removed from, and then AddPrefix is added to, every location (in the
+// same way as store.PathRewrite).
+type PathRewrite struct {
+	TrimPrefix, AddPrefix string
+}
+
+// Export returns the playlist file location for the library location loc.
+func (r PathRewrite) Export(loc string) string {
+	return r.AddPrefix + strings.TrimPrefix(loc, r.TrimPrefix)
+}
+
+// Import returns the library location for the playlist file location loc (the reverse of
+// Export).
+func (r PathRewrite) Import(loc string) string {
+	return r.TrimPrefix + strings.TrimPrefix(loc, r.AddPrefix)
+}
+
+// Entries returns the list of playlist file entries for all the tracks of the Playlist,
+// using the "Root" collection c as the data source.
+func Entries(p *Playlist, c index.Collection, r PathRewrite) ([]Entry, error) {
+	var entries []Entry
+	for _, item := range p.Items() {
+		err := walkItem(item, c, func(t index.Track, _ index.Path) {
+			entries = append(entries, Entry{
+				Location: r.Export(t.GetString("Location")),
+				Title:    t.GetString("Name"),
+				Artist:   t.GetString("Artist"),
+				Duration: time.Duration(t.GetInt("TotalTime")) * time.Millisecond,
+			})
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+	return entries, nil
+}
+
+// Import creates a Playlist from the entries by matching their locations with tracks in
+// the "Root" collection c.  Consecutive tracks from the same group are added as a single
+// item.  Returns the new Playlist and the entries which couldn't be found.
+func Import(entries []Entry, c index.Collection, r PathRewrite) (*Playlist, []Entry) {
+	locs := make([]string, len(entries))
+	for i, e := range entries {
+		locs[i] = fileLocation(r.Import(e.Location))
+	}
+
+	p, missing := importTracks(locs, c, func(t index.Track) string {
+		return fileLocation(t.GetString("Location"))
+	})
+	var result []Entry
+	for _, i := range missing {
+		result = append(result, entries[i])
+	}
+	return p, result
+}
+
+// ImportIDs creates a Playlist from the track IDs by matching them with tracks in the "Root"
+// collection c (see Import).  Returns the new Playlist and the IDs which couldn't be found.
+func ImportIDs(ids []string, c index.Collection) (*Playlist, []string) {
+	p, missing := importTracks(ids, c, func(t index.Track) string {
+		return t.GetString("ID")
+	})
+	var result []string
+	for _, i := range missing {
+		result = append(result, ids[i])
+	}
+	return p, result
+}
+
+// importTracks creates a Playlist from the list of keys, which are matched with the keys
+// of tracks (given by fn) in the "Root" collection c.  Returns the Playlist and the indices
+// of the keys which couldn't be found.
+func importTracks(keys []string, c index.Collection, fn func(index.Track) string) (*Playlist, []int) {
+	paths := make(map[string]index.Path)
+	index.Walk(c, index.Path{"Root"}, func(t index.Track, p index.Path) error {
+		k := fn(t)
+		if _, ok := paths[k]; !ok {
+			paths[k] = p
+		}
+		return nil
+	})
+
+	var missing []int
+	var tracks []index.Path
+	for i, k := range keys {
+		p, ok := paths[k]
+		if !ok {
+			missing = append(missing, i)
+			continue
+		}
+		tracks = append(tracks, p)
+	}
+
+	p := &Playlist{}
+	for len(tracks) > 0 {
+		n := 1
+		group := tracks[0][:len(tracks[0])-1]
+		for n < len(tracks) && group.Equal(tracks[n][:len(tracks[n])-1]) && trackIndex(tracks[n]) > trackIndex(tracks[n-1]) {
+			n++
+		}
+		p.items = append(p.items, groupItem(c, group, tracks[:n]))
+		tracks = tracks[n:]
+	}
+	return p, missing
+}
+
+// trackIndex returns the index of the track in its group from its path.
+func trackIndex(p index.Path) int {
+	n := 0
+	for _, r := range string(p[len(p)-1]) {
+		n = 10*n + int(r-'0')
+	}
+	return n
+}
+
+// groupItem creates an item for the group which only includes the given tracks.
+func groupItem(c index.Collection, group index.Path, tracks []index.Path) *Item {
+	item := newItem(group)
+	include := make(map[string]bool, len(tracks))
+	for _, p := range tracks {
+		include[string(p[len(p)-1])] = true
+	}
+
+	g, err := index.GroupFromPath(c, group[1:])
+	if err != nil {
+		return item
+	}
+	index.Walk(g, group, func(_ index.Track, p index.Path) error {
+		if !include[string(p[len(p)-1])] {
+			item.AddTransform(RemovePath(p))
+		}
+		return nil
+	})
+	return item
+}
@@ -0,0 +1,301 @@
+// Copyright 2015, David Howden
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package playlist
+
+import (
+	"bufio"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"net/url"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Format is a type which represents a playlist file format.
+type Format string
+
+// Supported playlist file formats.
+const (
+	FormatM3U  Format = "m3u"
+	FormatM3U8 Format = "m3u8"
+	FormatPLS  Format = "pls"
+	FormatXSPF Format = "xspf"
+)
+
+// FormatFromExt returns the Format which corresponds to the extension of the file name.
+func FormatFromExt(name string) (Format, error) {
+	f := Format(strings.ToLower(strings.TrimPrefix(filepath.Ext(name), ".")))
+	switch f {
+	case FormatM3U, FormatM3U8, FormatPLS, FormatXSPF:
+		return f, nil
+	}
+	return "", fmt.Errorf("unknown playlist format: %q (expected .m3u, .m3u8, .pls or .xspf)", filepath.Ext(name))
+}
+
+// ContentType returns the MIME type of the Format.
+func (f Format) ContentType() string {
+	switch f {
+	case FormatM3U, FormatM3U8:
+		return "audio/x-mpegurl"
+	case FormatPLS:
+		return "audio/x-scpls"
+	case FormatXSPF:
+		return "application/xspf+xml"
+	}
+	return "application/octet-stream"
+}
+
+// Entry is a track in a playlist file.
+type Entry struct {
+	Location string
+	Title    string
+	Artist   string
+	Duration time.Duration
+}
+
+// WriteEntries writes the entries to w as a playlist file in the format f.
+func WriteEntries(w io.Writer, f Format, title string, entries []Entry) error {
+	switch f {
+	case FormatM3U, FormatM3U8:
+		return writeM3U(w, entries)
+	case FormatPLS:
+		return writePLS(w, entries)
+	case FormatXSPF:
+		return writeXSPF(w, title, entries)
+	}
+	return fmt.Errorf("unknown playlist format: %q", f)
+}
+
+// ReadEntries reads the entries from the playlist file in the format f.
+func ReadEntries(r io.Reader, f Format) ([]Entry, error) {
+	switch f {
+	case FormatM3U, FormatM3U8:
+		return readM3U(r)
+	case FormatPLS:
+		return readPLS(r)
+	case FormatXSPF:
+		return readXSPF(r)
+	}
+	return nil, fmt.Errorf("unknown playlist format: %q", f)
+}
+
+// displayName returns the "<Artist> - <Title>" name of the entry used in M3U and PLS files.
+func (e Entry) displayName() string {
+	if e.Artist == "" {
+		return e.Title
+	}
+	return e.Artist + " - " + e.Title
+}
+
+// seconds returns the duration of the entry in whole seconds, or -1 if it is unknown.
+func (e Entry) seconds() int {
+	if e.Duration <= 0 {
+		return -1
+	}
+	return int(e.Duration / time.Second)
+}
+
+func writeM3U(w io.Writer, entries []Entry) error {
+	bw := bufio.NewWriter(w)
+	fmt.Fprintln(bw, "#EXTM3U")
+	for _, e := range entries {
+		fmt.Fprintf(bw, "#EXTINF:%d,%v\n", e.seconds(), e.displayName())
+		fmt.Fprintln(bw, e.Location)
+	}
+	return bw.Flush()
+}
+
+func readM3U(r io.Reader) ([]Entry, error) {
+	var entries []Entry
+	var info Entry
+	s := bufio.NewScanner(r)
+	for s.Scan() {
+		line := strings.TrimSpace(strings.TrimPrefix(s.Text(), "\ufeff"))
+		switch {
+		case line == "":
+			continue
+
+		case strings.HasPrefix(line, "#EXTINF:"):
+			info = Entry{}
+			fields := strings.SplitN(strings.TrimPrefix(line, "#EXTINF:"), ",", 2)
+			if n, err := strconv.Atoi(strings.TrimSpace(fields[0])); err == nil && n > 0 {
+				info.Duration = time.Duration(n) * time.Second
+			}
+			if len(fields) == 2 {
+				info.Artist, info.Title = splitDisplayName(fields[1])
+			}
+
+		case strings.HasPrefix(line, "#"):
+			continue
+
+		default:
+			info.Location = fileLocation(line)
+			entries = append(entries, info)
+			info = Entry{}
+		}
+	}
+	return entries, s.Err()
+}
+
+// splitDisplayName splits an "<Artist> - <Title>" name.
+func splitDisplayName(s string) (artist, title string) {
+	if i := strings.Index(s, " - "); i >= 0 {
+		return strings.TrimSpace(s[:i]), strings.TrimSpace(s[i+3:])
+	}
+	return "", strings.TrimSpace(s)
+}
+
+func writePLS(w io.Writer, entries []Entry) error {
+	bw := bufio.NewWriter(w)
+	fmt.Fprintln(bw, "[playlist]")
+	for i, e := range entries {
+		fmt.Fprintf(bw, "File%d=%v\n", i+1, e.Location)
+		fmt.Fprintf(bw, "Title%d=%v\n", i+1, e.displayName())
+		fmt.Fprintf(bw, "Length%d=%d\n", i+1, e.seconds())
+	}
+	fmt.Fprintf(bw, "NumberOfEntries=%d\n", len(entries))
+	fmt.Fprintln(bw, "Version=2")
+	return bw.Flush()
+}
+
+func readPLS(r io.Reader) ([]Entry, error) {
+	m := make(map[int]*Entry)
+	var nums []int
+	s := bufio.NewScanner(r)
+	for s.Scan() {
+		line := strings.TrimSpace(s.Text())
+		i := strings.Index(line, "=")
+		if i < 0 {
+			continue
+		}
+		k, v := strings.ToLower(line[:i]), line[i+1:]
+
+		var field string
+		for _, f := range []string{"file", "title", "length"} {
+			if strings.HasPrefix(k, f) {
+				field = f
+				break
+			}
+		}
+		if field == "" {
+			continue
+		}
+		n, err := strconv.Atoi(k[len(field):])
+		if err != nil {
+			continue
+		}
+
+		e, ok := m[n]
+		if !ok {
+			e = &Entry{}
+			m[n] = e
+			nums = append(nums, n)
+		}
+		switch field {
+		case "file":
+			e.Location = fileLocation(v)
+		case "title":
+			e.Artist, e.Title = splitDisplayName(v)
+		case "length":
+			if x, err := strconv.Atoi(v); err == nil && x > 0 {
+				e.Duration = time.Duration(x) * time.Second
+			}
+		}
+	}
+	if err := s.Err(); err != nil {
+		return nil, err
+	}
+
+	sort.Ints(nums)
+	var entries []Entry
+	for _, n := range nums {
+		if e := m[n]; e.Location != "" {
+			entries = append(entries, *e)
+		}
+	}
+	return entries, nil
+}
+
+type xspfPlaylist struct {
+	XMLName xml.Name    `xml:"http://xspf.org/ns/0/ playlist"`
+	Version int         `xml:"version,attr"`
+	Title   string      `xml:"title,omitempty"`
+	Tracks  []xspfTrack `xml:"trackList>track"`
+}
+
+type xspfTrack struct {
+	Location string `xml:"location"`
+	Title    string `xml:"title,omitempty"`
+	Creator  string `xml:"creator,omitempty"`
+	Duration int64  `xml:"duration,omitempty"` // milliseconds
+}
+
+func writeXSPF(w io.Writer, title string, entries []Entry) error {
+	x := xspfPlaylist{
+		Version: 1,
+		Title:   title,
+		Tracks:  make([]xspfTrack, len(entries)),
+	}
+	for i, e := range entries {
+		x.Tracks[i] = xspfTrack{
+			Location: fileURL(e.Location),
+			Title:    e.Title,
+			Creator:  e.Artist,
+			Duration: int64(e.Duration / time.Millisecond),
+		}
+	}
+
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return err
+	}
+	enc := xml.NewEncoder(w)
+	enc.Indent("", "  ")
+	if err := enc.Encode(x); err != nil {
+		return err
+	}
+	_, err := io.WriteString(w, "\n")
+	return err
+}
+
+func readXSPF(r io.Reader) ([]Entry, error) {
+	var x xspfPlaylist
+	if err := xml.NewDecoder(r).Decode(&x); err != nil {
+		return nil, fmt.Errorf("error parsing XSPF playlist: %v", err)
+	}
+
+	entries := make([]Entry, 0, len(x.Tracks))
+	for _, t := range x.Tracks {
+		entries = append(entries, Entry{
+			Location: fileLocation(strings.TrimSpace(t.Location)),
+			Title:    t.Title,
+			Artist:   t.Creator,
+			Duration: time.Duration(t.Duration) * time.Millisecond,
+		})
+	}
+	return entries, nil
+}
+
+// fileURL returns the file:// URL for the location, unless it is already a URL.
+func fileURL(loc string) string {
+	if strings.Contains(loc, "://") {
+		return loc
+	}
+	u := url.URL{Scheme: "file", Path: filepath.ToSlash(loc)}
+	return u.String()
+}
+
+// fileLocation returns the path of a file:// URL, and any other location unchanged.
+func fileLocation(loc string) string {
+	if strings.HasPrefix(loc, "file://") {
+		if u, err := url.Parse(loc); err == nil {
+			return filepath.FromSlash(u.Path)
+		}
+	}
+	return loc
+}
@@ -0,0 +1,143 @@
+// Copyright 2015, David Howden
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package playlist
+
+import (
+	"bytes"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/amiforus/tchaik/index"
+	"github.com/amiforus/tchaik/index/attr"
+)
+
+func TestFormatFromExt(t *testing.T) {
+	tests := []struct {
+		in  string
+		out Format
+		ok  bool
+	}{
+		{"a.m3u", FormatM3U, true},
+		{"a/b.M3U8", FormatM3U8, true},
+		{"a.pls", FormatPLS, true},
+		{"Road Trip.xspf", FormatXSPF, true},
+		{"a.txt", "", false},
+		{"a", "", false},
+	}
+
+	for ii, tt := range tests {
+		got, err := FormatFromExt(tt.in)
+		if got != tt.out || (err == nil) != tt.ok {
+			t.Errorf("[%d] FormatFromExt(%q) = (%q, %v), expected (%q, ok: %v)", ii, tt.in, got, err, tt.out, tt.ok)
+		}
+	}
+}
+
+func TestEntriesRoundTrip(t *testing.T) {
+	entries := []Entry{
+		{Location: "/music/Miles Davis/So What.m4a", Title: "So What", Artist: "Miles Davis", Duration: 545 * time.Second},
+		{Location: "/music/Unknown/track & more.mp3", Title: "track & more"},
+	}
+
+	for _, f := range []Format{FormatM3U, FormatM3U8, FormatPLS, FormatXSPF} {
+		buf := &bytes.Buffer{}
+		if err := WriteEntries(buf, f, "Test", entries); err != nil {
+			t.Errorf("[%v] unexpected error from WriteEntries(): %v", f, err)
+			continue
+		}
+		got, err := ReadEntries(buf, f)
+		if err != nil {
+			t.Errorf("[%v] unexpected error from ReadEntries(): %v", f, err)
+			continue
+		}
+		if !reflect.DeepEqual(got, entries) {
+			t.Errorf("[%v] ReadEntries(WriteEntries()) = %#v, expected %#v", f, got, entries)
+		}
+	}
+}
+
+func TestReadEntries(t *testing.T) {
+	tests := []struct {
+		f   Format
+		in  string
+		out []Entry
+	}{
+		{
+			FormatM3U,
+			"/music/a.mp3\n\n# comment\nfile:///music/b%20c.mp3\n",
+			[]Entry{{Location: "/music/a.mp3"}, {Location: "/music/b c.mp3"}},
+		},
+		{
+			FormatPLS,
+			"[playlist]\nFile2=/music/b.mp3\nfile1=/music/a.mp3\nTitle1=A\nLength1=-1\nNumberOfEntries=2\n",
+			[]Entry{{Location: "/music/a.mp3", Title: "A"}, {Location: "/music/b.mp3"}},
+		},
+	}
+
+	for ii, tt := range tests {
+		got, err := ReadEntries(strings.NewReader(tt.in), tt.f)
+		if err != nil {
+			t.Errorf("[%d] unexpected error from ReadEntries(): %v", ii, err)
+		}
+		if !reflect.DeepEqual(got, tt.out) {
+			t.Errorf("[%d] ReadEntries(%q) = %#v, expected %#v", ii, tt.in, got, tt.out)
+		}
+	}
+}
+
+func TestPathRewrite(t *testing.T) {
+	r := PathRewrite{TrimPrefix: "/Users/x/Music/", AddPrefix: "/sdcard/Music/"}
+	loc := "/Users/x/Music/A/1.mp3"
+	exp := "/sdcard/Music/A/1.mp3"
+	if got := r.Export(loc); got != exp {
+		t.Errorf("Export(%q) = %q, expected %q", loc, got, exp)
+	}
+	if got := r.Import(exp); got != loc {
+		t.Errorf("Import(%q) = %q, expected %q", exp, got, loc)
+	}
+}
+
+func TestImportExport(t *testing.T) {
+	tracks := []testTrack{
+		{Name: "1", Album: "A", Artist: "X", Location: "/music/A/1.mp3", TotalTime: 60000},
+		{Name: "2", Album: "A", Artist: "X", Location: "/music/A/2.mp3", TotalTime: 120000},
+		{Name: "3", Album: "A", Artist: "X", Location: "/music/A/3.mp3"},
+		{Name: "1", Album: "B", Location: "/music/B/1.mp3"},
+	}
+	c := index.Collect(testTracker(tracks), index.By(attr.String("Album")))
+	index.SortKeysByGroupName(c)
+	r := PathRewrite{TrimPrefix: "/music/", AddPrefix: "/sdcard/"}
+
+	entries := []Entry{
+		{Location: "/sdcard/A/1.mp3"},
+		{Location: "/sdcard/A/3.mp3"},
+		{Location: "/sdcard/B/1.mp3"},
+		{Location: "/sdcard/C/1.mp3"},
+		{Location: "/sdcard/A/2.mp3"},
+	}
+	p, missing := Import(entries, c, r)
+	if !reflect.DeepEqual(missing, entries[3:4]) {
+		t.Errorf("Import() missing = %#v, expected %#v", missing, entries[3:4])
+	}
+	if n := len(p.Items()); n != 3 {
+		t.Errorf("len(Items()) = %d, expected 3", n)
+	}
+
+	got, err := Entries(p, c, r)
+	if err != nil {
+		t.Fatalf("unexpected error from Entries(): %v", err)
+	}
+	expected := []Entry{
+		{Location: "/sdcard/A/1.mp3", Title: "1", Artist: "X", Duration: time.Minute},
+		{Location: "/sdcard/A/3.mp3", Title: "3", Artist: "X"},
+		{Location: "/sdcard/B/1.mp3", Title: "1"},
+		{Location: "/sdcard/A/2.mp3", Title: "2", Artist: "X", Duration: 2 * time.Minute},
+	}
+	if !reflect.DeepEqual(got, expected) {
+		t.Errorf("Entries() = %#v, expected %#v", got, expected)
+	}
+}
@@ -7,7 +7,9 @@ package playlist
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
+	"reflect"
 
 	"github.com/amiforus/tchaik/index"
 )
@@ -52,6 +54,14 @@ func newItem(path index.Path) *Item {
 	}
 }
 
+// copy returns a copy of the Item.
+func (i *Item) copy() *Item {
+	return &Item{
+		path:       i.path,
+		transforms: append([]Transformer(nil), i.transforms...),
+	}
+}
+
 // AddTransform adds the Transformer to the Item.
 func (i *Item) AddTransform(t Transformer) {
 	i.transforms = append(i.transforms, t)
@@ -96,17 +106,34 @@ func (i *Item) UnmarshalJSON(b []byte) error {
 	return nil
 }
 
+// maxUndo is the maximum number of changes which can be undone for each playlist.
+const maxUndo = 20
+
 // Playlist is a basic implementation of a playlist
 type Playlist struct {
 	items []*Item
+	smart *Smart
+
+	// undo is the list of previous item lists (most recent last), which is not persisted.
+	undo [][]*Item
+}
+
+// NewSmart creates a smart playlist from the definition s.  The items of the playlist are
+// empty until Update is called.
+func NewSmart(s *Smart) *Playlist {
+	return &Playlist{
+		smart: s,
+	}
 }
 
 // MarshalJSON implements json.Marshaler.
 func (p *Playlist) MarshalJSON() ([]byte, error) {
 	exp := struct {
 		Items []*Item `json:"items"`
+		Smart *Smart  `json:"smart,omitempty"`
 	}{
 		p.items,
+		p.smart,
 	}
 	return json.Marshal(exp)
 }
@@ -115,23 +142,127 @@ func (p *Playlist) MarshalJSON() ([]byte, error) {
 func (p *Playlist) UnmarshalJSON(b []byte) error {
 	exp := struct {
 		Items []*Item `json:"items"`
+		Smart *Smart  `json:"smart"`
 	}{}
 	err := json.Unmarshal(b, &exp)
 	if err != nil {
 		return err
 	}
 	p.items = exp.Items
+	p.smart = exp.Smart
+	return nil
+}
+
+// Smart returns the smart playlist definition, or nil if this is not a smart playlist.
+func (p *Playlist) Smart() *Smart {
+	return p.smart
+}
+
+// Update re-evaluates a smart playlist using the tracks ts and metadata sources src, and
+// returns true if its items have changed.  Does nothing for playlists which aren't
+// smart.  The Playlist is changed in place, so use a Copy of playlists which are shared
+// (i.e. returned by a Store).
+func (p *Playlist) Update(ts *Tracks, src Sources) bool {
+	if p.smart == nil {
+		return false
+	}
+	items := p.smart.Items(ts, src)
+	changed := !itemsEqual(p.items, items)
+	p.items = items
+	return changed
+}
+
+// itemsEqual returns true if the lists of items have the same paths and transforms.
+func itemsEqual(x, y []*Item) bool {
+	if len(x) != len(y) {
+		return false
+	}
+	for i := range x {
+		if !x[i].path.Equal(y[i].path) || len(x[i].transforms) != len(y[i].transforms) {
+			return false
+		}
+		for j := range x[i].transforms {
+			if !reflect.DeepEqual(x[i].transforms[j], y[i].transforms[j]) {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// Copy returns a copy of the Playlist (without its undo history).
+func (p *Playlist) Copy() *Playlist {
+	return &Playlist{
+		items: copyItems(p.items),
+		smart: p.smart,
+	}
+}
+
+// clone returns a copy of the Playlist which keeps its undo history.
+func (p *Playlist) clone() *Playlist {
+	q := p.Copy()
+	q.undo = append([][]*Item(nil), p.undo...)
+	return q
+}
+
+func copyItems(items []*Item) []*Item {
+	result := make([]*Item, len(items))
+	for i, item := range items {
+		result[i] = item.copy()
+	}
+	return result
+}
+
+// checkpoint saves the current items so that the next change can be undone.
+func (p *Playlist) checkpoint() {
+	p.undo = append(p.undo, copyItems(p.items))
+	if len(p.undo) > maxUndo {
+		p.undo = p.undo[len(p.undo)-maxUndo:]
+	}
+}
+
+// Undo reverts the last change made to the items of the Playlist.  Changes can be undone
+// up to a maximum of 20, but are forgotten when the Playlist is reloaded.
+func (p *Playlist) Undo() error {
+	if len(p.undo) == 0 {
+		return errors.New("nothing to undo")
+	}
+	n := len(p.undo) - 1
+	p.items = p.undo[n]
+	p.undo = p.undo[:n]
 	return nil
 }
 
 // Add adds a new with the path to the Playlist.
-func (p *Playlist) Add(path index.Path) {
-	p.items = append(p.items, newItem(path))
+func (p *Playlist) Add(path index.Path) error {
+	return p.Insert(len(p.items), path)
 }
 
+// Insert inserts a new item with the path into the Playlist at index `n`.
+func (p *Playlist) Insert(n int, path index.Path) error {
+	if p.smart != nil {
+		return errSmart
+	}
+	if n < 0 || n > len(p.items) {
+		return fmt.Errorf("invalid insert index (items: %d): %d", len(p.items), n)
+	}
+
+	p.checkpoint()
+	p.items = append(p.items, nil)
+	copy(p.items[n+1:], p.items[n:])
+	p.items[n] = newItem(path)
+	return nil
+}
+
+// errSmart is returned when attempting to change the items of a smart playlist.
+var errSmart = errors.New("cannot change the items of a smart playlist")
+
 // Remove removes the item with index `n` and path `path` from the Playlist.
 func (p *Playlist) Remove(n int, path index.Path) error {
-	if n >= len(p.items) {
+	if p.smart != nil {
+		return errSmart
+	}
+	if n < 0 || n >= len(p.items) {
 		return fmt.Errorf("invalid item index (items: %d): %d", len(p.items), n)
 	}
 
@@ -140,6 +271,7 @@ func (p *Playlist) Remove(n int, path index.Path) error {
 		return fmt.Errorf("path '%v' is not contained in item '%v'", path, item.path)
 	}
 
+	p.checkpoint()
 	if path.Equal(item.path) {
 		p.items = append(p.items[:n], p.items[n+1:]...)
 		return nil
@@ -148,6 +280,45 @@ func (p *Playlist) Remove(n int, path index.Path) error {
 	return nil
 }
 
+// Move moves the item with index `from` so that it has index `to`.
+func (p *Playlist) Move(from, to int) error {
+	if p.smart != nil {
+		return errSmart
+	}
+	if from < 0 || from >= len(p.items) {
+		return fmt.Errorf("invalid item index (items: %d): %d", len(p.items), from)
+	}
+	if to < 0 || to >= len(p.items) {
+		return fmt.Errorf("invalid move index (items: %d): %d", len(p.items), to)
+	}
+	if from == to {
+		return nil
+	}
+
+	p.checkpoint()
+	item := p.items[from]
+	if from < to {
+		copy(p.items[from:], p.items[from+1:to+1])
+	} else {
+		copy(p.items[to+1:], p.items[to:from])
+	}
+	p.items[to] = item
+	return nil
+}
+
+// Clear removes all the items from the Playlist.
+func (p *Playlist) Clear() error {
+	if p.smart != nil {
+		return errSmart
+	}
+	if len(p.items) == 0 {
+		return nil
+	}
+	p.checkpoint()
+	p.items = nil
+	return nil
+}
+
 // Items returns a slice of *Item instances which represent each item in the playlist.
 func (p *Playlist) Items() []*Item {
 	items := make([]*Item, len(p.items))
@@ -157,31 +328,83 @@ func (p *Playlist) Items() []*Item {
 	return items
 }
 
+// migrate maps the paths of the items in the Playlist using pm, and returns the paths which
+// could not be mapped (which are kept, so that they can be resolved by a later migration), and
+// true if the Playlist was changed.  Smart playlists are not changed: their items are
+// re-evaluated by Update.
+func (p *Playlist) migrate(pm index.PathMap) ([]index.Path, bool) {
+	if p.smart != nil {
+		return nil, false
+	}
+
+	var unresolved []index.Path
+	changed := false
+	mapPath := func(x index.Path) index.Path {
+		nx, ok := pm(x)
+		if !ok {
+			unresolved = append(unresolved, x)
+			return x
+		}
+		if !nx.Equal(x) {
+			changed = true
+		}
+		return nx
+	}
+
+	items := make([]*Item, len(p.items))
+	for i, item := range p.items {
+		transforms := make([]Transformer, len(item.transforms))
+		for j, t := range item.transforms {
+			if rp, ok := t.(RemovePath); ok {
+				t = RemovePath(mapPath(index.Path(rp)))
+			}
+			transforms[j] = t
+		}
+		items[i] = &Item{
+			path:       mapPath(item.path),
+			transforms: transforms,
+		}
+	}
+	p.items = items
+	return unresolved, changed
+}
+
 // Paths returns the list of paths for the tracks within the Item, using Collection
 // as the data source.
 func Paths(item *Item, c index.Collection) ([]index.Path, error) {
-	g, err := index.GroupFromPath(c, item.path[1:]) // Trim "Root" prefix
+	var paths []index.Path
+	err := walkItem(item, c, func(_ index.Track, p index.Path) {
+		paths = append(paths, p)
+	})
 	if err != nil {
 		return nil, err
 	}
+	return paths, nil
+}
+
+// walkItem calls fn for each of the tracks (and their paths) within the Item, using
+// Collection as the data source.
+func walkItem(item *Item, c index.Collection, fn func(index.Track, index.Path)) error {
+	g, err := index.GroupFromPath(c, item.path[1:]) // Trim "Root" prefix
+	if err != nil {
+		return err
+	}
 
-	removePaths := make([]index.Path, len(item.transforms))
+	removePaths := make([]index.Path, 0, len(item.transforms))
 	for _, transform := range item.transforms {
 		if path, ok := transform.(RemovePath); ok {
 			removePaths = append(removePaths, index.Path(path))
 		}
 	}
 
-	var paths []index.Path
 	walkFn := func(t index.Track, p index.Path) error {
 		for _, rp := range removePaths {
 			if rp.Contains(p) {
 				return nil
 			}
 		}
-		paths = append(paths, p)
+		fn(t, p)
 		return nil
 	}
-	index.Walk(g, item.path, walkFn)
-	return paths, nil
+	return index.Walk(g, item.path, walkFn)
 }
@@ -5,6 +5,12 @@
 package playlist
 
 import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
 	"testing"
 
 	"github.com/amiforus/tchaik/index"
@@ -138,3 +144,300 @@ func TestPlaylistItemRemove(t *testing.T) {
 		t.Errorf("expected error for removing invalid item (items: %v)", p.Items())
 	}
 }
+
+func itemPaths(p *Playlist) []string {
+	var out []string
+	for _, item := range p.Items() {
+		out = append(out, fmt.Sprintf("%v", item.path))
+	}
+	return out
+}
+
+func TestPlaylistInsertMove(t *testing.T) {
+	p := &Playlist{}
+	p.Add(index.NewPath("Root:a"))
+	p.Add(index.NewPath("Root:b"))
+
+	if err := p.Insert(1, index.NewPath("Root:c")); err != nil {
+		t.Errorf("unexpected error inserting item: %v", err)
+	}
+	if err := p.Insert(0, index.NewPath("Root:d")); err != nil {
+		t.Errorf("unexpected error inserting item: %v", err)
+	}
+	if err := p.Insert(5, index.NewPath("Root:e")); err == nil {
+		t.Errorf("expected error inserting item at invalid index")
+	}
+
+	tests := []struct {
+		from, to int
+		out      []string
+		ok       bool
+	}{
+		{0, 0, []string{"Root:d", "Root:a", "Root:c", "Root:b"}, true},
+		{0, 3, []string{"Root:a", "Root:c", "Root:b", "Root:d"}, true},
+		{2, 0, []string{"Root:b", "Root:a", "Root:c", "Root:d"}, true},
+		{1, 2, []string{"Root:b", "Root:c", "Root:a", "Root:d"}, true},
+		{4, 0, []string{"Root:b", "Root:c", "Root:a", "Root:d"}, false},
+		{0, -1, []string{"Root:b", "Root:c", "Root:a", "Root:d"}, false},
+	}
+
+	for ii, tt := range tests {
+		err := p.Move(tt.from, tt.to)
+		if (err == nil) != tt.ok {
+			t.Errorf("[%d] Move(%d, %d) = %v, expected ok: %v", ii, tt.from, tt.to, err, tt.ok)
+		}
+		if got := itemPaths(p); !reflect.DeepEqual(got, tt.out) {
+			t.Errorf("[%d] Move(%d, %d) items = %v, expected %v", ii, tt.from, tt.to, got, tt.out)
+		}
+	}
+}
+
+func TestPlaylistUndo(t *testing.T) {
+	p := &Playlist{}
+	if err := p.Undo(); err == nil {
+		t.Errorf("expected error undoing with no changes")
+	}
+
+	p.Add(index.NewPath("Root:a"))
+	p.Add(index.NewPath("Root:b"))
+	p.Remove(0, index.NewPath("Root:a:1"))
+	p.Move(0, 1)
+	p.Clear()
+
+	expected := [][]string{
+		{"Root:b", "Root:a"},
+		{"Root:a", "Root:b"},
+		{"Root:a", "Root:b"},
+		{"Root:a"},
+		nil,
+	}
+	for ii, e := range expected {
+		if err := p.Undo(); err != nil {
+			t.Errorf("[%d] unexpected error from Undo(): %v", ii, err)
+		}
+		if got := itemPaths(p); !reflect.DeepEqual(got, e) {
+			t.Errorf("[%d] Undo() items = %v, expected %v", ii, got, e)
+		}
+		if ii == 2 {
+			if n := len(p.Items()[0].transforms); n != 0 {
+				t.Errorf("len(transforms) = %d after undoing remove, expected 0", n)
+			}
+		}
+	}
+
+	for i := 0; i < maxUndo+5; i++ {
+		p.Add(index.NewPath("Root:a"))
+	}
+	n := 0
+	for p.Undo() == nil {
+		n++
+	}
+	if n != maxUndo {
+		t.Errorf("undid %d changes, expected %d", n, maxUndo)
+	}
+}
+
+func TestPlaylistMigrate(t *testing.T) {
+	p := &Playlist{}
+	p.Add(index.NewPath("Root:a"))
+	p.Add(index.NewPath("Root:b"))
+	p.Add(index.NewPath("Root:c"))
+	p.items[0].AddTransform(RemovePath(index.NewPath("Root:a:1")))
+
+	pm := func(x index.Path) (index.Path, bool) {
+		if x[1] == "c" {
+			return nil, false
+		}
+		return append(index.Path{"Root", "x"}, x[1:]...), true
+	}
+
+	unresolved, changed := p.migrate(pm)
+	if !changed {
+		t.Errorf("migrate() changed = false, expected true")
+	}
+	if expected := []index.Path{index.NewPath("Root:c")}; !reflect.DeepEqual(unresolved, expected) {
+		t.Errorf("migrate() unresolved = %v, expected %v", unresolved, expected)
+	}
+
+	expected := []index.Path{index.NewPath("Root:x:a"), index.NewPath("Root:x:b"), index.NewPath("Root:c")}
+	if len(p.Items()) != len(expected) {
+		t.Fatalf("len(Items()) = %d, expected %d", len(p.Items()), len(expected))
+	}
+	for i, item := range p.Items() {
+		if !item.path.Equal(expected[i]) {
+			t.Errorf("[%d] path = %v, expected %v", i, item.path, expected[i])
+		}
+	}
+	if rp := index.Path(p.items[0].transforms[0].(RemovePath)); !rp.Equal(index.NewPath("Root:x:a:1")) {
+		t.Errorf("RemovePath = %v, expected %v", rp, index.NewPath("Root:x:a:1"))
+	}
+
+	s := NewSmart(&Smart{Match: MatchAll})
+	if _, changed := s.migrate(pm); changed {
+		t.Errorf("migrate() changed = true for smart playlist, expected false")
+	}
+}
+
+func TestStoreMigrate(t *testing.T) {
+	dir, err := ioutil.TempDir("", "playlist")
+	if err != nil {
+		t.Fatalf("unexpected error creating temp dir: %v", err)
+	}
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "playlists.json")
+	s, err := NewStore(path)
+	if err != nil {
+		t.Fatalf("unexpected error from NewStore: %v", err)
+	}
+	p := &Playlist{}
+	p.Add(index.NewPath("Root:a"))
+	p.Add(index.NewPath("Root:c"))
+	if err := s.Set("p", p); err != nil {
+		t.Fatalf("unexpected error from Set: %v", err)
+	}
+
+	pm := func(x index.Path) (index.Path, bool) {
+		return x, x[1] != "c"
+	}
+	unresolved, err := s.Migrate(pm)
+	if err != nil {
+		t.Fatalf("unexpected error from Migrate: %v", err)
+	}
+	if expected := []index.Path{index.NewPath("Root:c")}; !reflect.DeepEqual(unresolved, expected) {
+		t.Errorf("Migrate() unresolved = %v, expected %v", unresolved, expected)
+	}
+
+	// Unresolved items are kept, so they are still in the playlist when it is reloaded.
+	s, err = NewStore(path)
+	if err != nil {
+		t.Fatalf("unexpected error from NewStore: %v", err)
+	}
+	items := s.Get("p").Items()
+	if len(items) != 2 {
+		t.Fatalf("len(Items()) = %d, expected 2", len(items))
+	}
+	if !items[1].path.Equal(index.NewPath("Root:c")) {
+		t.Errorf("path = %v, expected %v", items[1].path, index.NewPath("Root:c"))
+	}
+}
+
+type testStore map[string]*Playlist
+
+func (s testStore) Names() []string {
+	var n []string
+	for k := range s {
+		n = append(n, k)
+	}
+	sort.Strings(n)
+	return n
+}
+
+func (s testStore) Get(name string) *Playlist          { return s[name] }
+func (s testStore) Set(name string, p *Playlist) error { s[name] = p; return nil }
+func (s testStore) Delete(name string) error           { delete(s, name); return nil }
+
+func TestRepActionApply(t *testing.T) {
+	s := testStore{}
+	actions := []RepAction{
+		{Name: "x", Action: ActionCreate},
+		{Name: "x", Action: "ADD_ITEM", Path: index.NewPath("Root:a")},
+		{Name: "x", Action: "INSERT_ITEM", Path: index.NewPath("Root:b"), Index: 0},
+		{Name: "x", Action: "MOVE_ITEM", Index: 0, To: 1},
+		{Name: "x", Action: "DUPLICATE", NewName: "y"},
+		{Name: "x", Action: "RENAME", NewName: "z"},
+		{Name: "y", Action: "CLEAR"},
+	}
+	for ii, a := range actions {
+		if err := a.Apply(s); err != nil {
+			t.Errorf("[%d] Apply() = %v, expected nil", ii, err)
+		}
+	}
+
+	if got, expected := s.Names(), []string{"y", "z"}; !reflect.DeepEqual(got, expected) {
+		t.Errorf("Names() = %v, expected %v", got, expected)
+	}
+	if got, expected := itemPaths(s["z"]), []string{"Root:a", "Root:b"}; !reflect.DeepEqual(got, expected) {
+		t.Errorf("items = %v, expected %v", got, expected)
+	}
+	if got := itemPaths(s["y"]); len(got) != 0 {
+		t.Errorf("items = %v, expected none", got)
+	}
+
+	invalid := []RepAction{
+		{Name: "z", Action: "RENAME", NewName: "y"},
+		{Name: "z", Action: "DUPLICATE"},
+		{Name: "z", Action: "ADD_ITEM"},
+		{Name: "x", Action: "CLEAR"},
+		{Name: "z", Action: "UNKNOWN"},
+	}
+	for ii, a := range invalid {
+		if err := a.Apply(s); err == nil {
+			t.Errorf("[%d] Apply() = nil, expected error", ii)
+		}
+	}
+
+	if err := (RepAction{Name: "z", Action: "DELETE"}).Apply(s); err != nil {
+		t.Errorf("Apply(DELETE) = %v, expected nil", err)
+	}
+	if s.Get("z") != nil {
+		t.Errorf("expected playlist to be deleted")
+	}
+}
+
+func (s testStore) Migrate(index.PathMap) ([]index.Path, error) { return nil, nil }
+
+// setCountStore is a testStore which counts the calls to Set.
+type setCountStore struct {
+	testStore
+	n int
+}
+
+func (s *setCountStore) Set(name string, p *Playlist) error {
+	s.n++
+	return s.testStore.Set(name, p)
+}
+
+func TestRepActionApplyCopy(t *testing.T) {
+	s := &setCountStore{testStore: testStore{}}
+	for _, a := range []RepAction{
+		{Name: "x", Action: ActionCreate},
+		{Name: "x", Action: "ADD_ITEM", Path: index.NewPath("Root:a")},
+		{Name: "x", Action: "ADD_ITEM", Path: index.NewPath("Root:b")},
+	} {
+		if err := a.Apply(s); err != nil {
+			t.Fatalf("Apply(%v) = %v, expected nil", a.Action, err)
+		}
+	}
+
+	p := s.Get("x")
+	tests := []struct {
+		a        RepAction
+		set      bool
+		expected []string
+	}{
+		{RepAction{Name: "x", Action: "MOVE_ITEM", Index: 0, To: 1}, true, []string{"Root:b", "Root:a"}},
+		{RepAction{Name: "x", Action: "MOVE_ITEM", Index: 1, To: 1}, false, []string{"Root:b", "Root:a"}},
+		{RepAction{Name: "x", Action: "UNDO"}, true, []string{"Root:a", "Root:b"}},
+		{RepAction{Name: "x", Action: "CLEAR"}, true, nil},
+		{RepAction{Name: "x", Action: "CLEAR"}, false, nil},
+	}
+	for ii, tt := range tests {
+		n := s.n
+		if err := tt.a.Apply(s); err != nil {
+			t.Errorf("[%d] Apply() = %v, expected nil", ii, err)
+			continue
+		}
+		if set := s.n > n; set != tt.set {
+			t.Errorf("[%d] Set called = %v, expected %v", ii, set, tt.set)
+		}
+		if got := itemPaths(s.Get("x")); !reflect.DeepEqual(got, tt.expected) {
+			t.Errorf("[%d] items = %v, expected %v", ii, got, tt.expected)
+		}
+	}
+
+	// The playlist which was in the store to begin with is unchanged.
+	if got, expected := itemPaths(p), []string{"Root:a", "Root:b"}; !reflect.DeepEqual(got, expected) {
+		t.Errorf("items = %v, expected %v", got, expected)
+	}
+}
@@ -14,27 +14,49 @@ import (
 type Action string
 
 const (
-	ActionCreate Action = "create"
-	ActionDelete        = "delete"
+	ActionCreate    Action = "create"
+	ActionDelete           = "delete"
+	ActionRename           = "rename"
+	ActionDuplicate        = "duplicate"
+	ActionClear            = "clear"
+	ActionUndo             = "undo"
 
 	ActionAddItem    = "addItem"
+	ActionInsertItem = "insertItem"
 	ActionRemoveItem = "deleteItem"
+	ActionMoveItem   = "moveItem"
 )
 
 var actionToAction = map[string]Action{
-	"ADD_ITEM": ActionAddItem,
-	"REMOVE":   ActionRemoveItem,
+	"DELETE":      ActionDelete,
+	"RENAME":      ActionRename,
+	"DUPLICATE":   ActionDuplicate,
+	"CLEAR":       ActionClear,
+	"UNDO":        ActionUndo,
+	"ADD_ITEM":    ActionAddItem,
+	"INSERT_ITEM": ActionInsertItem,
+	"REMOVE":      ActionRemoveItem,
+	"MOVE_ITEM":   ActionMoveItem,
 }
 
 type RepAction struct {
-	Name   string     `json:"name"`
-	Action Action     `json:"action"`
-	Path   index.Path `json:"path"`
-	Index  int        `json:"index"`
+	Name    string     `json:"name"`
+	Action  Action     `json:"action"`
+	Path    index.Path `json:"path"`
+	Index   int        `json:"index"`
+	To      int        `json:"to"`      // destination index for MOVE_ITEM
+	NewName string     `json:"newName"` // name for RENAME and DUPLICATE
+	Smart   *Smart     `json:"smart"`
 }
 
 func (a RepAction) Apply(s Store) error {
 	if a.Action == ActionCreate {
+		if a.Smart != nil {
+			if err := a.Smart.Validate(); err != nil {
+				return err
+			}
+			return s.Set(a.Name, NewSmart(a.Smart))
+		}
 		s.Set(a.Name, &Playlist{})
 		return nil
 	}
@@ -51,13 +73,53 @@ func (a RepAction) Apply(s Store) error {
 
 	switch action {
 	case ActionDelete:
-		s.Delete(a.Name)
+		return s.Delete(a.Name)
+
+	case ActionRename, ActionDuplicate:
+		if a.NewName == "" {
+			return fmt.Errorf("new name for playlist must not be empty")
+		}
+		if s.Get(a.NewName) != nil {
+			return fmt.Errorf("playlist already exists: '%v'", a.NewName)
+		}
+		if action == ActionDuplicate {
+			return s.Set(a.NewName, p.Copy())
+		}
+		if err := s.Set(a.NewName, p); err != nil {
+			return err
+		}
+		return s.Delete(a.Name)
+
+	case ActionAddItem, ActionInsertItem, ActionRemoveItem:
+		if len(a.Path) == 0 {
+			return fmt.Errorf("path must not be empty")
+		}
+	}
+
+	// p is shared (i.e. with cursors and other connections), so the copy is changed and
+	// replaces it.
+	q := p.clone()
+	var err error
+	switch action {
+	case ActionClear:
+		err = q.Clear()
+	case ActionUndo:
+		err = q.Undo()
 	case ActionAddItem:
-		p.Add(a.Path)
+		err = q.Add(a.Path)
+	case ActionInsertItem:
+		err = q.Insert(a.Index, a.Path)
 	case ActionRemoveItem:
-		p.Remove(a.Index, a.Path)
+		err = q.Remove(a.Index, a.Path)
+	case ActionMoveItem:
+		err = q.Move(a.Index, a.To)
+	}
+	if err != nil {
+		return err
 	}
 
-	s.Set(a.Name, p)
-	return nil
+	if action != ActionUndo && itemsEqual(p.items, q.items) {
+		return nil
+	}
+	return s.Set(a.Name, q)
 }
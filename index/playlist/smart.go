@@ -0,0 +1,355 @@
+// Copyright 2015, David Howden
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package playlist
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/amiforus/tchaik/index"
+	"github.com/amiforus/tchaik/index/favourite"
+	"github.com/amiforus/tchaik/index/history"
+	"github.com/amiforus/tchaik/index/rating"
+)
+
+// Operators which can be used in a Rule.
+const (
+	OpEqual        = "="
+	OpNotEqual     = "!="
+	OpLess         = "<"
+	OpLessEqual    = "<="
+	OpGreater      = ">"
+	OpGreaterEqual = ">="
+	OpContains     = "contains"
+)
+
+// Match values for Smart.
+const (
+	MatchAll = "all"
+	MatchAny = "any"
+)
+
+// Track attributes which can be used in rules.
+var (
+	stringFields = map[string]bool{
+		"Name":        true,
+		"Album":       true,
+		"AlbumArtist": true,
+		"Artist":      true,
+		"Composer":    true,
+		"Genre":       true,
+		"Kind":        true,
+		"Location":    true,
+	}
+
+	intFields = map[string]bool{
+		"Year":        true,
+		"TotalTime":   true,
+		"BitRate":     true,
+		"DiscNumber":  true,
+		"DiscCount":   true,
+		"TrackNumber": true,
+		"TrackCount":  true,
+	}
+)
+
+// Fields computed from the metadata Sources (and the track DateAdded attribute).
+const (
+	FieldRating          = "Rating"
+	FieldFavourite       = "Favourite"
+	FieldPlayCount       = "PlayCount"
+	FieldDaysSincePlayed = "DaysSincePlayed" // tracks which have never been played are treated as played at the epoch
+	FieldDaysSinceAdded  = "DaysSinceAdded"
+)
+
+// Rule is a condition which a track must satisfy to be included in a smart playlist.
+type Rule struct {
+	Field string      `json:"field"`
+	Op    string      `json:"op"`
+	Value interface{} `json:"value"`
+}
+
+// Smart is a smart playlist definition: the playlist contains all the tracks which
+// match all (or any) of the rules.
+type Smart struct {
+	Match string `json:"match"`
+	Rules []Rule `json:"rules"`
+}
+
+// SmartFromJSONInterface creates a Smart from the JSON-decoded interface{} value,
+// and validates it.
+func SmartFromJSONInterface(raw interface{}) (*Smart, error) {
+	b, err := json.Marshal(raw)
+	if err != nil {
+		return nil, err
+	}
+
+	s := &Smart{}
+	if err := json.Unmarshal(b, s); err != nil {
+		return nil, fmt.Errorf("invalid smart playlist: %v", err)
+	}
+	if err := s.Validate(); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// Validate checks that the fields, operators and values used in the rules are valid.
+func (s *Smart) Validate() error {
+	if s.Match != MatchAll && s.Match != MatchAny {
+		return fmt.Errorf("invalid match: %q (expected %q or %q)", s.Match, MatchAll, MatchAny)
+	}
+	if len(s.Rules) == 0 {
+		return fmt.Errorf("smart playlist must have at least one rule")
+	}
+	for _, r := range s.Rules {
+		if err := r.validate(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (r Rule) validate() error {
+	switch {
+	case stringFields[r.Field]:
+		if _, ok := r.Value.(string); !ok {
+			return fmt.Errorf("expected string value for field %v, got %T", r.Field, r.Value)
+		}
+		switch r.Op {
+		case OpEqual, OpNotEqual, OpContains:
+			return nil
+		}
+
+	case r.Field == FieldFavourite:
+		if _, ok := r.Value.(bool); !ok {
+			return fmt.Errorf("expected bool value for field %v, got %T", r.Field, r.Value)
+		}
+		switch r.Op {
+		case OpEqual, OpNotEqual:
+			return nil
+		}
+
+	case intFields[r.Field], r.Field == FieldRating, r.Field == FieldPlayCount,
+		r.Field == FieldDaysSincePlayed, r.Field == FieldDaysSinceAdded:
+		if _, ok := r.intValue(); !ok {
+			return fmt.Errorf("expected numeric value for field %v, got %T", r.Field, r.Value)
+		}
+		switch r.Op {
+		case OpEqual, OpNotEqual, OpLess, OpLessEqual, OpGreater, OpGreaterEqual:
+			return nil
+		}
+
+	default:
+		return fmt.Errorf("unknown field: %v", r.Field)
+	}
+	return fmt.Errorf("invalid operator for field %v: %v", r.Field, r.Op)
+}
+
+func (r Rule) intValue() (int, bool) {
+	switch v := r.Value.(type) {
+	case int:
+		return v, true
+	case float64:
+		return int(v), true
+	}
+	return 0, false
+}
+
+// Sources are the metadata stores used when evaluating rules.  Any of the stores can be
+// nil, in which case rules using them are evaluated as if the store was empty.
+type Sources struct {
+	History    history.Store
+	Ratings    rating.Store
+	Favourites favourite.Store
+}
+
+// rating returns the rating of the path, or of its closest rated ancestor.
+func (s Sources) rating(p index.Path) int {
+	if s.Ratings == nil {
+		return 0
+	}
+	for n := len(p); n > 0; n-- {
+		if v := s.Ratings.Get(p[:n]); v != rating.None {
+			return int(v)
+		}
+	}
+	return 0
+}
+
+// favourite returns true if the path, or any of its ancestors, is a favourite.
+func (s Sources) favourite(p index.Path) bool {
+	if s.Favourites == nil {
+		return false
+	}
+	for n := len(p); n > 0; n-- {
+		if s.Favourites.Get(p[:n]) {
+			return true
+		}
+	}
+	return false
+}
+
+func (s Sources) plays(p index.Path) []time.Time {
+	if s.History == nil {
+		return nil
+	}
+	return s.History.Get(p)
+}
+
+// now returns the current time, used to evaluate rules which are relative to it.
+var now = time.Now
+
+func daysSince(t time.Time) int {
+	return int(now().Sub(t) / (24 * time.Hour))
+}
+
+// match returns true if the track t (with path p) satisfies the rule.
+func (r Rule) match(t index.Track, p index.Path, src Sources) bool {
+	switch {
+	case stringFields[r.Field]:
+		v, _ := r.Value.(string)
+		return compareString(r.Op, t.GetString(r.Field), v)
+
+	case r.Field == FieldFavourite:
+		v, _ := r.Value.(bool)
+		if r.Op == OpNotEqual {
+			return src.favourite(p) != v
+		}
+		return src.favourite(p) == v
+	}
+
+	var x int
+	switch r.Field {
+	case FieldRating:
+		x = src.rating(p)
+	case FieldPlayCount:
+		x = len(src.plays(p))
+	case FieldDaysSincePlayed:
+		var last time.Time
+		for _, t := range src.plays(p) {
+			if t.After(last) {
+				last = t
+			}
+		}
+		if last.IsZero() {
+			last = time.Unix(0, 0)
+		}
+		x = daysSince(last)
+	case FieldDaysSinceAdded:
+		x = daysSince(t.GetTime("DateAdded"))
+	default:
+		x = t.GetInt(r.Field)
+	}
+	v, _ := r.intValue()
+	return compareInt(r.Op, x, v)
+}
+
+func compareString(op, x, y string) bool {
+	x, y = strings.ToLower(x), strings.ToLower(y)
+	switch op {
+	case OpEqual:
+		return x == y
+	case OpNotEqual:
+		return x != y
+	case OpContains:
+		return strings.Contains(x, y)
+	}
+	return false
+}
+
+func compareInt(op string, x, y int) bool {
+	switch op {
+	case OpEqual:
+		return x == y
+	case OpNotEqual:
+		return x != y
+	case OpLess:
+		return x < y
+	case OpLessEqual:
+		return x <= y
+	case OpGreater:
+		return x > y
+	case OpGreaterEqual:
+		return x >= y
+	}
+	return false
+}
+
+// match returns true if the track t (with path p) satisfies the rules of the smart playlist.
+func (s *Smart) match(t index.Track, p index.Path, src Sources) bool {
+	for _, r := range s.Rules {
+		m := r.match(t, p, src)
+		if s.Match == MatchAny && m {
+			return true
+		}
+		if s.Match == MatchAll && !m {
+			return false
+		}
+	}
+	return s.Match == MatchAll
+}
+
+// Tracks is the list of tracks (and their paths) in a "Root" collection which smart playlists
+// are evaluated against.  It is built once for each collection (see NewTracks), so that the
+// collection isn't walked each time a smart playlist is evaluated.
+type Tracks struct {
+	groups []trackGroup
+}
+
+// trackGroup is a top-level group of the collection, with the tracks it contains.
+type trackGroup struct {
+	path   index.Path
+	tracks []index.Track
+	paths  []index.Path
+}
+
+// NewTracks walks the "Root" collection c, and returns its tracks.
+func NewTracks(c index.Collection) *Tracks {
+	keys := c.Keys()
+	ts := &Tracks{
+		groups: make([]trackGroup, len(keys)),
+	}
+	for i, k := range keys {
+		g := &ts.groups[i]
+		g.path = index.Path{"Root", k}
+		index.Walk(c.Get(k), g.path, func(t index.Track, p index.Path) error {
+			g.tracks = append(g.tracks, t)
+			g.paths = append(g.paths, p)
+			return nil
+		})
+	}
+	return ts
+}
+
+// Items evaluates the smart playlist against the tracks ts and returns the resulting list of
+// items.  Each item is a (top-level) group of the collection which contains matching tracks,
+// with the tracks that don't match removed.
+func (s *Smart) Items(ts *Tracks, src Sources) []*Item {
+	var items []*Item
+	for _, g := range ts.groups {
+		var matched bool
+		var remove []index.Path
+		for i, t := range g.tracks {
+			if s.match(t, g.paths[i], src) {
+				matched = true
+				continue
+			}
+			remove = append(remove, g.paths[i])
+		}
+
+		if !matched {
+			continue
+		}
+		item := newItem(g.path)
+		for _, p := range remove {
+			item.AddTransform(RemovePath(p))
+		}
+		items = append(items, item)
+	}
+	return items
+}
@@ -0,0 +1,262 @@
+// Copyright 2015, David Howden
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package playlist
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/amiforus/tchaik/index"
+	"github.com/amiforus/tchaik/index/attr"
+	"github.com/amiforus/tchaik/index/history"
+	"github.com/amiforus/tchaik/index/rating"
+)
+
+type testTrack struct {
+	Name, Album, Genre string
+	Artist, Location   string
+	Year, TotalTime    int
+	DateAdded          time.Time
+}
+
+func (t testTrack) GetString(f string) string {
+	switch f {
+	case "Name":
+		return t.Name
+	case "Album":
+		return t.Album
+	case "Genre":
+		return t.Genre
+	case "Artist":
+		return t.Artist
+	case "Location":
+		return t.Location
+	}
+	return ""
+}
+
+func (t testTrack) GetStrings(f string) []string { return nil }
+
+func (t testTrack) GetInt(f string) int {
+	switch f {
+	case "Year":
+		return t.Year
+	case "TotalTime":
+		return t.TotalTime
+	}
+	return 0
+}
+
+func (t testTrack) GetTime(f string) time.Time {
+	if f == "DateAdded" {
+		return t.DateAdded
+	}
+	return time.Time{}
+}
+
+type testTracker []testTrack
+
+func (d testTracker) Tracks() []index.Track {
+	result := make([]index.Track, len(d))
+	for i, x := range d {
+		result[i] = x
+	}
+	return result
+}
+
+type testHistory map[string][]time.Time
+
+func (h testHistory) Add(p index.Path) error                      { return nil }
+func (h testHistory) Get(p index.Path) []time.Time                { return h[fmt.Sprintf("%v", p)] }
+func (h testHistory) Import([]history.Plays) error                { return nil }
+func (h testHistory) Migrate(index.PathMap) ([]index.Path, error) { return nil, nil }
+
+type testRatings map[string]rating.Value
+
+func (r testRatings) Set(p index.Path, v rating.Value) error      { return nil }
+func (r testRatings) Get(p index.Path) rating.Value               { return r[fmt.Sprintf("%v", p)] }
+func (r testRatings) Import([]rating.Rating) error                { return nil }
+func (r testRatings) Migrate(index.PathMap) ([]index.Path, error) { return nil, nil }
+
+func TestSmartValidate(t *testing.T) {
+	tests := []struct {
+		s  Smart
+		ok bool
+	}{
+		{Smart{Match: MatchAll, Rules: []Rule{{"Genre", OpEqual, "Jazz"}}}, true},
+		{Smart{Match: MatchAny, Rules: []Rule{{"Year", OpLess, 1965.0}, {"Rating", OpGreaterEqual, 4}}}, true},
+		{Smart{Match: MatchAll, Rules: []Rule{{"Favourite", OpEqual, true}}}, true},
+		{Smart{Match: MatchAll}, false},
+		{Smart{Match: "some", Rules: []Rule{{"Genre", OpEqual, "Jazz"}}}, false},
+		{Smart{Match: MatchAll, Rules: []Rule{{"Unknown", OpEqual, "Jazz"}}}, false},
+		{Smart{Match: MatchAll, Rules: []Rule{{"Genre", OpLess, "Jazz"}}}, false},
+		{Smart{Match: MatchAll, Rules: []Rule{{"Genre", OpEqual, 1.0}}}, false},
+		{Smart{Match: MatchAll, Rules: []Rule{{"Year", OpContains, 1965.0}}}, false},
+		{Smart{Match: MatchAll, Rules: []Rule{{"Favourite", OpEqual, "yes"}}}, false},
+	}
+
+	for ii, tt := range tests {
+		err := tt.s.Validate()
+		if (err == nil) != tt.ok {
+			t.Errorf("[%d] Validate() = %v, expected ok: %v", ii, err, tt.ok)
+		}
+	}
+}
+
+func TestSmartItems(t *testing.T) {
+	now = func() time.Time { return time.Date(2015, 6, 1, 0, 0, 0, 0, time.UTC) }
+	defer func() { now = time.Now }()
+
+	tracks := []testTrack{
+		{Name: "So What", Album: "Kind of Blue", Genre: "Jazz", Year: 1959},
+		{Name: "Freddie Freeloader", Album: "Kind of Blue", Genre: "Jazz", Year: 1959},
+		{Name: "Blue in Green", Album: "Kind of Blue", Genre: "Jazz", Year: 1959},
+		{Name: "Take Five", Album: "Time Out", Genre: "jazz", Year: 1959},
+		{Name: "Tutu", Album: "Tutu", Genre: "Jazz", Year: 1986},
+		{Name: "Aria", Album: "Goldberg Variations", Genre: "Classical", Year: 1955, DateAdded: now().Add(-24 * time.Hour)},
+	}
+	c := index.Collect(testTracker(tracks), index.By(attr.String("Album")))
+	index.SortKeysByGroupName(c)
+
+	keys := make(map[string]index.Key)
+	for _, k := range c.Keys() {
+		keys[c.Get(k).Name()] = k
+	}
+	kob := index.Path{"Root", keys["Kind of Blue"]}
+	track := func(p index.Path, n string) index.Path {
+		return append(append(index.Path{}, p...), index.Key(n))
+	}
+
+	src := Sources{
+		History: testHistory{
+			fmt.Sprintf("%v", track(kob, "0")): {now().Add(-10 * 24 * time.Hour)},
+			fmt.Sprintf("%v", track(kob, "1")): {now().Add(-200 * 24 * time.Hour)},
+		},
+		Ratings: testRatings{
+			fmt.Sprintf("%v", kob):             4,
+			fmt.Sprintf("%v", track(kob, "2")): 2,
+		},
+	}
+
+	jazzBefore1965 := []Rule{
+		{"Genre", OpEqual, "Jazz"},
+		{"Year", OpLess, 1965},
+	}
+
+	tests := []struct {
+		smart Smart
+		out   []string
+	}{
+		{
+			Smart{Match: MatchAll, Rules: jazzBefore1965},
+			[]string{"Kind of Blue", "Time Out"},
+		},
+		{
+			Smart{Match: MatchAll, Rules: append(jazzBefore1965, Rule{"DaysSincePlayed", OpGreater, 90})},
+			[]string{"Kind of Blue -0", "Time Out"},
+		},
+		{
+			Smart{Match: MatchAll, Rules: append(jazzBefore1965, Rule{"DaysSincePlayed", OpGreater, 90}, Rule{"Rating", OpGreaterEqual, 4})},
+			[]string{"Kind of Blue -0 -2"},
+		},
+		{
+			Smart{Match: MatchAll, Rules: []Rule{{"PlayCount", OpEqual, 1}}},
+			[]string{"Kind of Blue -2"},
+		},
+		{
+			Smart{Match: MatchAny, Rules: []Rule{{"Year", OpGreater, 1980}, {"DaysSinceAdded", OpLess, 7}}},
+			[]string{"Goldberg Variations", "Tutu"},
+		},
+		{
+			Smart{Match: MatchAll, Rules: []Rule{{"Name", OpContains, "blue"}}},
+			[]string{"Kind of Blue -0 -1"},
+		},
+		{
+			Smart{Match: MatchAll, Rules: []Rule{{"Favourite", OpEqual, true}}},
+			nil,
+		},
+	}
+
+	ts := NewTracks(c)
+	for ii, tt := range tests {
+		var got []string
+		for _, item := range tt.smart.Items(ts, src) {
+			s := c.Get(item.path[1]).Name()
+			for _, tr := range item.transforms {
+				p := index.Path(tr.(RemovePath))
+				s += " -" + string(p[len(p)-1])
+			}
+			got = append(got, s)
+		}
+		if !reflect.DeepEqual(got, tt.out) {
+			t.Errorf("[%d] Items() = %#v, expected %#v", ii, got, tt.out)
+		}
+	}
+}
+
+func TestSmartPlaylist(t *testing.T) {
+	p := NewSmart(&Smart{Match: MatchAll, Rules: []Rule{{"Genre", OpEqual, "Jazz"}}})
+	if err := p.Add(index.NewPath("Root:a")); err == nil {
+		t.Errorf("expected error adding item to smart playlist")
+	}
+
+	b, err := json.Marshal(p)
+	if err != nil {
+		t.Fatalf("unexpected error marshalling smart playlist: %v", err)
+	}
+
+	got := &Playlist{}
+	if err := json.Unmarshal(b, got); err != nil {
+		t.Fatalf("unexpected error unmarshalling smart playlist: %v", err)
+	}
+	if got.Smart() == nil || !reflect.DeepEqual(*got.Smart(), *p.Smart()) {
+		t.Errorf("json.Unmarshal(%s) = %#v, expected %#v", b, got.Smart(), p.Smart())
+	}
+}
+
+func TestPlaylistUpdate(t *testing.T) {
+	tracks := []testTrack{
+		{Name: "So What", Album: "Kind of Blue", Genre: "Jazz"},
+		{Name: "Aria", Album: "Goldberg Variations", Genre: "Classical"},
+	}
+	c := index.Collect(testTracker(tracks), index.By(attr.String("Album")))
+	index.SortKeysByGroupName(c)
+	ts := NewTracks(c)
+
+	p := NewSmart(&Smart{Match: MatchAll, Rules: []Rule{{"Genre", OpEqual, "Jazz"}}})
+	if !p.Update(ts, Sources{}) {
+		t.Errorf("Update() = false, expected true for first evaluation")
+	}
+	if n := len(p.Items()); n != 1 {
+		t.Errorf("len(Items()) = %d, expected 1", n)
+	}
+
+	// Re-evaluating with the same library doesn't change the items, including after the
+	// playlist has been reloaded.
+	b, err := json.Marshal(p)
+	if err != nil {
+		t.Fatalf("unexpected error from Marshal: %v", err)
+	}
+	q := &Playlist{}
+	if err := json.Unmarshal(b, q); err != nil {
+		t.Fatalf("unexpected error from Unmarshal: %v", err)
+	}
+	if q.Update(ts, Sources{}) {
+		t.Errorf("Update() = true, expected false when items haven't changed")
+	}
+
+	// Updating a copy doesn't change the original.
+	x := NewSmart(&Smart{Match: MatchAll, Rules: []Rule{{"Genre", OpEqual, "Classical"}}})
+	y := x.Copy()
+	if !y.Update(ts, Sources{}) {
+		t.Errorf("Update() = false, expected true")
+	}
+	if n := len(x.Items()); n != 0 {
+		t.Errorf("len(Items()) of original = %d, expected 0", n)
+	}
+}
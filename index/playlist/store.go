@@ -5,6 +5,7 @@
 package playlist
 
 import (
+	"sort"
 	"sync"
 
 	"github.com/amiforus/tchaik/index"
@@ -12,7 +13,7 @@ import (
 
 // Store is an interface which defines methods for implementing a playlist store.
 type Store interface {
-	// Names returns the name of playlists in the store.
+	// Names returns the (sorted) names of playlists in the store.
 	Names() []string
 
 	// Get returns the playlist for the given name.
@@ -23,6 +24,11 @@ type Store interface {
 
 	// Delete removes the playlist with the given name.
 	Delete(name string) error
+
+	// Migrate maps the paths in the playlists using the index.PathMap (i.e. after the library
+	// has been rebuilt), and returns the paths which could not be mapped (which are kept, so
+	// that they can be resolved by a later migration).
+	Migrate(index.PathMap) ([]index.Path, error)
 }
 
 // NewStore creates a basic implementation of a playlist store, using the given path as the
@@ -49,10 +55,14 @@ type store struct {
 
 // Names implements Store.
 func (s *store) Names() []string {
+	s.RLock()
+	defer s.RUnlock()
+
 	n := make([]string, 0, len(s.m))
 	for k := range s.m {
 		n = append(n, k)
 	}
+	sort.Strings(n)
 	return n
 }
 
@@ -81,3 +91,28 @@ func (s *store) Delete(name string) error {
 	delete(s.m, name)
 	return s.store.Persist(&s.m)
 }
+
+// Migrate implements Store.
+func (s *store) Migrate(pm index.PathMap) ([]index.Path, error) {
+	s.Lock()
+	defer s.Unlock()
+
+	var unresolved []index.Path
+	changed := false
+	for name, p := range s.m {
+		// p may be in use (i.e. by cursors), so the copy is changed and replaces it.
+		q := p.clone()
+		u, ok := q.migrate(pm)
+		unresolved = append(unresolved, u...)
+		if ok {
+			s.m[name] = q
+			changed = true
+		}
+	}
+	sort.Sort(index.PathSlice(unresolved))
+
+	if !changed {
+		return unresolved, nil
+	}
+	return unresolved, s.store.Persist(&s.m)
+}
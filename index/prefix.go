@@ -4,10 +4,7 @@
 
 package index
 
-import (
-	"strconv"
-	"strings"
-)
+import "strings"
 
 // pfxCol is the collection implementation for prefix-grouped collections
 type pfxCol struct {
@@ -15,7 +12,7 @@ type pfxCol struct {
 	field string
 
 	last string
-	n    int
+	key  Key // key of the last group, empty if there isn't one
 }
 
 // pfxTrack is a track which truncates the specified field with by the given
@@ -44,12 +41,22 @@ func (c *pfxCol) add(name string, t Track) {
 			break
 		}
 	}
+	c.addGroup(name, pfxLen, t)
+}
 
-	if c.last != name {
-		c.n++
+// addGroup adds the track t to the group with the given name, trimming the first pfx bytes
+// from its field.  Consecutive tracks with the same name are added to the same group.
+func (c *pfxCol) addGroup(name string, pfx int, t Track) {
+	// Keys are created from the group names (see groupKeys), so that they don't change when
+	// groups are added before them.  Names can occur more than once (i.e. tracks without a
+	// prefix either side of a group), and so each occurrence is given its own key.
+	if c.key == "" || c.last != name {
+		k, renamed := c.names.add(c.names.unique(name))
+		c.rekey(renamed)
 		c.last = name
+		c.key = k
 	}
-	c.col.addTrack(name, Key(strconv.Itoa(c.n)), pfxTrack{t, c.field, pfxLen})
+	c.col.addTrack(name, c.key, pfxTrack{t, c.field, pfx})
 }
 
 // extension of strings.SplitAfter to split string multiple times using multiple
@@ -105,18 +112,25 @@ func (p ByPrefix) Collect(t Tracker) Collection {
 	}
 
 	gg := pfxCol{col: newCol(newName), field: field}
+	gg.addPrefixed(tracks)
+	return gg
+}
+
+// addPrefixed adds the tracks to the collection, grouping consecutive tracks which have a
+// common prefix in the field.
+func (c *pfxCol) addPrefixed(tracks []Track) {
 	switch len(tracks) {
 	case 0:
-		return gg
+		return
 
 	case 1:
-		gg.add("", tracks[0])
-		return gg
+		c.add("", tracks[0])
+		return
 	}
 
 	words := make([][]string, len(tracks))
 	for i, t := range tracks {
-		words[i] = splitAfterMultiple(t.GetString(field), prefixGroupSplit)
+		words[i] = splitAfterMultiple(t.GetString(c.field), prefixGroupSplit)
 	}
 
 	items := buildItems(words)
@@ -125,7 +139,7 @@ func (p ByPrefix) Collect(t Tracker) Collection {
 	var curr int
 	for i, item := range items {
 		if item.before >= item.after && item.before == curr {
-			gg.add(name, tracks[i])
+			c.add(name, tracks[i])
 			continue
 		}
 
@@ -134,10 +148,8 @@ func (p ByPrefix) Collect(t Tracker) Collection {
 		if item.after > 0 {
 			name = strings.Join(words[i][:item.after], "")
 		}
-		gg.add(name, tracks[i])
+		c.add(name, tracks[i])
 	}
-
-	return gg
 }
 
 func largestPrefixWords(s, t []string) int {
@@ -557,3 +557,44 @@ func trackStrings(t Tracker, field string) []string {
 	}
 	return result
 }
+
+func TestPrefixKeys(t *testing.T) {
+	tracks := []testTrack{
+		{Name: "Intro"},
+		{Name: "Symphony No. 1: I. Allegro"},
+		{Name: "Symphony No. 1: II. Adagio"},
+		{Name: "Interlude"},
+		{Name: "Symphony No. 2: I. Allegro"},
+		{Name: "Symphony No. 2: II. Adagio"},
+		{Name: "Outro"},
+	}
+
+	keys := func(tracks []testTrack) map[string]Key {
+		c := ByPrefix("Name").Collect(testTracker(tracks))
+		result := make(map[string]Key)
+		seen := make(map[Key]bool)
+		for _, k := range c.Keys() {
+			if seen[k] {
+				t.Errorf("duplicate key: %#v", k)
+			}
+			seen[k] = true
+
+			// Keys of unnamed groups depend on the number of unnamed groups before them.
+			if g := c.Get(k); g.Name() != "" {
+				result[g.Name()] = k
+			}
+		}
+		return result
+	}
+
+	before := keys(tracks[1:])
+	after := keys(tracks)
+	if len(before) != 2 {
+		t.Errorf("expected 2 named groups, got %v", before)
+	}
+	for n, k := range before {
+		if after[n] != k {
+			t.Errorf("key for group %#v changed from %#v to %#v", n, k, after[n])
+		}
+	}
+}
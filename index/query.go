@@ -0,0 +1,723 @@
+// Copyright 2015, David Howden
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package index
+
+import (
+	"sort"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"github.com/amiforus/tchaik/index/attr"
+)
+
+// queryDoc is the searchable representation of a Track.
+type queryDoc struct {
+	path Path
+	strs [][]string // normalised words for each string field
+	ints []int      // values of each int field
+}
+
+// queryFields maps (lower case) field names to their position in queryDoc.
+type queryFields struct {
+	strs map[string]int
+	ints map[string]int
+	def  []int // default fields for unqualified terms
+}
+
+// queryNode is a node in a parsed query tree.
+type queryNode interface {
+	// match returns true if the queryDoc matches the node.
+	match(*queryDoc) bool
+
+	// score returns the relevance of a matching queryDoc.
+	score(*queryDoc) int
+}
+
+type andNode []queryNode
+
+func (n andNode) match(d *queryDoc) bool {
+	for _, x := range n {
+		if !x.match(d) {
+			return false
+		}
+	}
+	return true
+}
+
+func (n andNode) score(d *queryDoc) int {
+	s := 0
+	for _, x := range n {
+		s += x.score(d)
+	}
+	return s
+}
+
+type orNode []queryNode
+
+func (n orNode) match(d *queryDoc) bool {
+	for _, x := range n {
+		if x.match(d) {
+			return true
+		}
+	}
+	return false
+}
+
+func (n orNode) score(d *queryDoc) int {
+	s := 0
+	for _, x := range n {
+		if x.match(d) {
+			if k := x.score(d); k > s {
+				s = k
+			}
+		}
+	}
+	return s
+}
+
+type notNode struct {
+	queryNode
+}
+
+func (n notNode) match(d *queryDoc) bool {
+	return !n.queryNode.match(d)
+}
+
+func (n notNode) score(*queryDoc) int { return 0 }
+
+// termNode matches a word (or phrase) in any of the given string fields.  Single words
+// are matched against their expansions (see FuzzyExpand), phrases must match a
+// consecutive sequence of words exactly.
+type termNode struct {
+	fields  []int
+	words   []string       // phrase
+	weights map[string]int // single word expansions
+}
+
+func (n termNode) match(d *queryDoc) bool {
+	return n.score(d) > 0
+}
+
+func (n termNode) score(d *queryDoc) int {
+	s := 0
+	for _, f := range n.fields {
+		if n.weights == nil {
+			if matchPhrase(d.strs[f], n.words) {
+				return matchExact * len(n.words)
+			}
+			continue
+		}
+		for _, w := range d.strs[f] {
+			if k := n.weights[w]; k > s {
+				s = k
+			}
+		}
+	}
+	return s
+}
+
+func matchPhrase(words, q []string) bool {
+	if len(q) == 0 {
+		return false
+	}
+	for i := 0; i+len(q) <= len(words); i++ {
+		found := true
+		for j, x := range q {
+			if words[i+j] != x {
+				found = false
+				break
+			}
+		}
+		if found {
+			return true
+		}
+	}
+	return false
+}
+
+// rangeNode matches an int field within the range [min, max].
+type rangeNode struct {
+	field    int
+	min, max int
+}
+
+func (n rangeNode) match(d *queryDoc) bool {
+	v := d.ints[n.field]
+	return v != 0 && n.min <= v && v <= n.max
+}
+
+func (n rangeNode) score(*queryDoc) int { return matchExact }
+
+// noneNode never matches.
+type noneNode struct{}
+
+func (noneNode) match(*queryDoc) bool { return false }
+func (noneNode) score(*queryDoc) int  { return 0 }
+
+// positive returns true if the query tree contains terms which aren't negated.  Queries
+// with no positive terms would match most of the index, so are not evaluated.
+func positive(n queryNode) bool {
+	switch n := n.(type) {
+	case andNode:
+		for _, x := range n {
+			if positive(x) {
+				return true
+			}
+		}
+		return false
+	case orNode:
+		for _, x := range n {
+			if !positive(x) {
+				return false
+			}
+		}
+		return len(n) > 0
+	case notNode, noneNode:
+		return false
+	}
+	return true
+}
+
+type queryTokenType int
+
+const (
+	tokTerm queryTokenType = iota
+	tokOr
+	tokNot
+	tokOpen
+	tokClose
+)
+
+type queryToken struct {
+	typ    queryTokenType
+	field  string
+	value  string
+	phrase bool
+}
+
+// lexQuery splits the query string into tokens.
+func lexQuery(s string) []queryToken {
+	var tokens []queryToken
+	r := []rune(s)
+
+	readUntil := func(i int, fn func(rune) bool) (string, int) {
+		j := i
+		for j < len(r) && !fn(r[j]) {
+			j++
+		}
+		return string(r[i:j]), j
+	}
+	isQuote := func(x rune) bool { return x == '"' }
+	isBreak := func(x rune) bool { return unicode.IsSpace(x) || x == '(' || x == ')' || x == '"' }
+
+	for i := 0; i < len(r); {
+		switch x := r[i]; {
+		case unicode.IsSpace(x):
+			i++
+
+		case x == '(':
+			tokens = append(tokens, queryToken{typ: tokOpen})
+			i++
+
+		case x == ')':
+			tokens = append(tokens, queryToken{typ: tokClose})
+			i++
+
+		case x == '|':
+			tokens = append(tokens, queryToken{typ: tokOr})
+			i++
+
+		case x == '-' && i+1 < len(r) && !unicode.IsSpace(r[i+1]):
+			tokens = append(tokens, queryToken{typ: tokNot})
+			i++
+
+		case x == '"':
+			var v string
+			v, i = readUntil(i+1, isQuote)
+			tokens = append(tokens, queryToken{typ: tokTerm, value: v, phrase: true})
+			i++
+
+		default:
+			var w string
+			w, i = readUntil(i, isBreak)
+			if w == "OR" {
+				tokens = append(tokens, queryToken{typ: tokOr})
+				continue
+			}
+
+			t := queryToken{typ: tokTerm, value: w}
+			if n := strings.Index(w, ":"); n > 0 {
+				t.field, t.value = strings.ToLower(w[:n]), w[n+1:]
+				if t.value == "" && i < len(r) && r[i] == '"' {
+					t.value, i = readUntil(i+1, isQuote)
+					t.phrase = true
+					i++
+				}
+			}
+			tokens = append(tokens, t)
+		}
+	}
+	return tokens
+}
+
+// queryParser is a recursive descent parser for queries:
+//
+//	or    := and { ("OR" | "|") and }
+//	and   := unary { unary }
+//	unary := "-" unary | "(" or ")" | term
+type queryParser struct {
+	tokens   []queryToken
+	fields   queryFields
+	expander FuzzyExpand
+}
+
+func (p *queryParser) peek() (queryToken, bool) {
+	if len(p.tokens) == 0 {
+		return queryToken{}, false
+	}
+	return p.tokens[0], true
+}
+
+func (p *queryParser) next() queryToken {
+	t := p.tokens[0]
+	p.tokens = p.tokens[1:]
+	return t
+}
+
+func (p *queryParser) parseOr() queryNode {
+	var n orNode
+	for {
+		if x := p.parseAnd(); x != nil {
+			n = append(n, x)
+		}
+		t, ok := p.peek()
+		if !ok || t.typ != tokOr {
+			break
+		}
+		p.next()
+	}
+	switch len(n) {
+	case 0:
+		return nil
+	case 1:
+		return n[0]
+	}
+	return n
+}
+
+func (p *queryParser) parseAnd() queryNode {
+	var n andNode
+	for {
+		t, ok := p.peek()
+		if !ok || t.typ == tokOr || t.typ == tokClose {
+			break
+		}
+		if x := p.parseUnary(); x != nil {
+			n = append(n, x)
+		}
+	}
+	switch len(n) {
+	case 0:
+		return nil
+	case 1:
+		return n[0]
+	}
+	return n
+}
+
+func (p *queryParser) parseUnary() queryNode {
+	t := p.next()
+	switch t.typ {
+	case tokNot:
+		if _, ok := p.peek(); !ok {
+			return nil
+		}
+		x := p.parseUnary()
+		if x == nil {
+			return nil
+		}
+		return notNode{x}
+
+	case tokOpen:
+		n := p.parseOr()
+		if t, ok := p.peek(); ok && t.typ == tokClose {
+			p.next()
+		}
+		return n
+
+	case tokTerm:
+		return p.term(t)
+	}
+	return nil
+}
+
+// term creates the queryNode for a term token.
+func (p *queryParser) term(t queryToken) queryNode {
+	if t.field != "" {
+		if f, ok := p.fields.ints[t.field]; ok {
+			min, max, ok := parseRange(t.value)
+			if !ok {
+				return noneNode{}
+			}
+			return rangeNode{field: f, min: min, max: max}
+		}
+
+		f, ok := p.fields.strs[t.field]
+		if !ok {
+			// Not a field we know about, so treat the whole thing as words.
+			return p.words(p.fields.def, t.field+" "+t.value, t.phrase)
+		}
+		return p.words([]int{f}, t.value, t.phrase)
+	}
+	return p.words(p.fields.def, t.value, t.phrase)
+}
+
+// words creates the queryNode for the (unnormalised) string s in the given fields.
+func (p *queryParser) words(fields []int, s string, phrase bool) queryNode {
+	words := strings.Fields(removeNonAlphaNumeric(s))
+	switch {
+	case len(words) == 0:
+		return nil
+	case phrase:
+		return termNode{fields: fields, words: words}
+	case len(words) == 1:
+		return termNode{fields: fields, weights: p.expander.expand(words[0])}
+	}
+
+	// Words which have been split by normalisation (i.e. "op.27") must all be present.
+	n := make(andNode, len(words))
+	for i, w := range words {
+		n[i] = termNode{fields: fields, weights: p.expander.expand(w)}
+	}
+	return n
+}
+
+// parseRange parses "x", "x..y", "x.." or "..y" into an inclusive range.
+func parseRange(s string) (min, max int, ok bool) {
+	parts := strings.SplitN(s, "..", 2)
+	if len(parts) == 1 {
+		n, err := strconv.Atoi(s)
+		return n, n, err == nil
+	}
+
+	min, max = 0, int(^uint(0)>>1)
+	var err error
+	if parts[0] != "" {
+		if min, err = strconv.Atoi(parts[0]); err != nil {
+			return 0, 0, false
+		}
+	}
+	if parts[1] != "" {
+		if max, err = strconv.Atoi(parts[1]); err != nil {
+			return 0, 0, false
+		}
+	}
+	return min, max, parts[0] != "" || parts[1] != ""
+}
+
+// parseQuery parses the query string into a query tree, returns nil if the query is empty.
+func parseQuery(s string, f queryFields, e FuzzyExpand) queryNode {
+	p := &queryParser{
+		tokens:   lexQuery(s),
+		fields:   f,
+		expander: e,
+	}
+
+	var n andNode
+	for len(p.tokens) > 0 {
+		if x := p.parseOr(); x != nil {
+			n = append(n, x)
+		}
+		if t, ok := p.peek(); ok && t.typ == tokClose {
+			p.next() // unbalanced
+		}
+	}
+
+	switch len(n) {
+	case 0:
+		return nil
+	case 1:
+		return n[0]
+	}
+	return n
+}
+
+// querySearcher is a Searcher which evaluates queries against the tracks in a Collection.
+type querySearcher struct {
+	fields   queryFields
+	docs     []queryDoc
+	words    map[string][]int // mapping from word -> (ascending) indices of docs containing it
+	expander FuzzyExpand
+}
+
+// BuildQuerySearcher creates a Searcher which evaluates queries against the tracks in the
+// Collection, returning the paths of the Groups which contain matching tracks.  Results are
+// ordered by relevance: exact word matches rank above transliterations (Tchaikovsky,
+// Tschaikowsky, Čajkovskij), then prefixes and then words within a small edit distance
+// (see FuzzyExpand), with ties broken by the number of matching tracks.  The query
+// syntax supports:
+//
+//	bach                    words in any of the default fields
+//	"goldberg variations"   phrases
+//	composer:bach           words or phrases in a specific field
+//	year:1720..1750         numeric values or ranges (also year:1720.. and year:..1750)
+//	-artist:gould           negation
+//	bach OR handel          alternatives (also "|"), with grouping by parentheses
+//
+// Fields are referred to by the lower case name of the attribute.  String and Strings
+// attributes are searchable by word, Int attributes are searchable by range.  String and
+// Strings attributes with the same name are searched together (i.e. the original and
+// canonical names of artists, see SplitAliasList).  Unqualified terms are matched against
+// the attributes named in defaults.
+func BuildQuerySearcher(c Collection, fields []attr.Interface, defaults []string) Searcher {
+	s := &querySearcher{
+		fields: queryFields{
+			strs: make(map[string]int),
+			ints: make(map[string]int),
+		},
+	}
+
+	var strs [][]attr.Interface
+	var ints []attr.Interface
+	for _, a := range fields {
+		name := strings.ToLower(a.Name())
+		if _, ok := a.Value(zeroGetter{}).(int); ok {
+			s.fields.ints[name] = len(ints)
+			ints = append(ints, a)
+			continue
+		}
+		if i, ok := s.fields.strs[name]; ok {
+			strs[i] = append(strs[i], a)
+			continue
+		}
+		s.fields.strs[name] = len(strs)
+		strs = append(strs, []attr.Interface{a})
+	}
+	if i, ok := s.fields.strs["name"]; ok {
+		s.fields.strs["title"] = i
+	}
+	for _, d := range defaults {
+		if i, ok := s.fields.strs[strings.ToLower(d)]; ok {
+			s.fields.def = append(s.fields.def, i)
+		}
+	}
+
+	Walk(c, Path([]Key{"Root"}), func(t Track, p Path) error {
+		d := queryDoc{
+			path: p[:len(p)-1],
+			strs: make([][]string, len(strs)),
+			ints: make([]int, len(ints)),
+		}
+		for i, as := range strs {
+			for _, a := range as {
+				switch v := a.Value(t).(type) {
+				case string:
+					d.strs[i] = append(d.strs[i], strings.Fields(removeNonAlphaNumeric(v))...)
+				case []string:
+					d.strs[i] = append(d.strs[i], strings.Fields(removeNonAlphaNumeric(strings.Join(v, " ")))...)
+				}
+			}
+		}
+		for i, a := range ints {
+			d.ints[i], _ = a.Value(t).(int)
+		}
+		s.docs = append(s.docs, d)
+		return nil
+	})
+
+	s.words = make(map[string][]int)
+	for i, d := range s.docs {
+		for _, ws := range d.strs {
+			for _, w := range ws {
+				if n := s.words[w]; len(n) == 0 || n[len(n)-1] != i {
+					s.words[w] = append(n, i)
+				}
+			}
+		}
+	}
+
+	words := make([]string, 0, len(s.words))
+	for w := range s.words {
+		words = append(words, w)
+	}
+	s.expander = BuildFuzzyExpander(words)
+	return s
+}
+
+// zeroGetter is an attr.Getter which returns zero values, used to determine the
+// type of attributes.
+type zeroGetter struct{}
+
+func (zeroGetter) GetInt(string) int          { return 0 }
+func (zeroGetter) GetString(string) string    { return "" }
+func (zeroGetter) GetStrings(string) []string { return nil }
+
+// Search implements Searcher.
+func (s *querySearcher) Search(input string) []Path {
+	q := parseQuery(input, s.fields, s.expander)
+	if q == nil || !positive(q) {
+		return []Path{}
+	}
+
+	docs, ok := s.candidates(q)
+	if !ok {
+		docs = make([]int, len(s.docs))
+		for i := range docs {
+			docs[i] = i
+		}
+	}
+
+	m := make(map[string]int)
+	var r pathScores
+	for _, i := range docs {
+		d := &s.docs[i]
+		if !q.match(d) {
+			continue
+		}
+		e := d.path.Encode()
+		j, ok := m[e]
+		if !ok {
+			j = len(r.paths)
+			m[e] = j
+			r.paths = append(r.paths, d.path)
+			r.scores = append(r.scores, 0)
+			r.counts = append(r.counts, 0)
+		}
+		if k := q.score(d); k > r.scores[j] {
+			r.scores[j] = k
+		}
+		r.counts[j]++
+	}
+	sort.Stable(r)
+	return r.paths
+}
+
+// candidates returns the (ascending) indices of docs which could match the query tree,
+// using the word index to avoid evaluating the query against every doc.  Returns false
+// if the node can't be narrowed using words (i.e. negations and ranges), in which case
+// every doc is a candidate.
+func (s *querySearcher) candidates(n queryNode) ([]int, bool) {
+	switch n := n.(type) {
+	case andNode:
+		var r []int
+		found := false
+		for _, x := range n {
+			c, ok := s.candidates(x)
+			if !ok {
+				continue
+			}
+			if !found {
+				r, found = c, true
+				continue
+			}
+			r = intersectInts(r, c)
+		}
+		return r, found
+
+	case orNode:
+		var r []int
+		for _, x := range n {
+			c, ok := s.candidates(x)
+			if !ok {
+				return nil, false
+			}
+			r = unionInts(r, c)
+		}
+		return r, true
+
+	case termNode:
+		if n.weights == nil {
+			// All the words in a phrase must be present.
+			var r []int
+			for i, w := range n.words {
+				if i == 0 {
+					r = s.words[w]
+					continue
+				}
+				r = intersectInts(r, s.words[w])
+			}
+			return r, true
+		}
+		seen := make(map[int]bool)
+		var r []int
+		for w := range n.weights {
+			for _, i := range s.words[w] {
+				if !seen[i] {
+					seen[i] = true
+					r = append(r, i)
+				}
+			}
+		}
+		sort.Ints(r)
+		return r, true
+
+	case noneNode:
+		return nil, true
+	}
+	return nil, false
+}
+
+// intersectInts returns the ascending list of ints in both of the ascending lists x and y.
+func intersectInts(x, y []int) []int {
+	var r []int
+	for i, j := 0, 0; i < len(x) && j < len(y); {
+		switch {
+		case x[i] < y[j]:
+			i++
+		case x[i] > y[j]:
+			j++
+		default:
+			r = append(r, x[i])
+			i++
+			j++
+		}
+	}
+	return r
+}
+
+// unionInts returns the ascending list of ints in either of the ascending lists x and y.
+func unionInts(x, y []int) []int {
+	r := make([]int, 0, len(x)+len(y))
+	i, j := 0, 0
+	for i < len(x) && j < len(y) {
+		switch {
+		case x[i] < y[j]:
+			r = append(r, x[i])
+			i++
+		case x[i] > y[j]:
+			r = append(r, y[j])
+			j++
+		default:
+			r = append(r, x[i])
+			i++
+			j++
+		}
+	}
+	r = append(r, x[i:]...)
+	return append(r, y[j:]...)
+}
+
+// pathScores sorts paths by descending score and then by descending count.
+type pathScores struct {
+	paths  []Path
+	scores []int
+	counts []int
+}
+
+func (p pathScores) Len() int { return len(p.paths) }
+
+func (p pathScores) Swap(i, j int) {
+	p.paths[i], p.paths[j] = p.paths[j], p.paths[i]
+	p.scores[i], p.scores[j] = p.scores[j], p.scores[i]
+	p.counts[i], p.counts[j] = p.counts[j], p.counts[i]
+}
+
+func (p pathScores) Less(i, j int) bool {
+	if p.scores[i] != p.scores[j] {
+		return p.scores[i] > p.scores[j]
+	}
+	return p.counts[i] > p.counts[j]
+}
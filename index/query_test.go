@@ -0,0 +1,227 @@
+// Copyright 2015, David Howden
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package index
+
+import (
+	"reflect"
+	"sort"
+	"testing"
+
+	"github.com/amiforus/tchaik/index/attr"
+)
+
+func TestParseRange(t *testing.T) {
+	max := int(^uint(0) >> 1)
+	tests := []struct {
+		in       string
+		min, max int
+		ok       bool
+	}{
+		{"1720", 1720, 1720, true},
+		{"1720..1750", 1720, 1750, true},
+		{"1720..", 1720, max, true},
+		{"..1750", 0, 1750, true},
+		{"..", 0, 0, false},
+		{"", 0, 0, false},
+		{"baroque", 0, 0, false},
+		{"1720..x", 0, 0, false},
+	}
+
+	for ii, tt := range tests {
+		min, max, ok := parseRange(tt.in)
+		if ok != tt.ok || ok && (min != tt.min || max != tt.max) {
+			t.Errorf("[%d] parseRange(%q) = %d, %d, %v, expected %d, %d, %v", ii, tt.in, min, max, ok, tt.min, tt.max, tt.ok)
+		}
+	}
+}
+
+func TestLexQuery(t *testing.T) {
+	tests := []struct {
+		in  string
+		out []queryToken
+	}{
+		{"", nil},
+		{
+			`bach -Artist:gould`,
+			[]queryToken{
+				{typ: tokTerm, value: "bach"},
+				{typ: tokNot},
+				{typ: tokTerm, field: "artist", value: "gould"},
+			},
+		},
+		{
+			`composer:"j.s. bach" (x OR y)|z`,
+			[]queryToken{
+				{typ: tokTerm, field: "composer", value: "j.s. bach", phrase: true},
+				{typ: tokOpen},
+				{typ: tokTerm, value: "x"},
+				{typ: tokOr},
+				{typ: tokTerm, value: "y"},
+				{typ: tokClose},
+				{typ: tokOr},
+				{typ: tokTerm, value: "z"},
+			},
+		},
+		{
+			`"goldberg variations" saint-saens - x`,
+			[]queryToken{
+				{typ: tokTerm, value: "goldberg variations", phrase: true},
+				{typ: tokTerm, value: "saint-saens"},
+				{typ: tokTerm, value: "-"},
+				{typ: tokTerm, value: "x"},
+			},
+		},
+	}
+
+	for ii, tt := range tests {
+		got := lexQuery(tt.in)
+		if !reflect.DeepEqual(got, tt.out) {
+			t.Errorf("[%d] lexQuery(%q) = %#v, expected %#v", ii, tt.in, got, tt.out)
+		}
+	}
+}
+
+func TestQuerySearcher(t *testing.T) {
+	tracks := []testTrack{
+		{Name: "Aria", Album: "Goldberg Variations (1955)", Composer: "Johann Sebastian Bach", Artist: "Glenn Gould", Year: 1955},
+		{Name: "Variatio 1", Album: "Goldberg Variations (1955)", Composer: "Johann Sebastian Bach", Artist: "Glenn Gould", Year: 1955},
+		{Name: "Aria", Album: "Goldberg Variations", Composer: "Johann Sebastian Bach", Artist: "Murray Perahia", Year: 2000},
+		{Name: "Ouverture", Album: "Water Music", Composer: "George Frideric Handel", Artist: "Trevor Pinnock", Year: 1983},
+		{Name: "Variations on a Theme", Album: "Enigma", Composer: "Edward Elgar", Artist: "Adrian Boult", Year: 1970},
+		{Name: "Piano Sonata No. 14, Op. 27", Album: "Sonatas", Composer: "Ludwig van Beethoven", Artist: "Glenn Gould", Year: 1967},
+	}
+	c := By(attr.String("Album")).Collect(testTracker(tracks))
+	s := BuildQuerySearcher(c, []attr.Interface{
+		attr.String("Composer"),
+		attr.Strings("Artist"),
+		attr.String("Album"),
+		attr.String("Name"),
+		attr.Int("Year"),
+	}, []string{"Composer", "Artist", "Album", "Name"})
+
+	tests := []struct {
+		in  string
+		out []string
+	}{
+		{"", nil},
+		{"bach", []string{"Goldberg Variations (1955)", "Goldberg Variations"}},
+		{"BACH", []string{"Goldberg Variations (1955)", "Goldberg Variations"}},
+		{"composer:bach", []string{"Goldberg Variations (1955)", "Goldberg Variations"}},
+		{"artist:bach", nil},
+		{"composer:bach -artist:gould", []string{"Goldberg Variations"}},
+		{"-artist:gould", nil},
+		{"gould", []string{"Goldberg Variations (1955)", "Sonatas"}},
+		{"year:1950..1969", []string{"Goldberg Variations (1955)", "Sonatas"}},
+		{"year:1983", []string{"Water Music"}},
+		{"year:..1960", []string{"Goldberg Variations (1955)"}},
+		{"year:baroque", nil},
+		{`"variations on"`, []string{"Enigma"}},
+		{`"on variations"`, nil},
+		{`name:"variatio 1"`, []string{"Goldberg Variations (1955)"}},
+		{"title:ouverture", []string{"Water Music"}},
+		{"variations", []string{"Goldberg Variations (1955)", "Goldberg Variations", "Enigma"}},
+		{"handel OR elgar", []string{"Enigma", "Water Music"}},
+		{"handel | elgar", []string{"Enigma", "Water Music"}},
+		{"(handel OR elgar) -year:1983", []string{"Enigma"}},
+		{"handel OR", []string{"Water Music"}},
+		{"op:27", []string{"Sonatas"}},
+		{"op. 14", []string{"Sonatas"}},
+		{"bee", []string{"Sonatas"}},
+		{"be", nil},
+	}
+
+	for ii, tt := range tests {
+		var got []string
+		for _, p := range s.Search(tt.in) {
+			got = append(got, c.Get(p[1]).Name())
+		}
+		// Paths with the same number of matching tracks can be in any order.
+		sort.Strings(got)
+		sort.Strings(tt.out)
+		if !reflect.DeepEqual(got, tt.out) {
+			t.Errorf("[%d] Search(%q) = %#v, expected %#v", ii, tt.in, got, tt.out)
+		}
+	}
+}
+
+func TestQuerySearcherOrder(t *testing.T) {
+	tracks := []testTrack{
+		{Name: "Aria", Album: "A"},
+		{Name: "Aria", Album: "B"},
+		{Name: "Aria", Album: "B"},
+		{Name: "Aria", Album: "C"},
+		{Name: "Aria", Album: "C"},
+		{Name: "Aria", Album: "C"},
+	}
+	c := By(attr.String("Album")).Collect(testTracker(tracks))
+	s := BuildQuerySearcher(c, []attr.Interface{attr.String("Name")}, []string{"Name"})
+
+	var got []string
+	for _, p := range s.Search("aria") {
+		got = append(got, c.Get(p[1]).Name())
+	}
+	expected := []string{"C", "B", "A"}
+	if !reflect.DeepEqual(got, expected) {
+		t.Errorf("Search(%q) = %v, expected %v", "aria", got, expected)
+	}
+}
+
+func TestQuerySearcherCandidates(t *testing.T) {
+	tracks := []testTrack{
+		{Name: "Aria", Album: "Goldberg Variations", Composer: "Bach", Year: 1955},
+		{Name: "Ouverture", Album: "Water Music", Composer: "Handel", Year: 1983},
+		{Name: "Variations on a Theme", Album: "Enigma", Composer: "Elgar", Year: 1970},
+		{Name: "Aria", Album: "Rinaldo", Composer: "Handel", Year: 1711},
+	}
+	c := By(attr.String("Album")).Collect(testTracker(tracks))
+	s := BuildQuerySearcher(c, []attr.Interface{
+		attr.String("Composer"),
+		attr.String("Album"),
+		attr.String("Name"),
+		attr.Int("Year"),
+	}, []string{"Composer", "Album", "Name"}).(*querySearcher)
+
+	tests := []struct {
+		in  string
+		n   int // number of candidates
+		all bool
+	}{
+		{"handel", 2, false},
+		{"handel aria", 1, false},
+		{"handel -aria", 2, false},
+		{"handel OR elgar", 3, false},
+		{"handel OR year:1955", 0, true},
+		{"year:1955", 0, true},
+		{"year:1955 aria", 2, false},
+		{`"variations on"`, 1, false},
+		{"year:baroque aria", 0, false},
+		{"vari", 2, false},
+	}
+
+	for ii, tt := range tests {
+		q := parseQuery(tt.in, s.fields, s.expander)
+		got, ok := s.candidates(q)
+		if ok == tt.all || len(got) != tt.n {
+			t.Errorf("[%d] candidates(%q) = %v, %v, expected %d candidates, %v", ii, tt.in, got, ok, tt.n, !tt.all)
+		}
+
+		// Narrowing the docs must not change the result.
+		var expected []Path
+		for i := range s.docs {
+			if q.match(&s.docs[i]) {
+				expected = append(expected, s.docs[i].path)
+			}
+		}
+		var n int
+		for _, i := range got {
+			if q.match(&s.docs[i]) {
+				n++
+			}
+		}
+		if !tt.all && n != len(expected) {
+			t.Errorf("[%d] candidates(%q) has %d matches, expected %d", ii, tt.in, n, len(expected))
+		}
+	}
+}
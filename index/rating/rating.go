@@ -27,6 +27,19 @@ type Store interface {
 	Set(index.Path, Value) error
 	// Get the rating for the path.
 	Get(index.Path) Value
+	// Import sets the ratings for many paths at once (i.e. from another media player).
+	Import([]Rating) error
+	// Migrate maps the paths in the store using the index.PathMap (i.e. after the library has
+	// been rebuilt), and returns the paths which could not be mapped (which are kept, so
+	// that they can be resolved by a later migration).
+	// If paths are mapped to the same path then the highest rating is kept.
+	Migrate(index.PathMap) ([]index.Path, error)
+}
+
+// Rating is the rating Value for a path.
+type Rating struct {
+	Path  index.Path
+	Value Value
 }
 
 // NewStore creates a basic implementation of a ratings store, using the given path as the
@@ -61,6 +74,17 @@ func (s *store) Set(p index.Path, v Value) error {
 	return s.store.Persist(&s.m)
 }
 
+// Import implements Store.
+func (s *store) Import(rs []Rating) error {
+	s.Lock()
+	defer s.Unlock()
+
+	for _, r := range rs {
+		s.m[fmt.Sprintf("%v", r.Path)] = r.Value
+	}
+	return s.store.Persist(&s.m)
+}
+
 // Get implements Store.
 func (s *store) Get(p index.Path) Value {
 	s.RLock()
@@ -68,3 +92,16 @@ func (s *store) Get(p index.Path) Value {
 
 	return s.m[fmt.Sprintf("%v", p)]
 }
+
+// Migrate implements Store.
+func (s *store) Migrate(pm index.PathMap) ([]index.Path, error) {
+	s.Lock()
+	defer s.Unlock()
+
+	return s.store.Migrate(&s.m, pm, func(x, y interface{}) interface{} {
+		if x.(Value) > y.(Value) {
+			return x
+		}
+		return y
+	})
+}
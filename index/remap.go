@@ -0,0 +1,159 @@
+// Copyright 2015, David Howden
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package index
+
+import "strconv"
+
+// PathMap is a function which maps a Path to a new Path, returning false if the Path
+// could not be resolved.
+type PathMap func(Path) (Path, bool)
+
+// Remapper maps paths in one Collection to the equivalent paths in another (i.e. after
+// the library has been rebuilt), so that metadata stored against paths can be kept.  Paths
+// are resolved to the tracks they contain in the old Collection, and then mapped to the
+// path of the same tracks (identified by ID) in the new Collection.
+//
+// Paths which use the keys of older versions of tchaik (where the keys of groups within
+// albums were counters) are also resolved, so a Remapper from a Collection to itself
+// updates old paths.
+type Remapper struct {
+	old, new Collection
+
+	paths map[string]Path // track ID -> path in new (excluding the first element)
+}
+
+// NewRemapper creates a Remapper which maps paths in old to paths in new, which should be
+// the collections as paths see them (i.e. wrapped in RootCollection).  The first element of
+// each path is the key of the collection (i.e. "Root"), and is kept as-is.
+func NewRemapper(old, new Collection) *Remapper {
+	return &Remapper{
+		old: old,
+		new: new,
+	}
+}
+
+// Map maps the path p in the old Collection to the equivalent path in the new Collection,
+// and returns false if p doesn't resolve in the old Collection, or its tracks are no longer
+// in the new Collection.  Paths to groups are mapped to the group (at the same depth or above)
+// which contains all their remaining tracks.
+func (r *Remapper) Map(p Path) (Path, bool) {
+	if len(p) < 2 {
+		return p, len(p) == 1
+	}
+
+	ids, track, ok := resolve(r.old, p[1:])
+	if !ok {
+		return nil, false
+	}
+
+	if r.paths == nil {
+		r.paths = make(map[string]Path)
+		Walk(r.new, Path{}, func(t Track, p Path) error {
+			r.paths[t.GetString("ID")] = p
+			return nil
+		})
+	}
+
+	var result Path
+	if track {
+		np, ok := r.paths[ids[0]]
+		if !ok {
+			return nil, false
+		}
+		result = np
+	} else {
+		for _, id := range ids {
+			np, ok := r.paths[id]
+			if !ok {
+				continue
+			}
+			np = np[:len(np)-1] // remove the track key
+			if result == nil {
+				result = np
+				continue
+			}
+			result = result[:commonKeysLen(result, np)]
+		}
+		if len(result) == 0 {
+			return nil, false
+		}
+		if len(result) > len(p)-1 {
+			// All the tracks are in one sub-group of the group.
+			result = result[:len(p)-1]
+		}
+	}
+
+	np := make(Path, len(result)+1)
+	np[0] = p[0]
+	copy(np[1:], result)
+	return np, true
+}
+
+// resolve returns the IDs of the tracks in the group given by p (relative to g), and true if
+// p is the path of a track.
+func resolve(g Group, p Path) (ids []string, track bool, ok bool) {
+	for i, k := range p {
+		c, ok := g.(Collection)
+		if !ok {
+			// Leaf group: the last key is the index of a track.
+			if i != len(p)-1 {
+				return nil, false, false
+			}
+			n, err := strconv.Atoi(string(k))
+			tracks := g.Tracks()
+			if err != nil || n < 0 || n >= len(tracks) {
+				return nil, false, false
+			}
+			return []string{tracks[n].GetString("ID")}, true, true
+		}
+
+		ng := c.Get(k)
+		if ng == nil {
+			ng = getCounterKey(c, k)
+			if ng == nil {
+				return nil, false, false
+			}
+		}
+		g = ng
+	}
+
+	for _, t := range g.Tracks() {
+		ids = append(ids, t.GetString("ID"))
+	}
+	return ids, false, true
+}
+
+// getCounterKey returns the Group in c which had the key k when keys of groups within
+// albums were counters (starting at 0 if the first group has no name, 1 otherwise), or nil
+// if there isn't one.
+func getCounterKey(c Collection, k Key) Group {
+	if len(k) >= keyLen {
+		return nil
+	}
+	n, err := strconv.Atoi(string(k))
+	if err != nil {
+		return nil
+	}
+
+	keys := c.Keys()
+	if len(keys) == 0 {
+		return nil
+	}
+	if c.Get(keys[0]).Name() != "" {
+		n--
+	}
+	if n < 0 || n >= len(keys) {
+		return nil
+	}
+	return c.Get(keys[n])
+}
+
+func commonKeysLen(p, q Path) int {
+	n := 0
+	for n < len(p) && n < len(q) && p[n] == q[n] {
+		n++
+	}
+	return n
+}
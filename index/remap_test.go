@@ -0,0 +1,98 @@
+// Copyright 2015, David Howden
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package index
+
+import "testing"
+
+func testRemapLibrary(tracks ...*track) Collection {
+	l := &library{trks: make(map[string]*track, len(tracks))}
+	for _, t := range tracks {
+		l.trks[t.ID] = t
+	}
+	return &RootCollection{Collection: CollectAlbums(l, GroupByAlbum, DefaultCollation)}
+}
+
+// testRemapPath returns the path of the track with the given ID in c.
+func testRemapPath(c Collection, id string) Path {
+	var result Path
+	Walk(c, Path{"Root"}, func(t Track, p Path) error {
+		if t.GetString("ID") == id {
+			result = p
+		}
+		return nil
+	})
+	return result
+}
+
+func TestRemapper(t *testing.T) {
+	old := testRemapLibrary(
+		&track{ID: "1", Name: "So What", Album: "Kind of Blue", TrackNumber: 1},
+		&track{ID: "2", Name: "Freddie Freeloader", Album: "Kind of Blue", TrackNumber: 2},
+		&track{ID: "3", Name: "Symphony No. 1: I. Allegro", Album: "Symphonies", TrackNumber: 1},
+		&track{ID: "4", Name: "Symphony No. 1: II. Adagio", Album: "Symphonies", TrackNumber: 2},
+		&track{ID: "5", Name: "Symphony No. 2: I. Allegro", Album: "Symphonies", TrackNumber: 3},
+		&track{ID: "6", Name: "Symphony No. 2: II. Adagio", Album: "Symphonies", TrackNumber: 4},
+		&track{ID: "7", Name: "Removed", Album: "Removed"},
+		&track{ID: "10", Name: "Suite: I. Prelude", Album: "Suite", TrackNumber: 1},
+		&track{ID: "11", Name: "Suite: II. Allemande", Album: "Suite", TrackNumber: 2},
+	)
+
+	// Kind of Blue is renamed, a work is added to the start of Symphonies, and Removed is
+	// removed.
+	new := testRemapLibrary(
+		&track{ID: "1", Name: "So What", Album: "Kind of Blue (Legacy Edition)", TrackNumber: 1},
+		&track{ID: "2", Name: "Freddie Freeloader", Album: "Kind of Blue (Legacy Edition)", TrackNumber: 2},
+		&track{ID: "8", Name: "Overture: I. Allegro", Album: "Symphonies", TrackNumber: 1},
+		&track{ID: "9", Name: "Overture: II. Adagio", Album: "Symphonies", TrackNumber: 2},
+		&track{ID: "3", Name: "Symphony No. 1: I. Allegro", Album: "Symphonies", TrackNumber: 3},
+		&track{ID: "4", Name: "Symphony No. 1: II. Adagio", Album: "Symphonies", TrackNumber: 4},
+		&track{ID: "5", Name: "Symphony No. 2: I. Allegro", Album: "Symphonies", TrackNumber: 5},
+		&track{ID: "6", Name: "Symphony No. 2: II. Adagio", Album: "Symphonies", TrackNumber: 6},
+	)
+
+	oldAlbum := testRemapPath(old, "1")[:2]
+	newAlbum := testRemapPath(new, "1")[:2]
+	oldWork := testRemapPath(old, "5")[:3]
+	newWork := testRemapPath(new, "5")[:3]
+
+	// Path of track 6 when keys of groups within albums were counters.
+	legacy := Path{oldWork[0], oldWork[1], "2", "1"}
+
+	tests := []struct {
+		in       Path
+		expected Path
+		ok       bool
+	}{
+		{Path{"Root"}, Path{"Root"}, true},
+		{oldAlbum, newAlbum, true},
+		{testRemapPath(old, "2"), testRemapPath(new, "2"), true},
+		{oldWork, newWork, true},
+		{testRemapPath(old, "6"), testRemapPath(new, "6"), true},
+		{legacy, testRemapPath(new, "6"), true},
+		{testRemapPath(old, "7"), nil, false},
+		{Path{"Root", "unknown"}, nil, false},
+		{append(oldAlbum, "10"), nil, false},
+	}
+
+	r := NewRemapper(old, new)
+	for ii, tt := range tests {
+		got, ok := r.Map(tt.in)
+		if ok != tt.ok || !got.Equal(tt.expected) {
+			t.Errorf("[%d] Map(%v) = %v, %v, expected %v, %v", ii, tt.in, got, ok, tt.expected, tt.ok)
+		}
+	}
+
+	// Mapping to the same collection updates legacy paths, and leaves others unchanged.
+	r = NewRemapper(old, old)
+	suite := testRemapPath(old, "10")[:2]
+	for _, p := range []Path{oldAlbum, oldWork, testRemapPath(old, "6"), suite} {
+		if got, ok := r.Map(p); !ok || !got.Equal(p) {
+			t.Errorf("Map(%v) = %v, %v, expected %v, true", p, got, ok, p)
+		}
+	}
+	if got, ok := r.Map(legacy); !ok || !got.Equal(testRemapPath(old, "6")) {
+		t.Errorf("Map(%v) = %v, %v, expected %v, true", legacy, got, ok, testRemapPath(old, "6"))
+	}
+}
@@ -0,0 +1,103 @@
+// Copyright 2015, David Howden
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Package rhythmbox defines types and methods for creating a Tchaik library representation
+// of a Rhythmbox library (rhythmdb.xml).
+package rhythmbox
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/amiforus/tchaik/index/migrate"
+	"github.com/amiforus/tchaik/index/rating"
+)
+
+// entry is a song entry in rhythmdb.xml.
+type entry struct {
+	Type        string  `xml:"type,attr"`
+	Title       string  `xml:"title"`
+	Genre       string  `xml:"genre"`
+	Artist      string  `xml:"artist"`
+	Album       string  `xml:"album"`
+	AlbumArtist string  `xml:"album-artist"`
+	Composer    string  `xml:"composer"`
+	TrackNumber int     `xml:"track-number"`
+	TrackTotal  int     `xml:"track-total"`
+	DiscNumber  int     `xml:"disc-number"`
+	DiscTotal   int     `xml:"disc-total"`
+	Duration    int     `xml:"duration"` // seconds
+	BitRate     int     `xml:"bitrate"`  // kbps
+	Date        int     `xml:"date"`     // days since 1 January, year 1 (Julian day, as in GDate)
+	Location    string  `xml:"location"`
+	MTime       int64   `xml:"mtime"`
+	FirstSeen   int64   `xml:"first-seen"`
+	Rating      float64 `xml:"rating"` // 0-5
+	PlayCount   int     `xml:"play-count"`
+	LastPlayed  int64   `xml:"last-played"`
+	Hidden      int     `xml:"hidden"`
+}
+
+// ReadFrom creates a Tchaik Library implementation from a Rhythmbox database (rhythmdb.xml)
+// passed through an io.Reader.  Only songs are included (podcasts, radio stations and
+// hidden entries are ignored).  Play counts and ratings are available from the Library Stats
+// method.
+func ReadFrom(r io.Reader) (*migrate.Library, error) {
+	db := struct {
+		XMLName xml.Name `xml:"rhythmdb"`
+		Entries []entry  `xml:"entry"`
+	}{}
+	if err := xml.NewDecoder(r).Decode(&db); err != nil {
+		return nil, fmt.Errorf("error parsing Rhythmbox database: %v", err)
+	}
+
+	var tracks []*migrate.Track
+	for _, e := range db.Entries {
+		if e.Type != "song" || e.Hidden != 0 || e.Location == "" {
+			continue
+		}
+		tracks = append(tracks, e.track())
+	}
+	return migrate.NewLibrary(tracks), nil
+}
+
+func (e entry) track() *migrate.Track {
+	return &migrate.Track{
+		Name:        e.Title,
+		Album:       e.Album,
+		AlbumArtist: e.AlbumArtist,
+		Artist:      e.Artist,
+		Composer:    e.Composer,
+		Genre:       e.Genre,
+		Location:    migrate.FileLocation(e.Location),
+
+		TotalTime:   e.Duration * 1000,
+		Year:        julianYear(e.Date),
+		DiscNumber:  e.DiscNumber,
+		DiscCount:   e.DiscTotal,
+		TrackNumber: e.TrackNumber,
+		TrackCount:  e.TrackTotal,
+		BitRate:     e.BitRate,
+
+		DateAdded:    migrate.UnixTime(e.FirstSeen),
+		DateModified: migrate.UnixTime(e.MTime),
+
+		Stats: migrate.Stats{
+			PlayCount:  e.PlayCount,
+			LastPlayed: migrate.UnixTime(e.LastPlayed),
+			Rating:     rating.Value(e.Rating + 0.5),
+		},
+	}
+}
+
+// julianYear returns the year of the Julian day n (days since 1 January, year 1), or zero if
+// n is not positive.
+func julianYear(n int) int {
+	if n <= 0 {
+		return 0
+	}
+	return time.Date(1, time.January, 1, 0, 0, 0, 0, time.UTC).AddDate(0, 0, n-1).Year()
+}
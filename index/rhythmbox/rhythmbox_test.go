@@ -0,0 +1,109 @@
+// Copyright 2015, David Howden
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package rhythmbox
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/amiforus/tchaik/index/rating"
+)
+
+const testDB = `<?xml version="1.0" standalone="yes"?>
+<rhythmdb version="2.0">
+  <entry type="song">
+    <title>So What</title>
+    <genre>Jazz</genre>
+    <artist>Miles Davis</artist>
+    <album>Kind of Blue</album>
+    <track-number>1</track-number>
+    <disc-number>1</disc-number>
+    <duration>562</duration>
+    <bitrate>256</bitrate>
+    <date>715150</date>
+    <location>file:///music/Miles%20Davis/Kind%20of%20Blue/01%20So%20What.mp3</location>
+    <mtime>1420070400</mtime>
+    <first-seen>1420070400</first-seen>
+    <rating>4</rating>
+    <play-count>3</play-count>
+    <last-played>1430000000</last-played>
+  </entry>
+  <entry type="song">
+    <title>Missing</title>
+    <location>file:///music/missing.mp3</location>
+    <hidden>1</hidden>
+  </entry>
+  <entry type="iradio">
+    <title>Radio</title>
+    <location>http://example.com/stream</location>
+  </entry>
+</rhythmdb>`
+
+func TestReadFrom(t *testing.T) {
+	l, err := ReadFrom(strings.NewReader(testDB))
+	if err != nil {
+		t.Fatalf("unexpected error from ReadFrom(): %v", err)
+	}
+
+	tracks := l.Tracks()
+	if len(tracks) != 1 {
+		t.Fatalf("expected 1 track, got %d", len(tracks))
+	}
+	tr := tracks[0]
+
+	for _, tt := range []struct {
+		field, expected string
+	}{
+		{"Name", "So What"},
+		{"Album", "Kind of Blue"},
+		{"Artist", "Miles Davis"},
+		{"Location", "/music/Miles Davis/Kind of Blue/01 So What.mp3"},
+		{"Kind", "MPEG audio file"},
+	} {
+		if got := tr.GetString(tt.field); got != tt.expected {
+			t.Errorf("GetString(%q) = %q, expected %q", tt.field, got, tt.expected)
+		}
+	}
+
+	for _, tt := range []struct {
+		field    string
+		expected int
+	}{
+		{"TotalTime", 562000},
+		{"Year", 1959},
+		{"TrackNumber", 1},
+		{"BitRate", 256},
+	} {
+		if got := tr.GetInt(tt.field); got != tt.expected {
+			t.Errorf("GetInt(%q) = %d, expected %d", tt.field, got, tt.expected)
+		}
+	}
+
+	s, ok := l.Stats(tr.GetString("ID"))
+	if !ok {
+		t.Fatalf("expected stats for track")
+	}
+	if s.PlayCount != 3 || s.Rating != rating.Value(4) || !s.LastPlayed.Equal(time.Unix(1430000000, 0)) {
+		t.Errorf("Stats() = %#v", s)
+	}
+}
+
+func TestJulianYear(t *testing.T) {
+	tests := []struct {
+		in, out int
+	}{
+		{0, 0},
+		{1, 1},
+		{715150, 1959},
+		{735600, 2015},
+	}
+
+	for ii, tt := range tests {
+		if got := julianYear(tt.in); got != tt.out {
+			t.Errorf("[%d] julianYear(%d) = %d, expected %d", ii, tt.in, got, tt.out)
+		}
+	}
+}
@@ -0,0 +1,82 @@
+// Copyright 2015, David Howden
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package index
+
+import "github.com/amiforus/tchaik/index/attr"
+
+// CollectAlbums creates the "Root" collection of the library: tracks grouped into albums using
+// the rule g (see ByAlbum), with keys ordered by album name using the Collation.
+func CollectAlbums(l Library, g AlbumGrouping, c *Collation) Collection {
+	root := Collect(l, ByAlbum(g))
+	c.SortKeys(root)
+	return root
+}
+
+// RootCollection is a wrapper around a root collection which applies transforms to
+// each of the leaf groups (albums) when they are fetched.  Paths to tracks (i.e. in
+// playlists) are relative to the transformed groups.  RootCollection is safe to use
+// concurrently.
+type RootCollection struct {
+	Collection
+
+	// SplitRules are the rules for splitting lists of artists and composers (see
+	// SplitAliasList), nil for the defaults.
+	SplitRules *SplitRules
+
+	// Cache keeps the transformed groups so that they aren't rebuilt each time they are
+	// fetched, nil to not cache them.  The Collection must not change while it is in use
+	// (i.e. a new Cache should be used when the library is reloaded).
+	Cache *GroupCache
+
+	path Path // path of the collection from the root
+}
+
+// Get implements Collection.
+func (r *RootCollection) Get(k Key) Group {
+	p := make(Path, len(r.path)+1)
+	copy(p, r.path)
+	p[len(r.path)] = k
+
+	if g, ok := r.Cache.get(p); ok {
+		return g
+	}
+
+	g := r.Collection.Get(k)
+	if g == nil {
+		return g
+	}
+	if c, ok := g.(Collection); ok {
+		return &RootCollection{
+			Collection: c,
+			SplitRules: r.SplitRules,
+			Cache:      r.Cache,
+			path:       p,
+		}
+	}
+
+	// The tracks of g are shared, and so must not be sorted in place.
+	g = &subGrpTrks{
+		Group:  g,
+		tracks: Sorted(g.Tracks(), MultiSort(SortByString("Kind"), SortByInt("DiscNumber"), SortByInt("TrackNumber"))),
+	}
+	g = Transform(g, SplitAliasList(r.SplitRules, nil, "Artist", "AlbumArtist", "Composer"))
+	g = Transform(g, TrimTrackNumPrefix)
+	c := Collect(g, ByCatalogue("Name"))
+	g = SubTransform(c, TrimEnumPrefix)
+	g = SumGroupIntAttr("TotalTime", g)
+	commonFields := []attr.Interface{
+		attr.String("Album"),
+		attr.Strings("Artist"),
+		attr.Strings("AlbumArtist"),
+		attr.Strings("Composer"),
+		attr.String("Kind"),
+		attr.Int("Year"),
+		attr.Int("BitRate"),
+		attr.Int("DiscNumber"),
+	}
+	g = CommonGroupAttr(commonFields, g)
+	g = RemoveEmptyCollections(g)
+	return r.Cache.add(p, g)
+}
@@ -7,6 +7,7 @@ package index
 import (
 	"fmt"
 	"strings"
+	"sync"
 	"unicode"
 
 	"golang.org/x/text/transform"
@@ -17,7 +18,13 @@ func isMn(r rune) bool {
 	return unicode.Is(unicode.Mn, r) // Mn: nonspacing marks
 }
 
-var transformer = transform.Chain(norm.NFD, transform.RemoveFunc(isMn), norm.NFC)
+// transformers is a pool of transform.Transformers which remove accents: a Transformer can't
+// be used concurrently.
+var transformers = sync.Pool{
+	New: func() interface{} {
+		return transform.Chain(norm.NFD, transform.RemoveFunc(isMn), norm.NFC)
+	},
+}
 
 func removeNonAlphaNumeric(s string) string {
 	in := []rune(s)
@@ -34,10 +41,19 @@ func removeNonAlphaNumeric(s string) string {
 			i++
 		}
 	}
-	result, _, _ := transform.String(transformer, string(res[:i]))
+	t := transformers.Get().(transform.Transformer)
+	result, _, _ := transform.String(t, string(res[:i]))
+	transformers.Put(t)
 	return result
 }
 
+// Normalise returns s in lower case with accents and punctuation removed and whitespace
+// collapsed, so that similar strings (i.e. "Symphony No. 5 - I. Allegro" and "symphony no 5
+// i allegro") are equal.
+func Normalise(s string) string {
+	return strings.Join(strings.Fields(removeNonAlphaNumeric(s)), " ")
+}
+
 // Searcher is an interface which defines the Search method.
 type Searcher interface {
 	// Search uses the given string to filter a list of paths.
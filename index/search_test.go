@@ -136,6 +136,24 @@ func TestRemoveNonAlphaNumeric(t *testing.T) {
 	}
 }
 
+func TestNormalise(t *testing.T) {
+	tests := []struct {
+		in, out string
+	}{
+		{"", ""},
+		{"  Dvorák ", "dvorak"},
+		{"Symphony No. 5 - I. Allegro", "symphony no 5 i allegro"},
+		{"symphony no 5  i allegro", "symphony no 5 i allegro"},
+	}
+
+	for ii, tt := range tests {
+		got := Normalise(tt.in)
+		if got != tt.out {
+			t.Errorf("[%d] Normalise(%#v) = %#v, expected %#v", ii, tt.in, got, tt.out)
+		}
+	}
+}
+
 func TestWordIndex(t *testing.T) {
 	tests := []struct {
 		in    map[string][]Path
@@ -24,6 +24,16 @@ func Sort(tracks []Track, f LessFn) {
 	sort.Sort(o)
 }
 
+// Sorted returns a copy of the slice of tracks sorted using the given LessFn, keeping the
+// order of equal tracks.  Unlike Sort, the slice isn't changed, so it is safe to use on
+// tracks which are shared (i.e. between concurrent requests).
+func Sorted(tracks []Track, f LessFn) []Track {
+	result := make([]Track, len(tracks))
+	copy(result, tracks)
+	sort.Stable(&trackSlice{f, result})
+	return result
+}
+
 // SortByString returns a LessFn which orders Tracks using the GetString Attr on the given
 // field.
 func SortByString(field string) LessFn {
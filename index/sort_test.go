@@ -53,3 +53,21 @@ func TestMultiSort(t *testing.T) {
 		t.Errorf("Sort(...) = %v, expected %v", tracks, expectedTracks)
 	}
 }
+
+func TestSorted(t *testing.T) {
+	tracks := []Track{
+		testTrack{Name: "B", Album: "X"},
+		testTrack{Name: "A", Album: "X"},
+		testTrack{Name: "A", Album: "Y"},
+	}
+	original := append([]Track(nil), tracks...)
+
+	got := Sorted(tracks, SortByString("Name"))
+	expected := []Track{tracks[1], tracks[2], tracks[0]}
+	if !reflect.DeepEqual(got, expected) {
+		t.Errorf("Sorted(...) = %v, expected %v", got, expected)
+	}
+	if !reflect.DeepEqual(tracks, original) {
+		t.Errorf("Sorted(...) changed tracks to %v, expected %v", tracks, original)
+	}
+}
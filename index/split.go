@@ -4,7 +4,14 @@
 
 package index
 
-import "strings"
+import (
+	"encoding/json"
+	"io"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
 
 type subGrpTrks struct {
 	Group
@@ -44,16 +51,177 @@ func splitMultiple(x string, s []string) []string {
 	return res
 }
 
-// ListSepeartors is the list of strings used to separate String fields into 'Strings' fields.
-var ListSeparators = []string{"/", ",", ";", ":", "&", " and ", " - ", " And "}
+// ListSepeartors is the list of strings used to separate String fields into 'Strings' fields
+// when no other separators are set (see SplitRules).
+var ListSeparators = []string{"/", ",", ";", ":", "&", " and ", " - ", " And ",
+	" feat. ", " Feat. ", " ft. ", " Ft. ", " featuring ", " Featuring ", " vs. ", " Vs. ", " vs ", " with ", " With "}
+
+// multiValueFields are the fields which can have multiple values (from multi-valued tags) in
+// 'Strings' fields.
+var multiValueFields = map[string]bool{
+	"Artist":      true,
+	"AlbumArtist": true,
+	"Composer":    true,
+}
+
+// SplitRules are the rules for splitting lists of names in 'String' fields (i.e. "Artist A
+// feat. Artist B") into 'Strings' fields.  A nil *SplitRules splits all fields by
+// ListSeparators.
+type SplitRules struct {
+	// Separators maps fields to the strings which separate names in their values.  Fields
+	// which aren't in the map are split by ListSeparators.
+	Separators map[string][]string `json:"separators"`
+
+	// Exceptions are names which are never split (i.e. "Earth, Wind & Fire"), matched
+	// ignoring case.
+	Exceptions []string `json:"exceptions"`
+}
+
+// ReadSplitRules reads SplitRules from r, which is a JSON object of separators (keyed by field)
+// and exceptions, i.e.
+//
+//	{"separators": {"Composer": ["/", ";"]}, "exceptions": ["Earth, Wind & Fire"]}
+func ReadSplitRules(r io.Reader) (*SplitRules, error) {
+	var x SplitRules
+	if err := json.NewDecoder(r).Decode(&x); err != nil {
+		return nil, err
+	}
+	return &x, nil
+}
+
+// separators returns the separators for the field.
+func (r *SplitRules) separators(field string) []string {
+	if r != nil {
+		if s, ok := r.Separators[field]; ok {
+			return s
+		}
+	}
+	return ListSeparators
+}
+
+// isException returns true if the value is an exception (and so shouldn't be split).
+func (r *SplitRules) isException(v string) bool {
+	if r == nil {
+		return false
+	}
+	for _, e := range r.Exceptions {
+		if strings.EqualFold(strings.TrimSpace(v), e) {
+			return true
+		}
+	}
+	return false
+}
+
+// Split splits the value of the field into a list of names.  Exceptions within the value are
+// kept whole.
+func (r *SplitRules) Split(field, v string) []string {
+	seps := r.separators(field)
+	if r == nil || len(r.Exceptions) == 0 {
+		return splitMultiple(v, seps)
+	}
+
+	// Exceptions are replaced by placeholders (which don't contain separators) so that they
+	// aren't split, and then restored.
+	var found []string
+	for _, e := range r.Exceptions {
+		for {
+			i := indexName(v, e)
+			if i < 0 {
+				break
+			}
+			found = append(found, v[i:i+len(e)])
+			v = v[:i] + placeholder(len(found)-1) + v[i+len(e):]
+		}
+	}
+
+	names := splitMultiple(v, seps)
+	for i, n := range names {
+		for j, f := range found {
+			n = strings.Replace(n, placeholder(j), f, 1)
+		}
+		names[i] = n
+	}
+	return names
+}
+
+func placeholder(i int) string {
+	return "\x00" + strconv.Itoa(i) + "\x00"
+}
+
+// indexName returns the index of the first instance of the name in s (ignoring case) which
+// isn't part of a longer word, or -1 if there isn't one.
+func indexName(s, name string) int {
+	if name == "" {
+		return -1
+	}
+	for i := 0; i+len(name) <= len(s); i++ {
+		if !utf8.RuneStart(s[i]) || !strings.EqualFold(s[i:i+len(name)], name) {
+			continue
+		}
+		before, _ := utf8.DecodeLastRuneInString(s[:i])
+		after, _ := utf8.DecodeRuneInString(s[i+len(name):])
+		if i > 0 && isWordRune(before) || i+len(name) < len(s) && isWordRune(after) {
+			continue
+		}
+		return i
+	}
+	return -1
+}
+
+func isWordRune(r rune) bool {
+	return unicode.IsLetter(r) || unicode.IsDigit(r)
+}
+
+// values returns the values of the field of the track, and true if they are the values of a
+// multi-valued tag (see Track.GetStrings) rather than a single value which can be split.
+func values(t Track, field string) ([]string, bool) {
+	if multiValueFields[field] {
+		if v := t.GetStrings(field); len(v) > 1 {
+			return v, true
+		}
+	}
+	return DefaultGetStrings(t, field), false
+}
+
+// names returns the names in the field of the track, replaced by their canonical names in a:
+// the values of a multi-valued tag, or the value split by the rules.
+func (r *SplitRules) names(a *Aliases, t Track, field string) []string {
+	vs, multi := values(t, field)
+	if len(vs) == 0 {
+		return nil
+	}
+	if !multi {
+		if c, ok := a.Canonical(vs[0]); ok {
+			return []string{c}
+		}
+		if !r.isException(vs[0]) {
+			vs = r.Split(field, vs[0])
+		}
+	}
+
+	names := make([]string, len(vs))
+	for i, n := range vs {
+		names[i], _ = a.Canonical(n)
+	}
+	return names
+}
 
 // SplitList returns a transform which splits lists of names in 'String' fields of Tracks
 // into 'Strings' fields.  The String values are split by ListSeparators.
 func SplitList(fields ...string) TransformFn {
+	return SplitAliasList(nil, nil, fields...)
+}
+
+// SplitAliasList returns a transform which splits lists of names in 'String' fields of Tracks
+// into 'Strings' fields using the rules r (or ListSeparators if r is nil), and then replaces
+// names with their canonical names in a (see Aliases).  Values which are aliases as a whole
+// (i.e. "Bach, Johann Sebastian") are not split, and tracks with multi-valued tags keep their
+// values.  The 'String' fields are unchanged, so tracks keep their original names.
+func SplitAliasList(r *SplitRules, a *Aliases, fields ...string) TransformFn {
 	return func(g Group) Group {
 		return &subGrpTrks{
 			Group:  g,
-			tracks: splitNameList(fields, g.Tracks()),
+			tracks: splitNameList(r, a, fields, g.Tracks()),
 		}
 	}
 }
@@ -71,12 +239,12 @@ func (s *stringsTrack) GetStrings(k string) []string {
 	return v
 }
 
-func splitNameList(fields []string, tracks []Track) []Track {
+func splitNameList(r *SplitRules, a *Aliases, fields []string, tracks []Track) []Track {
 	result := make([]Track, len(tracks))
 	for i, t := range tracks {
 		m := make(map[string][]string)
 		for _, f := range fields {
-			m[f] = splitMultiple(t.GetString(f), ListSeparators)
+			m[f] = r.names(a, t, f)
 		}
 		result[i] = &stringsTrack{
 			Track: t,
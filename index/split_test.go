@@ -6,6 +6,7 @@ package index
 
 import (
 	"reflect"
+	"strings"
 	"testing"
 )
 
@@ -57,9 +58,81 @@ func TestSplitMultiple(t *testing.T) {
 
 func TestSplitNameList(t *testing.T) {
 	tracks := []Track{&tr}
-	out := splitNameList([]string{"Album", "Artist", "AlbumArtist"}, tracks)
+	out := splitNameList(nil, nil, []string{"Album", "Artist", "AlbumArtist"}, tracks)
 	if len(out) != 1 {
 		t.Errorf("expected at least one track in output")
 	}
 	// TODO(dhowden): Fill out this test!
 }
+
+func TestSplitRules(t *testing.T) {
+	r := &SplitRules{
+		Separators: map[string][]string{
+			"Composer": {"/", ";"},
+		},
+		Exceptions: []string{"Earth, Wind & Fire", "Simon & Garfunkel"},
+	}
+
+	tests := []struct {
+		r     *SplitRules
+		field string
+		in    string
+		out   []string
+	}{
+		{nil, "Artist", "Earth, Wind & Fire", []string{"Earth", "Wind", "Fire"}},
+		{r, "Artist", "Earth, Wind & Fire", []string{"Earth, Wind & Fire"}},
+		{r, "Artist", "earth, wind & fire feat. The Emotions", []string{"earth, wind & fire", "The Emotions"}},
+		{r, "Artist", "Paul Simon & Simon & Garfunkel", []string{"Paul Simon", "Simon & Garfunkel"}},
+		{r, "Artist", "Simon & Garfunkels", []string{"Simon", "Garfunkels"}},
+		{r, "Artist", "Daft Punk ft. Pharrell Williams", []string{"Daft Punk", "Pharrell Williams"}},
+		{r, "Artist", "Jay-Z vs. Linkin Park", []string{"Jay-Z", "Linkin Park"}},
+		{r, "Composer", "Lennon, McCartney", []string{"Lennon, McCartney"}},
+		{r, "Composer", "Lennon/McCartney", []string{"Lennon", "McCartney"}},
+	}
+
+	for ii, tt := range tests {
+		got := tt.r.Split(tt.field, tt.in)
+		if !reflect.DeepEqual(got, tt.out) {
+			t.Errorf("[%d] Split(%#v, %#v) = %#v, expected %#v", ii, tt.field, tt.in, got, tt.out)
+		}
+	}
+}
+
+func TestReadSplitRules(t *testing.T) {
+	r, err := ReadSplitRules(strings.NewReader(`{"separators": {"Composer": ["/"]}, "exceptions": ["Earth, Wind & Fire"]}`))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	expected := &SplitRules{
+		Separators: map[string][]string{"Composer": {"/"}},
+		Exceptions: []string{"Earth, Wind & Fire"},
+	}
+	if !reflect.DeepEqual(r, expected) {
+		t.Errorf("ReadSplitRules() = %#v, expected %#v", r, expected)
+	}
+}
+
+func TestSplitAliasListMultiValued(t *testing.T) {
+	a := NewAliases(map[string][]string{
+		"Johann Sebastian Bach": {"J.S. Bach"},
+	})
+	tracks := []Track{
+		testTrack{
+			Artist:     "J.S. Bach; Glenn Gould, Yo-Yo Ma",
+			stringsMap: map[string][]string{"Artist": {"J.S. Bach", "Glenn Gould, Yo-Yo Ma"}},
+		},
+		testTrack{Artist: "Earth, Wind & Fire"},
+	}
+	r := &SplitRules{Exceptions: []string{"Earth, Wind & Fire"}}
+
+	g := SplitAliasList(r, a, "Artist")(group{tracks: tracks})
+	expected := [][]string{
+		{"Johann Sebastian Bach", "Glenn Gould, Yo-Yo Ma"},
+		{"Earth, Wind & Fire"},
+	}
+	for i, tr := range g.Tracks() {
+		if got := tr.GetStrings("Artist"); !reflect.DeepEqual(got, expected[i]) {
+			t.Errorf("[%d] GetStrings(Artist) = %#v, expected %#v", i, got, expected[i])
+		}
+	}
+}
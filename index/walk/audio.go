@@ -0,0 +1,550 @@
+package walk
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/dhowden/tag"
+)
+
+// audioInfo contains properties of the audio stream in a file.
+type audioInfo struct {
+	Duration time.Duration
+	BitRate  int // kbit/s
+}
+
+// readAudioInfo determines the duration and bit rate of the audio in r (of total length size),
+// which has the given file type.
+func readAudioInfo(r io.ReadSeeker, t tag.FileType, size int64) (audioInfo, error) {
+	if _, err := r.Seek(0, io.SeekStart); err != nil {
+		return audioInfo{}, err
+	}
+
+	switch t {
+	case tag.MP3:
+		return mp3Info(r, size)
+	case tag.FLAC:
+		return flacInfo(r, size)
+	case tag.M4A, tag.M4B, tag.M4P, tag.ALAC:
+		return mp4Info(r, size)
+	case tag.OGG:
+		return oggInfo(r, size)
+	}
+	return audioInfo{}, fmt.Errorf("unsupported file type: %v", t)
+}
+
+// ReadDuration returns the duration of the audio in r (of total length size), which has the
+// given file type.
+func ReadDuration(r io.ReadSeeker, t tag.FileType, size int64) (time.Duration, error) {
+	a, err := readAudioInfo(r, t, size)
+	return a.Duration, err
+}
+
+// averageBitRate returns the bit rate (kbit/s) of n bytes of audio with duration d.
+func averageBitRate(n int64, d time.Duration) int {
+	if d <= 0 {
+		return 0
+	}
+	return int(float64(n) * 8 / d.Seconds() / 1000)
+}
+
+// samplesDuration returns the duration of n samples at the given sample rate.
+func samplesDuration(n uint64, rate uint32) time.Duration {
+	if rate == 0 {
+		return 0
+	}
+	return time.Duration(float64(n) / float64(rate) * float64(time.Second))
+}
+
+// skipID3v2 moves r past an ID3v2 tag (if present), and returns the resulting offset.
+func skipID3v2(r io.ReadSeeker) (int64, error) {
+	b := make([]byte, 10)
+	if _, err := io.ReadFull(r, b); err != nil {
+		return 0, err
+	}
+
+	var n int64
+	if string(b[:3]) == "ID3" {
+		n = 10 + (int64(b[6]&0x7f)<<21 | int64(b[7]&0x7f)<<14 | int64(b[8]&0x7f)<<7 | int64(b[9]&0x7f))
+		if b[5]&0x10 != 0 {
+			n += 10 // footer
+		}
+	}
+	return r.Seek(n, io.SeekStart)
+}
+
+var mp3BitRates = map[byte][16]int{
+	0x13: {0, 32, 64, 96, 128, 160, 192, 224, 256, 288, 320, 352, 384, 416, 448}, // MPEG-1 layer I
+	0x12: {0, 32, 48, 56, 64, 80, 96, 112, 128, 160, 192, 224, 256, 320, 384},    // MPEG-1 layer II
+	0x11: {0, 32, 40, 48, 56, 64, 80, 96, 112, 128, 160, 192, 224, 256, 320},     // MPEG-1 layer III
+	0x23: {0, 32, 48, 56, 64, 80, 96, 112, 128, 144, 160, 176, 192, 224, 256},    // MPEG-2/2.5 layer I
+	0x22: {0, 8, 16, 24, 32, 40, 48, 56, 64, 80, 96, 112, 128, 144, 160},         // MPEG-2/2.5 layer II
+	0x21: {0, 8, 16, 24, 32, 40, 48, 56, 64, 80, 96, 112, 128, 144, 160},         // MPEG-2/2.5 layer III
+}
+
+var mp3SampleRates = map[byte][3]uint32{
+	3: {44100, 48000, 32000}, // MPEG-1
+	2: {22050, 24000, 16000}, // MPEG-2
+	0: {11025, 12000, 8000},  // MPEG-2.5
+}
+
+// mp3Frame is a parsed MPEG audio frame header.
+type mp3Frame struct {
+	version    byte // 3: MPEG-1, 2: MPEG-2, 0: MPEG-2.5
+	layer      byte // 3: layer I, 2: layer II, 1: layer III
+	bitRate    int  // kbit/s
+	sampleRate uint32
+	padding    int
+	mono       bool
+}
+
+func parseMP3Frame(b []byte) (mp3Frame, bool) {
+	if len(b) < 4 || b[0] != 0xff || b[1]&0xe0 != 0xe0 {
+		return mp3Frame{}, false
+	}
+
+	f := mp3Frame{
+		version: (b[1] >> 3) & 0x03,
+		layer:   (b[1] >> 1) & 0x03,
+		padding: int((b[2] >> 1) & 0x01),
+		mono:    b[3]>>6 == 3,
+	}
+	bri, sri := b[2]>>4, (b[2]>>2)&0x03
+	if f.version == 1 || f.layer == 0 || bri == 0 || bri == 15 || sri == 3 {
+		return mp3Frame{}, false
+	}
+
+	v := byte(0x10)
+	if f.version != 3 {
+		v = 0x20
+	}
+	f.bitRate = mp3BitRates[v|f.layer][bri]
+	f.sampleRate = mp3SampleRates[f.version][sri]
+	return f, true
+}
+
+// samples returns the number of samples in the frame.
+func (f mp3Frame) samples() int {
+	switch {
+	case f.layer == 3:
+		return 384
+	case f.layer == 1 && f.version != 3:
+		return 576
+	}
+	return 1152
+}
+
+// length returns the length of the frame in bytes.
+func (f mp3Frame) length() int {
+	if f.layer == 3 {
+		return (12*f.bitRate*1000/int(f.sampleRate) + f.padding) * 4
+	}
+	return f.samples()/8*f.bitRate*1000/int(f.sampleRate) + f.padding
+}
+
+// sideInfoLength returns the length of the side information which follows the header
+// in a layer III frame.
+func (f mp3Frame) sideInfoLength() int {
+	if f.version == 3 {
+		if f.mono {
+			return 17
+		}
+		return 32
+	}
+	if f.mono {
+		return 9
+	}
+	return 17
+}
+
+// mp3Info reads the first MPEG audio frame, using the Xing/Info or VBRI header (if present)
+// to determine the number of frames in the stream.  Otherwise assumes a constant bit rate.
+func mp3Info(r io.ReadSeeker, size int64) (audioInfo, error) {
+	start, err := skipID3v2(r)
+	if err != nil {
+		return audioInfo{}, err
+	}
+
+	buf := make([]byte, 64*1024)
+	n, err := io.ReadFull(r, buf)
+	if err != nil && err != io.ErrUnexpectedEOF {
+		return audioInfo{}, err
+	}
+	buf = buf[:n]
+
+	end := size
+	if size >= 128 {
+		if _, err := r.Seek(size-128, io.SeekStart); err == nil {
+			b := make([]byte, 3)
+			if _, err := io.ReadFull(r, b); err == nil && string(b) == "TAG" {
+				end -= 128
+			}
+		}
+	}
+
+	for i := 0; i+4 <= len(buf); i++ {
+		f, ok := parseMP3Frame(buf[i:])
+		if !ok {
+			continue
+		}
+		// Check that the next frame header is also valid to avoid false syncs.
+		if next := i + f.length(); next+4 <= len(buf) {
+			if _, ok := parseMP3Frame(buf[next:]); !ok {
+				continue
+			}
+		}
+		return mp3StreamInfo(f, buf[i:], end-(start+int64(i))), nil
+	}
+	return audioInfo{}, errors.New("could not find MPEG audio frame")
+}
+
+// mp3StreamInfo computes the audioInfo for the stream which starts with frame f (data b)
+// and has length n bytes.
+func mp3StreamInfo(f mp3Frame, b []byte, n int64) audioInfo {
+	var frames, length uint32
+	if x := 4 + f.sideInfoLength(); f.layer == 1 && len(b) >= x+16 {
+		switch string(b[x : x+4]) {
+		case "Xing", "Info":
+			flags := binary.BigEndian.Uint32(b[x+4:])
+			off := x + 8
+			if flags&0x01 != 0 {
+				frames = binary.BigEndian.Uint32(b[off:])
+				off += 4
+			}
+			if flags&0x02 != 0 && len(b) >= off+4 {
+				length = binary.BigEndian.Uint32(b[off:])
+			}
+		}
+	}
+	if len(b) >= 36+18 && string(b[36:40]) == "VBRI" {
+		length = binary.BigEndian.Uint32(b[46:])
+		frames = binary.BigEndian.Uint32(b[50:])
+	}
+
+	if frames == 0 {
+		d := time.Duration(float64(n) * 8 / float64(f.bitRate*1000) * float64(time.Second))
+		return audioInfo{
+			Duration: d,
+			BitRate:  f.bitRate,
+		}
+	}
+
+	if length == 0 {
+		length = uint32(n)
+	}
+	d := samplesDuration(uint64(frames)*uint64(f.samples()), f.sampleRate)
+	return audioInfo{
+		Duration: d,
+		BitRate:  averageBitRate(int64(length), d),
+	}
+}
+
+// flacInfo reads the STREAMINFO metadata block to determine the number of samples and the
+// sample rate.
+func flacInfo(r io.ReadSeeker, size int64) (audioInfo, error) {
+	start, err := skipID3v2(r)
+	if err != nil {
+		return audioInfo{}, err
+	}
+
+	b := make([]byte, 4)
+	if _, err := io.ReadFull(r, b); err != nil {
+		return audioInfo{}, err
+	}
+	if string(b) != "fLaC" {
+		return audioInfo{}, errors.New("expected 'fLaC' stream marker")
+	}
+	off := start + 4
+
+	var info []byte
+	for {
+		if _, err := io.ReadFull(r, b); err != nil {
+			return audioInfo{}, err
+		}
+		n := int64(b[1])<<16 | int64(b[2])<<8 | int64(b[3])
+		off += 4 + n
+
+		if b[0]&0x7f == 0 && info == nil {
+			if n < 34 {
+				return audioInfo{}, errors.New("invalid STREAMINFO block")
+			}
+			info = make([]byte, 34)
+			if _, err := io.ReadFull(r, info); err != nil {
+				return audioInfo{}, err
+			}
+		}
+		if b[0]&0x80 != 0 {
+			break
+		}
+		if _, err := r.Seek(off, io.SeekStart); err != nil {
+			return audioInfo{}, err
+		}
+	}
+
+	if info == nil {
+		return audioInfo{}, errors.New("missing STREAMINFO block")
+	}
+
+	rate := uint32(info[10])<<12 | uint32(info[11])<<4 | uint32(info[12])>>4
+	samples := uint64(info[13]&0x0f)<<32 | uint64(binary.BigEndian.Uint32(info[14:]))
+	d := samplesDuration(samples, rate)
+	return audioInfo{
+		Duration: d,
+		BitRate:  averageBitRate(size-off, d),
+	}, nil
+}
+
+// mp4Atom is the header of an MP4 atom.
+type mp4Atom struct {
+	typ        string
+	start, end int64 // offsets of the atom data
+}
+
+// readMP4Atoms reads the headers of the atoms between offsets start and end in r.
+func readMP4Atoms(r io.ReadSeeker, start, end int64) ([]mp4Atom, error) {
+	var atoms []mp4Atom
+	b := make([]byte, 8)
+	for off := start; off+8 <= end; {
+		if _, err := r.Seek(off, io.SeekStart); err != nil {
+			return nil, err
+		}
+		if _, err := io.ReadFull(r, b); err != nil {
+			return nil, err
+		}
+
+		a := mp4Atom{
+			typ:   string(b[4:]),
+			start: off + 8,
+		}
+		switch n := int64(binary.BigEndian.Uint32(b)); n {
+		case 0:
+			a.end = end
+		case 1:
+			if _, err := io.ReadFull(r, b); err != nil {
+				return nil, err
+			}
+			a.start += 8
+			a.end = off + int64(binary.BigEndian.Uint64(b))
+		default:
+			a.end = off + n
+		}
+
+		if a.end < a.start || a.end > end {
+			return nil, fmt.Errorf("invalid size for atom '%v'", a.typ)
+		}
+		atoms = append(atoms, a)
+		off = a.end
+	}
+	return atoms, nil
+}
+
+// readMP4Data reads up to n bytes of the atom data.
+func readMP4Data(r io.ReadSeeker, a mp4Atom, n int) ([]byte, error) {
+	if x := a.end - a.start; x < int64(n) {
+		n = int(x)
+	}
+	if _, err := r.Seek(a.start, io.SeekStart); err != nil {
+		return nil, err
+	}
+	b := make([]byte, n)
+	_, err := io.ReadFull(r, b)
+	return b, err
+}
+
+// mp4Duration parses the timescale and duration from an 'mvhd' or 'mdhd' atom.
+func mp4Duration(r io.ReadSeeker, a mp4Atom) (time.Duration, error) {
+	b, err := readMP4Data(r, a, 32)
+	if err != nil {
+		return 0, err
+	}
+
+	var scale uint32
+	var n uint64
+	switch {
+	case len(b) >= 20 && b[0] == 0:
+		scale = binary.BigEndian.Uint32(b[12:])
+		n = uint64(binary.BigEndian.Uint32(b[16:]))
+	case len(b) >= 32 && b[0] == 1:
+		scale = binary.BigEndian.Uint32(b[20:])
+		n = binary.BigEndian.Uint64(b[24:])
+	default:
+		return 0, fmt.Errorf("invalid '%v' atom", a.typ)
+	}
+	return samplesDuration(n, scale), nil
+}
+
+// mp4TrackDuration returns the duration of the media in the 'trak' atom, and true if the
+// media is audio.
+func mp4TrackDuration(r io.ReadSeeker, trak mp4Atom) (time.Duration, bool, error) {
+	atoms, err := readMP4Atoms(r, trak.start, trak.end)
+	if err != nil {
+		return 0, false, err
+	}
+
+	for _, a := range atoms {
+		if a.typ != "mdia" {
+			continue
+		}
+		mdia, err := readMP4Atoms(r, a.start, a.end)
+		if err != nil {
+			return 0, false, err
+		}
+
+		var d time.Duration
+		var audio bool
+		for _, x := range mdia {
+			switch x.typ {
+			case "mdhd":
+				d, err = mp4Duration(r, x)
+				if err != nil {
+					return 0, false, err
+				}
+			case "hdlr":
+				b, err := readMP4Data(r, x, 12)
+				if err != nil {
+					return 0, false, err
+				}
+				audio = len(b) == 12 && string(b[8:]) == "soun"
+			}
+		}
+		return d, audio, nil
+	}
+	return 0, false, nil
+}
+
+// mp4Info uses the duration of the first audio track (from its 'mdhd' atom), or the movie
+// duration (from the 'mvhd' atom) if no audio track is found.  The bit rate is averaged over
+// the 'mdat' atom.
+func mp4Info(r io.ReadSeeker, size int64) (audioInfo, error) {
+	atoms, err := readMP4Atoms(r, 0, size)
+	if err != nil {
+		return audioInfo{}, err
+	}
+
+	var d time.Duration
+	var found bool
+	mdat := size
+	for _, a := range atoms {
+		switch a.typ {
+		case "mdat":
+			mdat = a.end - a.start
+
+		case "moov":
+			moov, err := readMP4Atoms(r, a.start, a.end)
+			if err != nil {
+				return audioInfo{}, err
+			}
+			for _, x := range moov {
+				switch x.typ {
+				case "mvhd":
+					if d == 0 {
+						d, err = mp4Duration(r, x)
+					}
+				case "trak":
+					var td time.Duration
+					var audio bool
+					td, audio, err = mp4TrackDuration(r, x)
+					if audio && !found {
+						d, found = td, true
+					}
+				}
+				if err != nil {
+					return audioInfo{}, err
+				}
+			}
+		}
+	}
+
+	if d == 0 {
+		return audioInfo{}, errors.New("could not find 'mvhd' or 'mdhd' atom")
+	}
+	return audioInfo{
+		Duration: d,
+		BitRate:  averageBitRate(mdat, d),
+	}, nil
+}
+
+// oggPageHeaderLen is the length of an Ogg page header (without the segment table).
+const oggPageHeaderLen = 27
+
+// oggInfo reads the Vorbis (or Opus) identification header from the first Ogg page, and the
+// granule position of the last page to determine the number of samples in the stream.
+func oggInfo(r io.ReadSeeker, size int64) (audioInfo, error) {
+	b := make([]byte, oggPageHeaderLen+255+30)
+	n, err := io.ReadFull(r, b)
+	if err != nil && err != io.ErrUnexpectedEOF {
+		return audioInfo{}, err
+	}
+	b = b[:n]
+	if len(b) < oggPageHeaderLen || string(b[:4]) != "OggS" {
+		return audioInfo{}, errors.New("expected 'OggS' page")
+	}
+
+	off := oggPageHeaderLen + int(b[26])
+	if len(b) < off+19 {
+		return audioInfo{}, errors.New("invalid identification header")
+	}
+	p := b[off:]
+
+	var rate uint32
+	var skip uint64
+	var nominal int
+	switch {
+	case len(p) >= 30 && string(p[:7]) == "\x01vorbis":
+		rate = binary.LittleEndian.Uint32(p[12:])
+		nominal = int(int32(binary.LittleEndian.Uint32(p[20:])))
+	case string(p[:8]) == "OpusHead":
+		rate = 48000 // granule positions are always at 48kHz
+		skip = uint64(binary.LittleEndian.Uint16(p[10:]))
+	default:
+		return audioInfo{}, errors.New("unsupported Ogg stream (expected Vorbis or Opus)")
+	}
+
+	granule, err := oggLastGranule(r, size)
+	if err != nil {
+		return audioInfo{}, err
+	}
+	if granule > skip {
+		granule -= skip
+	}
+
+	d := samplesDuration(granule, rate)
+	br := averageBitRate(size, d)
+	if nominal > 0 {
+		br = nominal / 1000
+	}
+	return audioInfo{
+		Duration: d,
+		BitRate:  br,
+	}, nil
+}
+
+// oggLastGranule returns the granule position of the last page in the stream.
+func oggLastGranule(r io.ReadSeeker, size int64) (uint64, error) {
+	n := int64(64 * 1024)
+	if n > size {
+		n = size
+	}
+	if _, err := r.Seek(size-n, io.SeekStart); err != nil {
+		return 0, err
+	}
+	b := make([]byte, n)
+	if _, err := io.ReadFull(r, b); err != nil {
+		return 0, err
+	}
+
+	for i := bytes.LastIndex(b, []byte("OggS")); i >= 0; i = bytes.LastIndex(b[:i], []byte("OggS")) {
+		if i+oggPageHeaderLen > len(b) {
+			continue
+		}
+		g := binary.LittleEndian.Uint64(b[i+6:])
+		if g != ^uint64(0) { // -1: no packets finish on this page
+			return g, nil
+		}
+	}
+	return 0, errors.New("could not find last Ogg page")
+}
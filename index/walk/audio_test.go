@@ -0,0 +1,150 @@
+package walk
+
+import (
+	"bytes"
+	"encoding/binary"
+	"testing"
+	"time"
+
+	"github.com/dhowden/tag"
+)
+
+// mp3Frames returns n MPEG-1 layer III frames (128kbit/s, 44.1kHz, stereo, no padding).
+func mp3Frames(n int) []byte {
+	f := make([]byte, 417)
+	copy(f, []byte{0xff, 0xfb, 0x90, 0x00})
+	return bytes.Repeat(f, n)
+}
+
+func id3v2(n int) []byte {
+	b := []byte{'I', 'D', '3', 3, 0, 0, 0, 0, byte(n >> 7), byte(n & 0x7f)}
+	return append(b, make([]byte, n)...)
+}
+
+func mp3Xing(frames uint32) []byte {
+	b := mp3Frames(1)
+	x := 4 + 32
+	copy(b[x:], "Xing")
+	binary.BigEndian.PutUint32(b[x+4:], 0x01)
+	binary.BigEndian.PutUint32(b[x+8:], frames)
+	return append(b, mp3Frames(2)...)
+}
+
+func flacStream(rate uint32, samples uint64) []byte {
+	b := []byte("fLaC")
+	b = append(b, 0x80, 0, 0, 34) // last block: STREAMINFO
+	info := make([]byte, 34)
+	info[10] = byte(rate >> 12)
+	info[11] = byte(rate >> 4)
+	info[12] = byte(rate<<4) | 0x02 // 2 channels
+	info[13] = 0xf0 | byte(samples>>32)
+	binary.BigEndian.PutUint32(info[14:], uint32(samples))
+	b = append(b, info...)
+	return append(b, make([]byte, 1000)...)
+}
+
+// atom encodes an MP4 atom with the given type and data.
+func atom(typ string, data ...[]byte) []byte {
+	b := bytes.Join(data, nil)
+	h := make([]byte, 8)
+	binary.BigEndian.PutUint32(h, uint32(8+len(b)))
+	copy(h[4:], typ)
+	return append(h, b...)
+}
+
+func mp4Header(scale, duration uint32) []byte {
+	b := make([]byte, 20)
+	binary.BigEndian.PutUint32(b[12:], scale)
+	binary.BigEndian.PutUint32(b[16:], duration)
+	return b
+}
+
+func mp4File() []byte {
+	hdlr := make([]byte, 12)
+	copy(hdlr[8:], "soun")
+	return append(
+		atom("moov",
+			atom("mvhd", mp4Header(600, 600*200)),
+			atom("trak",
+				atom("mdia",
+					atom("mdhd", mp4Header(44100, 44100*180)),
+					atom("hdlr", hdlr),
+				),
+			),
+		),
+		atom("mdat", make([]byte, 4000))...,
+	)
+}
+
+func oggPage(granule uint64, packet []byte) []byte {
+	b := make([]byte, oggPageHeaderLen, oggPageHeaderLen+1+len(packet))
+	copy(b, "OggS")
+	binary.LittleEndian.PutUint64(b[6:], granule)
+	b[26] = 1
+	b = append(b, byte(len(packet)))
+	return append(b, packet...)
+}
+
+func oggVorbis(rate uint32, nominal int32, samples uint64) []byte {
+	id := make([]byte, 30)
+	copy(id, "\x01vorbis")
+	id[11] = 2
+	binary.LittleEndian.PutUint32(id[12:], rate)
+	binary.LittleEndian.PutUint32(id[20:], uint32(nominal))
+
+	b := oggPage(0, id)
+	b = append(b, oggPage(samples/2, make([]byte, 100))...)
+	b = append(b, oggPage(samples, make([]byte, 100))...)
+	return b
+}
+
+func TestReadAudioInfo(t *testing.T) {
+	table := []struct {
+		t        tag.FileType
+		data     []byte
+		duration time.Duration
+		bitRate  int
+	}{
+		// CBR: 10 * 417 bytes at 128kbit/s
+		{tag.MP3, mp3Frames(10), 260625 * time.Microsecond, 128},
+		{tag.MP3, append(id3v2(300), mp3Frames(10)...), 260625 * time.Microsecond, 128},
+		// Xing: 1000 frames of 1152 samples at 44.1kHz
+		{tag.MP3, mp3Xing(1000), 26122448979 * time.Nanosecond, 0},
+		{tag.FLAC, flacStream(44100, 44100*60), 60 * time.Second, 0},
+		{tag.M4A, mp4File(), 180 * time.Second, 0},
+		{tag.OGG, oggVorbis(44100, 160000, 44100*30), 30 * time.Second, 160},
+	}
+
+	for ii, tt := range table {
+		got, err := readAudioInfo(bytes.NewReader(tt.data), tt.t, int64(len(tt.data)))
+		if err != nil {
+			t.Errorf("[%d] unexpected error: %v", ii, err)
+			continue
+		}
+		if got.Duration != tt.duration {
+			t.Errorf("[%d] readAudioInfo() duration = %v, expected %v", ii, got.Duration, tt.duration)
+		}
+		if tt.bitRate != 0 && got.BitRate != tt.bitRate {
+			t.Errorf("[%d] readAudioInfo() bit rate = %d, expected %d", ii, got.BitRate, tt.bitRate)
+		}
+	}
+}
+
+func TestReadAudioInfoInvalid(t *testing.T) {
+	table := []struct {
+		t    tag.FileType
+		data []byte
+	}{
+		{tag.MP3, make([]byte, 2000)},
+		{tag.FLAC, []byte("not a flac file")},
+		{tag.M4A, atom("moov")},
+		{tag.OGG, []byte("OggS")},
+	}
+
+	for ii, tt := range table {
+		got, err := readAudioInfo(bytes.NewReader(tt.data), tt.t, int64(len(tt.data)))
+		if err == nil {
+			t.Errorf("[%d] readAudioInfo() = %v, expected error", ii, got)
+		}
+	}
+}
@@ -12,7 +12,7 @@ func getCreatedTime(path string) (time.Time, error) {
 	stat := unix.Stat_t{}
 	err := unix.Lstat(path, &stat)
 	if err != nil {
-		return err
+		return time.Time{}, err
 	}
-	return time.Unix(stat.Ctim.Sec, stat.Ctim.Nsec)
+	return time.Unix(stat.Ctim.Sec, stat.Ctim.Nsec), nil
 }
@@ -0,0 +1,316 @@
+package walk
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"io"
+	"strings"
+	"unicode/utf16"
+
+	"github.com/dhowden/tag"
+)
+
+// The tag library joins the values of multi-valued tags (ID3v2.4 text frames with values
+// separated by null characters, and repeated Vorbis comment fields), so they are read here.
+
+// id3v2MultiFrames are the ID3v2.4 frames which can have multiple values, and their fields.
+var id3v2MultiFrames = map[string]string{
+	"TPE1": "Artist",
+	"TPE2": "AlbumArtist",
+	"TCOM": "Composer",
+}
+
+// vorbisMultiFields are the Vorbis comment fields which can be repeated, and their fields.
+var vorbisMultiFields = map[string]string{
+	"ARTIST":       "Artist",
+	"ALBUMARTIST":  "AlbumArtist",
+	"ALBUM ARTIST": "AlbumArtist",
+	"COMPOSER":     "Composer",
+}
+
+// maxTagSize is the largest tag (or comment block) which will be read, to guard against
+// corrupt files.
+const maxTagSize = 64 << 20
+
+// readMultiValues reads the values of multi-valued tags in r (which has the given file type),
+// and returns a map of field name (i.e. Artist) -> values for fields with more than one value.
+func readMultiValues(r io.ReadSeeker, t tag.FileType) (map[string][]string, error) {
+	if _, err := r.Seek(0, io.SeekStart); err != nil {
+		return nil, err
+	}
+
+	var m map[string][]string
+	var err error
+	switch t {
+	case tag.MP3:
+		m, err = id3v2MultiValues(r)
+	case tag.FLAC:
+		m, err = flacMultiValues(r)
+	case tag.OGG:
+		m, err = oggMultiValues(r)
+	default:
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	for k, v := range m {
+		if len(v) < 2 {
+			delete(m, k)
+		}
+	}
+	if len(m) == 0 {
+		return nil, nil
+	}
+	return m, nil
+}
+
+// syncsafe decodes a 28-bit syncsafe integer.
+func syncsafe(b []byte) int {
+	return int(b[0]&0x7f)<<21 | int(b[1]&0x7f)<<14 | int(b[2]&0x7f)<<7 | int(b[3]&0x7f)
+}
+
+// id3v2MultiValues reads the multi-valued text frames of an ID3v2.4 tag.  Earlier versions
+// don't have multi-valued frames.
+func id3v2MultiValues(r io.Reader) (map[string][]string, error) {
+	h := make([]byte, 10)
+	if _, err := io.ReadFull(r, h); err != nil {
+		return nil, err
+	}
+	if string(h[:3]) != "ID3" || h[3] != 4 {
+		return nil, nil
+	}
+	n := syncsafe(h[6:])
+	if n > maxTagSize {
+		return nil, errors.New("ID3v2 tag too large")
+	}
+	b := make([]byte, n)
+	if _, err := io.ReadFull(r, b); err != nil {
+		return nil, err
+	}
+
+	if h[5]&0x40 != 0 { // extended header
+		if len(b) < 4 || syncsafe(b) > len(b) {
+			return nil, errors.New("invalid ID3v2 extended header")
+		}
+		b = b[syncsafe(b):]
+	}
+
+	m := make(map[string][]string)
+	for len(b) >= 10 && b[0] != 0 {
+		id, size, flags := string(b[:4]), syncsafe(b[4:8]), b[9]
+		if size > len(b)-10 {
+			return nil, errors.New("invalid ID3v2 frame size")
+		}
+		data := b[10 : 10+size]
+		b = b[10+size:]
+
+		f, ok := id3v2MultiFrames[id]
+		if !ok || flags&0x0c != 0 { // compressed or encrypted
+			continue
+		}
+		if flags&0x01 != 0 { // data length indicator
+			if len(data) < 4 {
+				continue
+			}
+			data = data[4:]
+		}
+		if flags&0x02 != 0 || h[5]&0x80 != 0 { // unsynchronisation
+			data = bytes.Replace(data, []byte{0xff, 0x00}, []byte{0xff}, -1)
+		}
+		m[f] = append(m[f], id3v2TextValues(data)...)
+	}
+	return m, nil
+}
+
+// id3v2TextValues decodes the null-separated values of a text frame.
+func id3v2TextValues(b []byte) []string {
+	if len(b) < 2 {
+		return nil
+	}
+	enc, b := b[0], b[1:]
+
+	var values []string
+	add := func(s string) {
+		if s = strings.TrimSpace(s); s != "" {
+			values = append(values, s)
+		}
+	}
+
+	switch enc {
+	case 1, 2: // UTF-16 (with a byte order mark for each value), UTF-16BE
+		bigEndian := true
+		for len(b) >= 2 {
+			i := 0
+			for i+1 < len(b) && (b[i] != 0 || b[i+1] != 0) {
+				i += 2
+			}
+			v := b[:i]
+			if i+2 <= len(b) {
+				b = b[i+2:]
+			} else {
+				b = nil
+			}
+			if len(v) >= 2 && v[0] == 0xff && v[1] == 0xfe {
+				bigEndian, v = false, v[2:]
+			} else if len(v) >= 2 && v[0] == 0xfe && v[1] == 0xff {
+				bigEndian, v = true, v[2:]
+			}
+			u := make([]uint16, len(v)/2)
+			for j := range u {
+				if bigEndian {
+					u[j] = binary.BigEndian.Uint16(v[2*j:])
+				} else {
+					u[j] = binary.LittleEndian.Uint16(v[2*j:])
+				}
+			}
+			add(string(utf16.Decode(u)))
+		}
+
+	case 0: // ISO-8859-1
+		for _, v := range bytes.Split(b, []byte{0}) {
+			r := make([]rune, len(v))
+			for i, c := range v {
+				r[i] = rune(c)
+			}
+			add(string(r))
+		}
+
+	default: // UTF-8
+		for _, v := range bytes.Split(b, []byte{0}) {
+			add(string(v))
+		}
+	}
+	return values
+}
+
+// vorbisMultiValues reads the repeated fields of a Vorbis comment block (without the
+// framing of Ogg packets, see oggMultiValues).
+func vorbisMultiValues(b []byte) (map[string][]string, error) {
+	errInvalid := errors.New("invalid Vorbis comment block")
+	next := func() ([]byte, error) {
+		if len(b) < 4 {
+			return nil, errInvalid
+		}
+		n := binary.LittleEndian.Uint32(b)
+		if uint64(n) > uint64(len(b)-4) {
+			return nil, errInvalid
+		}
+		x := b[4 : 4+n]
+		b = b[4+n:]
+		return x, nil
+	}
+
+	if _, err := next(); err != nil { // vendor
+		return nil, err
+	}
+	if len(b) < 4 {
+		return nil, errInvalid
+	}
+	count := binary.LittleEndian.Uint32(b)
+	b = b[4:]
+
+	m := make(map[string][]string)
+	for i := uint32(0); i < count; i++ {
+		c, err := next()
+		if err != nil {
+			return nil, err
+		}
+		kv := strings.SplitN(string(c), "=", 2)
+		if len(kv) != 2 {
+			continue
+		}
+		if f, ok := vorbisMultiFields[strings.ToUpper(kv[0])]; ok {
+			if v := strings.TrimSpace(kv[1]); v != "" {
+				m[f] = append(m[f], v)
+			}
+		}
+	}
+	return m, nil
+}
+
+// flacMultiValues reads the repeated fields of the VORBIS_COMMENT block of a FLAC file.
+func flacMultiValues(r io.ReadSeeker) (map[string][]string, error) {
+	start, err := skipID3v2(r)
+	if err != nil {
+		return nil, err
+	}
+
+	b := make([]byte, 4)
+	if _, err := io.ReadFull(r, b); err != nil {
+		return nil, err
+	}
+	if string(b) != "fLaC" {
+		return nil, errors.New("expected 'fLaC' stream marker")
+	}
+	off := start + 4
+
+	for {
+		if _, err := io.ReadFull(r, b); err != nil {
+			return nil, err
+		}
+		n := int64(b[1])<<16 | int64(b[2])<<8 | int64(b[3])
+		off += 4 + n
+
+		if b[0]&0x7f == 4 {
+			c := make([]byte, n)
+			if _, err := io.ReadFull(r, c); err != nil {
+				return nil, err
+			}
+			return vorbisMultiValues(c)
+		}
+		if b[0]&0x80 != 0 {
+			return nil, nil
+		}
+		if _, err := r.Seek(off, io.SeekStart); err != nil {
+			return nil, err
+		}
+	}
+}
+
+// oggMultiValues reads the repeated fields of the comment header of an Ogg Vorbis or Opus
+// stream, which is the second packet of the stream.
+func oggMultiValues(r io.Reader) (map[string][]string, error) {
+	var packets [][]byte
+	var p []byte
+	h := make([]byte, oggPageHeaderLen)
+	for len(packets) < 2 {
+		if _, err := io.ReadFull(r, h); err != nil {
+			return nil, err
+		}
+		if string(h[:4]) != "OggS" {
+			return nil, errors.New("expected 'OggS' page")
+		}
+		segments := make([]byte, h[26])
+		if _, err := io.ReadFull(r, segments); err != nil {
+			return nil, err
+		}
+		for _, n := range segments {
+			s := make([]byte, n)
+			if _, err := io.ReadFull(r, s); err != nil {
+				return nil, err
+			}
+			p = append(p, s...)
+			if len(p) > maxTagSize {
+				return nil, errors.New("Ogg comment header too large")
+			}
+			if n < 255 {
+				packets = append(packets, p)
+				p = nil
+			}
+		}
+	}
+
+	c := packets[1]
+	switch {
+	case bytes.HasPrefix(c, []byte("\x03vorbis")):
+		c = c[7:]
+	case bytes.HasPrefix(c, []byte("OpusTags")):
+		c = c[8:]
+	default:
+		return nil, errors.New("unsupported Ogg stream (expected Vorbis or Opus)")
+	}
+	return vorbisMultiValues(c)
+}
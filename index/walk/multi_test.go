@@ -0,0 +1,106 @@
+package walk
+
+import (
+	"bytes"
+	"encoding/binary"
+	"reflect"
+	"testing"
+
+	"github.com/dhowden/tag"
+)
+
+// id3v24 returns an ID3v2.4 tag containing the text frames.
+func id3v24(frames ...[]byte) []byte {
+	b := bytes.Join(frames, nil)
+	n := len(b)
+	h := []byte{'I', 'D', '3', 4, 0, 0, byte(n >> 21 & 0x7f), byte(n >> 14 & 0x7f), byte(n >> 7 & 0x7f), byte(n & 0x7f)}
+	return append(h, b...)
+}
+
+func id3v24Frame(id string, enc byte, text []byte) []byte {
+	n := len(text) + 1
+	b := []byte(id)
+	b = append(b, byte(n>>21&0x7f), byte(n>>14&0x7f), byte(n>>7&0x7f), byte(n&0x7f), 0, 0, enc)
+	return append(b, text...)
+}
+
+// vorbisComments returns a Vorbis comment block containing the comments.
+func vorbisComments(comments ...string) []byte {
+	var b []byte
+	put := func(s string) {
+		n := make([]byte, 4)
+		binary.LittleEndian.PutUint32(n, uint32(len(s)))
+		b = append(b, n...)
+		b = append(b, s...)
+	}
+	put("vendor")
+	n := make([]byte, 4)
+	binary.LittleEndian.PutUint32(n, uint32(len(comments)))
+	b = append(b, n...)
+	for _, c := range comments {
+		put(c)
+	}
+	return b
+}
+
+func TestReadMultiValues(t *testing.T) {
+	comments := vorbisComments("TITLE=Title", "ARTIST=Artist A", "artist=Artist B", "COMPOSER=Composer")
+
+	flac := []byte("fLaC")
+	flac = append(flac, 0, 0, 0, 34) // STREAMINFO
+	flac = append(flac, make([]byte, 34)...)
+	flac = append(flac, 0x84, byte(len(comments)>>16), byte(len(comments)>>8), byte(len(comments)))
+	flac = append(flac, comments...)
+
+	ogg := oggPage(0, append([]byte("\x01vorbis"), make([]byte, 23)...))
+	ogg = append(ogg, oggPage(0, append([]byte("\x03vorbis"), comments...))...)
+
+	table := []struct {
+		t        tag.FileType
+		data     []byte
+		expected map[string][]string
+	}{
+		{
+			tag.MP3,
+			append(id3v24(
+				id3v24Frame("TIT2", 3, []byte("Title")),
+				id3v24Frame("TPE1", 3, []byte("Artist A\x00Artist B")),
+				id3v24Frame("TCOM", 0, []byte("Composer")),
+				id3v24Frame("TPE2", 1, []byte{0xff, 0xfe, 'A', 0, 0, 0, 0xff, 0xfe, 'B', 0}),
+			), mp3Frames(2)...),
+			map[string][]string{
+				"Artist":      {"Artist A", "Artist B"},
+				"AlbumArtist": {"A", "B"},
+			},
+		},
+		{tag.MP3, append(id3v2(300), mp3Frames(2)...), nil},
+		{tag.FLAC, flac, map[string][]string{"Artist": {"Artist A", "Artist B"}}},
+		{tag.OGG, ogg, map[string][]string{"Artist": {"Artist A", "Artist B"}}},
+		{tag.M4A, mp4File(), nil},
+	}
+
+	for ii, tt := range table {
+		got, err := readMultiValues(bytes.NewReader(tt.data), tt.t)
+		if err != nil {
+			t.Errorf("[%d] unexpected error: %v", ii, err)
+			continue
+		}
+		if !reflect.DeepEqual(got, tt.expected) {
+			t.Errorf("[%d] readMultiValues() = %#v, expected %#v", ii, got, tt.expected)
+		}
+	}
+}
+
+func TestTrackMultiValues(t *testing.T) {
+	m := &track{
+		Metadata: rawMetadata{},
+		Multi:    map[string][]string{"Artist": {"Artist A", "Artist B"}},
+	}
+
+	if got, expected := m.GetString("Artist"), "Artist A; Artist B"; got != expected {
+		t.Errorf("GetString(\"Artist\") = %q, expected %q", got, expected)
+	}
+	if got, expected := m.GetStrings("Artist"), []string{"Artist A", "Artist B"}; !reflect.DeepEqual(got, expected) {
+		t.Errorf("GetStrings(\"Artist\") = %#v, expected %#v", got, expected)
+	}
+}
@@ -0,0 +1,113 @@
+package walk
+
+import (
+	"math"
+	"strconv"
+	"strings"
+
+	"github.com/dhowden/tag"
+	"github.com/dhowden/tag/mbz"
+)
+
+// Names of the raw tags which hold optional track attributes.  Each list contains ID3v2 frame
+// names, ID3v2 user-defined text (TXXX) descriptions, Vorbis comment fields and MP4 atom
+// names: as Vorbis comment fields are lower-cased (and MP4 freeform names often are), names
+// are also tried in lower case.
+var rawTagNames = map[string][]string{
+	"SortName":        {"TSOT", "TITLESORT", "sonm"},
+	"SortAlbum":       {"TSOA", "ALBUMSORT", "soal"},
+	"SortAlbumArtist": {"TSO2", "ALBUMARTISTSORT", "soaa"},
+	"SortArtist":      {"TSOP", "ARTISTSORT", "soar"},
+	"SortComposer":    {"TSOC", "COMPOSERSORT", "soco"},
+	"Label":           {"TPUB", "LABEL", "ORGANIZATION", "PUBLISHER"},
+	"CatalogueNumber": {"CATALOGNUMBER"},
+	"ISRC":            {"TSRC", "ISRC"},
+	"BPM":             {"TBPM", "BPM", "tmpo"},
+	"Compilation":     {"TCMP", "COMPILATION", "cpil"},
+	"TrackGain":       {"REPLAYGAIN_TRACK_GAIN"},
+	"TrackPeak":       {"REPLAYGAIN_TRACK_PEAK"},
+	"AlbumGain":       {"REPLAYGAIN_ALBUM_GAIN"},
+	"AlbumPeak":       {"REPLAYGAIN_ALBUM_PEAK"},
+}
+
+// MusicBrainz tags (see github.com/dhowden/tag/mbz) for the MusicBrainz ID attributes.
+var mbzTagNames = map[string]string{
+	"MusicBrainzTrackID":       mbz.Recording,
+	"MusicBrainzAlbumID":       mbz.Album,
+	"MusicBrainzArtistID":      mbz.Artist,
+	"MusicBrainzAlbumArtistID": mbz.AlbumArtist,
+}
+
+// rawValue returns the string representation of the raw tag value v.
+func rawValue(v interface{}) string {
+	switch v := v.(type) {
+	case string:
+		return strings.TrimSpace(v)
+	case int:
+		return strconv.Itoa(v)
+	case *tag.Comm:
+		return strings.TrimSpace(v.Text)
+	}
+	return ""
+}
+
+// rawString returns the value of the first of the raw tags names which is set in m.
+func rawString(m tag.Metadata, names []string) string {
+	raw := m.Raw()
+	for _, n := range names {
+		if v := rawValue(raw[n]); v != "" {
+			return v
+		}
+		if v := rawValue(raw[strings.ToLower(n)]); v != "" {
+			return v
+		}
+	}
+
+	// ID3v2 user-defined text frames are keyed by frame name, their name is the description.
+	for k, v := range raw {
+		c, ok := v.(*tag.Comm)
+		if !ok || !strings.HasPrefix(k, "TXX") {
+			continue
+		}
+		for _, n := range names {
+			if strings.EqualFold(c.Description, n) {
+				if v := rawValue(c); v != "" {
+					return v
+				}
+			}
+		}
+	}
+	return ""
+}
+
+// rawInt returns the integer value of the first of the raw tags names which is set in m.
+func rawInt(m tag.Metadata, names []string) int {
+	return parseInt(rawString(m, names))
+}
+
+// parseInt parses the leading integer in s (i.e. "120" or "3/12"), returning 0 if there
+// isn't one.
+func parseInt(s string) int {
+	i := strings.IndexFunc(s, func(r rune) bool { return r < '0' || r > '9' })
+	if i == 0 {
+		return 0
+	}
+	if i > 0 {
+		s = s[:i]
+	}
+	n, _ := strconv.Atoi(s)
+	return n
+}
+
+// parseScaled parses the leading decimal number in s (i.e. "-6.54 dB" or "0.988"), returning
+// it multiplied by scale and rounded to the nearest integer (or 0 if there isn't one).
+func parseScaled(s string, scale float64) int {
+	if f := strings.Fields(s); len(f) > 0 {
+		s = f[0]
+	}
+	x, err := strconv.ParseFloat(s, 64)
+	if err != nil {
+		return 0
+	}
+	return int(math.Floor(x*scale + 0.5))
+}
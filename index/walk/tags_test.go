@@ -0,0 +1,124 @@
+package walk
+
+import (
+	"testing"
+
+	"github.com/dhowden/tag"
+)
+
+// rawMetadata is a tag.Metadata with the given raw tags.
+type rawMetadata struct {
+	tag.Metadata
+	format tag.Format
+	raw    map[string]interface{}
+}
+
+func (m rawMetadata) Format() tag.Format          { return m.format }
+func (m rawMetadata) Raw() map[string]interface{} { return m.raw }
+
+func TestRawString(t *testing.T) {
+	tests := []struct {
+		raw      map[string]interface{}
+		name     string
+		expected string
+	}{
+		{map[string]interface{}{"TSOP": "Beatles, The"}, "SortArtist", "Beatles, The"},
+		{map[string]interface{}{"artistsort": "Beatles, The"}, "SortArtist", "Beatles, The"},
+		{map[string]interface{}{"soar": "Beatles, The"}, "SortArtist", "Beatles, The"},
+		{map[string]interface{}{"TPUB": "", "organization": "Blue Note"}, "Label", "Blue Note"},
+		{map[string]interface{}{"TXXX": &tag.Comm{Description: "CATALOGNUMBER", Text: "BST 84003"}}, "CatalogueNumber", "BST 84003"},
+		{map[string]interface{}{"TXXX": &tag.Comm{Description: "replaygain_track_gain", Text: "-6.54 dB"}}, "TrackGain", "-6.54 dB"},
+		{map[string]interface{}{"TXXX": &tag.Comm{Description: "OTHER", Text: "x"}}, "CatalogueNumber", ""},
+		{map[string]interface{}{"tmpo": 120}, "BPM", "120"},
+		{map[string]interface{}{}, "ISRC", ""},
+	}
+
+	for ii, tt := range tests {
+		got := rawString(rawMetadata{raw: tt.raw}, rawTagNames[tt.name])
+		if got != tt.expected {
+			t.Errorf("[%d] rawString(%v) = %q, expected %q", ii, tt.name, got, tt.expected)
+		}
+	}
+}
+
+func TestParseInt(t *testing.T) {
+	tests := []struct {
+		in  string
+		out int
+	}{
+		{"", 0},
+		{"120", 120},
+		{"120.5", 120},
+		{"1/2", 1},
+		{"x", 0},
+	}
+
+	for ii, tt := range tests {
+		if got := parseInt(tt.in); got != tt.out {
+			t.Errorf("[%d] parseInt(%q) = %d, expected %d", ii, tt.in, got, tt.out)
+		}
+	}
+}
+
+func TestParseScaled(t *testing.T) {
+	tests := []struct {
+		in    string
+		scale float64
+		out   int
+	}{
+		{"", 100, 0},
+		{"-6.54 dB", 100, -654},
+		{"+1.20 dB", 100, 120},
+		{"0.988312", 1e6, 988312},
+		{"dB", 100, 0},
+	}
+
+	for ii, tt := range tests {
+		if got := parseScaled(tt.in, tt.scale); got != tt.out {
+			t.Errorf("[%d] parseScaled(%q, %v) = %d, expected %d", ii, tt.in, tt.scale, got, tt.out)
+		}
+	}
+}
+
+func TestTrackOptionalFields(t *testing.T) {
+	m := &track{
+		Metadata: rawMetadata{
+			format: tag.VORBIS,
+			raw: map[string]interface{}{
+				"albumartistsort":       "Davis, Miles",
+				"compilation":           "1",
+				"bpm":                   "136",
+				"replaygain_album_gain": "-3.10 dB",
+				"replaygain_album_peak": "1.000000",
+				"musicbrainz_albumid":   "b8a5f0e4-6b32-4a4e-8bb5-6d0b1f1a0c2e",
+			},
+		},
+	}
+
+	for _, tt := range []struct {
+		name, expected string
+	}{
+		{"SortAlbumArtist", "Davis, Miles"},
+		{"MusicBrainzAlbumID", "b8a5f0e4-6b32-4a4e-8bb5-6d0b1f1a0c2e"},
+		{"ISRC", ""},
+	} {
+		if got := m.GetString(tt.name); got != tt.expected {
+			t.Errorf("GetString(%q) = %q, expected %q", tt.name, got, tt.expected)
+		}
+	}
+
+	for _, tt := range []struct {
+		name     string
+		expected int
+	}{
+		{"Compilation", 1},
+		{"BPM", 136},
+		{"AlbumGain", -310},
+		{"AlbumPeak", 1000000},
+		{"TrackGain", 0},
+	} {
+		if got := m.GetInt(tt.name); got != tt.expected {
+			t.Errorf("GetInt(%q) = %d, expected %d", tt.name, got, tt.expected)
+		}
+	}
+}
@@ -0,0 +1,119 @@
+package walk
+
+import (
+	"os"
+	"sort"
+	"time"
+
+	"github.com/amiforus/tchaik/index"
+)
+
+// Changes is a summary of the differences between an existing index.Library and the
+// directory tree it was updated from.  Each list contains track locations, and is sorted.
+type Changes struct {
+	Added   []string
+	Updated []string
+	Removed []string
+
+	// Failed are the new or changed files which could not be read.  The existing tracks of
+	// changed files are kept.
+	Failed []string
+}
+
+// Update constructs an index.Library by walking through the directory tree under the given
+// path, using the existing library l as the source of metadata for any files which have not
+// changed.  A file is considered unchanged if there is a track in l with the same Location, and
+// its DateModified and Size match the modification time and size of the file (tracks without
+// a Size, i.e. from libraries written by older versions, are compared by DateModified only).
+// All other files are read as in NewLibrary, and tracks in l whose files are no longer present
+// are dropped.  Any errors are logged to stdout (TODO: fix this!)
+func Update(l index.Library, path string) (index.Library, Changes) {
+	existing := make(map[string]index.Track)
+	for _, t := range l.Tracks() {
+		existing[t.GetString("Location")] = t
+	}
+
+	unchanged := make(map[string]index.Track)
+	var read []string // files sent to be read
+	changed := make(chan string)
+	go func() {
+		for p := range validFiles(walk(path)) {
+			if t, ok := existing[p]; ok {
+				info, err := os.Stat(p)
+				if err == nil && info.ModTime().Equal(t.GetTime("DateModified")) && sameSize(info.Size(), t) {
+					unchanged[p] = t
+					continue
+				}
+			}
+			read = append(read, p)
+			changed <- p
+		}
+		close(changed)
+	}()
+
+	var c Changes
+	tracks := make(map[string]index.Track)
+	processed := processFiles(changed)
+	for _, p := range read {
+		t, ok := processed[p]
+		old, exists := existing[p]
+		if !ok {
+			c.Failed = append(c.Failed, p)
+			if exists {
+				tracks[p] = old
+			}
+			continue
+		}
+		if !exists {
+			c.Added = append(c.Added, p)
+			tracks[p] = t
+			continue
+		}
+		c.Updated = append(c.Updated, p)
+		tracks[p] = &updatedTrack{
+			Track:     t,
+			dateAdded: old.GetTime("DateAdded"),
+		}
+	}
+
+	for p, t := range unchanged {
+		tracks[p] = t
+	}
+
+	for p := range existing {
+		if _, ok := tracks[p]; !ok {
+			c.Removed = append(c.Removed, p)
+		}
+	}
+
+	sort.Strings(c.Added)
+	sort.Strings(c.Updated)
+	sort.Strings(c.Removed)
+	sort.Strings(c.Failed)
+
+	return &library{
+		tracks: tracks,
+	}, c
+}
+
+// sameSize returns true if the file size n matches the Size of the track t, or t doesn't have
+// a Size.
+func sameSize(n int64, t index.Track) bool {
+	size := t.GetInt("Size")
+	return size == 0 || n == int64(size)
+}
+
+// updatedTrack is an index.Track which has been re-read from its file, but which keeps the
+// DateAdded value of the track it replaces (re-tagging a file shouldn't make it recent).
+type updatedTrack struct {
+	index.Track
+	dateAdded time.Time
+}
+
+// GetTime implements index.Track.
+func (u *updatedTrack) GetTime(name string) time.Time {
+	if name == "DateAdded" && !u.dateAdded.IsZero() {
+		return u.dateAdded
+	}
+	return u.Track.GetTime(name)
+}
@@ -0,0 +1,147 @@
+package walk
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/amiforus/tchaik/index"
+)
+
+// testTrack is an index.Track with the fields used to decide whether a file has changed.
+type testTrack struct {
+	Name, Location string
+	Size           int
+	DateModified   time.Time
+	DateAdded      time.Time
+}
+
+func (t testTrack) GetString(f string) string {
+	switch f {
+	case "Name":
+		return t.Name
+	case "Location":
+		return t.Location
+	}
+	return ""
+}
+
+func (t testTrack) GetStrings(f string) []string { return nil }
+
+func (t testTrack) GetInt(f string) int {
+	if f == "Size" {
+		return t.Size
+	}
+	return 0
+}
+
+func (t testTrack) GetTime(f string) time.Time {
+	switch f {
+	case "DateModified":
+		return t.DateModified
+	case "DateAdded":
+		return t.DateAdded
+	}
+	return time.Time{}
+}
+
+// writeTestFile writes a FLAC file to path, and returns a track for it.
+func writeTestFile(t *testing.T, path string) testTrack {
+	if err := ioutil.WriteFile(path, flacStream(44100, 44100), 0644); err != nil {
+		t.Fatalf("unexpected error writing file: %v", err)
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		t.Fatalf("unexpected error in stat: %v", err)
+	}
+	return testTrack{
+		Location:     path,
+		Size:         int(info.Size()),
+		DateModified: info.ModTime(),
+	}
+}
+
+func TestUpdate(t *testing.T) {
+	dir, err := ioutil.TempDir("", "update")
+	if err != nil {
+		t.Fatalf("unexpected error creating temp dir: %v", err)
+	}
+	defer os.RemoveAll(dir)
+
+	path := func(name string) string { return filepath.Join(dir, name) }
+	added := time.Date(2015, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	unchanged := writeTestFile(t, path("unchanged.flac"))
+	unchanged.Name = "Unchanged"
+
+	modified := writeTestFile(t, path("modified.flac"))
+	modified.DateModified = modified.DateModified.Add(-time.Hour)
+	modified.DateAdded = added
+
+	resized := writeTestFile(t, path("resized.flac"))
+	resized.Size++
+
+	// Libraries written by older versions don't have Size.
+	unsized := writeTestFile(t, path("unsized.flac"))
+	unsized.Size = 0
+
+	// A changed file which can't be read keeps its existing track.
+	broken := writeTestFile(t, path("broken.flac"))
+	broken.Name = "Broken"
+	broken.DateModified = broken.DateModified.Add(-time.Hour)
+	if err := ioutil.WriteFile(path("broken.flac"), []byte("not a flac file"), 0644); err != nil {
+		t.Fatalf("unexpected error writing file: %v", err)
+	}
+	if err := ioutil.WriteFile(path("unreadable.flac"), []byte("not a flac file"), 0644); err != nil {
+		t.Fatalf("unexpected error writing file: %v", err)
+	}
+
+	writeTestFile(t, path("added.flac"))
+
+	l := &library{tracks: map[string]index.Track{
+		"1": unchanged,
+		"2": modified,
+		"3": resized,
+		"4": testTrack{Location: path("removed.flac")},
+		"5": unsized,
+		"6": broken,
+	}}
+
+	nl, c := Update(l, dir)
+
+	expected := Changes{
+		Added:   []string{path("added.flac")},
+		Updated: []string{path("modified.flac"), path("resized.flac")},
+		Removed: []string{path("removed.flac")},
+		Failed:  []string{path("broken.flac"), path("unreadable.flac")},
+	}
+	if !reflect.DeepEqual(c, expected) {
+		t.Errorf("Update() changes = %#v, expected %#v", c, expected)
+	}
+
+	tracks := make(map[string]index.Track)
+	for _, t := range nl.Tracks() {
+		tracks[t.GetString("Location")] = t
+	}
+	if n := len(tracks); n != 6 {
+		t.Errorf("Update() returned %d tracks, expected 6", n)
+	}
+
+	// Unchanged files are not re-read, and files which can't be read keep their track.
+	for _, x := range []testTrack{unchanged, unsized, broken} {
+		if tr := tracks[x.Location]; tr != index.Track(x) {
+			t.Errorf("Update() track for %v = %#v, expected %#v", x.Location, tr, x)
+		}
+	}
+
+	tr, ok := tracks[path("modified.flac")]
+	if !ok {
+		t.Fatalf("Update() track for modified file not found")
+	}
+	if got := tr.GetTime("DateAdded"); !got.Equal(added) {
+		t.Errorf("Update() DateAdded of updated track = %v, expected %v", got, added)
+	}
+}
@@ -13,21 +13,29 @@ import (
 	"time"
 
 	"github.com/dhowden/tag"
+	"github.com/dhowden/tag/mbz"
 	"github.com/amiforus/tchaik/index"
 )
 
 var fileExtensions = []string{".mp3", ".m4a", ".flac", ".ogg"}
 
+// isValidFile returns true if the path has one of the supported fileExtensions.
+func isValidFile(path string) bool {
+	ext := strings.ToLower(filepath.Ext(filepath.Base(path)))
+	for _, x := range fileExtensions {
+		if ext == x {
+			return true
+		}
+	}
+	return false
+}
+
 func validFiles(in <-chan string) <-chan string {
 	out := make(chan string)
 	go func() {
 		for path := range in {
-			ext := strings.ToLower(filepath.Ext(filepath.Base(path)))
-			for _, x := range fileExtensions {
-				if ext == x {
-					out <- path
-					break
-				}
+			if isValidFile(path) {
+				out <- path
 			}
 		}
 		close(out)
@@ -45,9 +53,21 @@ type pathTrack struct {
 // NewLibrary constructs an index.Library by walking through the directory tree under
 // the given path.  Any errors are logged to stdout (TODO: fix this!)
 func NewLibrary(path string) index.Library {
+	tracks := make(map[string]index.Track)
+	for p, t := range processFiles(validFiles(walk(path))) {
+		tracks[p] = t
+	}
+
+	return &library{
+		tracks: tracks,
+	}
+}
+
+// processFiles reads the metadata of each file path received from files using a pool
+// of workers, and returns a map of file path -> track.  Any errors are logged to stdout.
+func processFiles(files <-chan string) map[string]*track {
 	trackCh := make(chan pathTrack)
 	errCh := make(chan error)
-	files := validFiles(walk(path))
 
 	go func() {
 		for err := range errCh {
@@ -86,15 +106,12 @@ func NewLibrary(path string) index.Library {
 	for pt := range trackCh {
 		tracks[pt.path] = pt.track
 	}
-
-	return &library{
-		tracks: tracks,
-	}
+	return tracks
 }
 
 // library is an implementation of index.library.
 type library struct {
-	tracks map[string]*track
+	tracks map[string]index.Track
 }
 
 // Track implements index.Library.
@@ -118,10 +135,16 @@ type track struct {
 	Location    string
 	FileInfo    os.FileInfo
 	CreatedTime time.Time
+	Audio       audioInfo
+	Multi       map[string][]string // values of multi-valued tags (see readMultiValues)
 }
 
-// GetString implements index.Track.
+// GetString implements index.Track.  The values of multi-valued tags are joined by "; ".
 func (m *track) GetString(name string) string {
+	if x, ok := m.Multi[name]; ok {
+		return strings.Join(x, "; ")
+	}
+
 	switch name {
 	case "Name":
 		title := m.Title()
@@ -148,6 +171,14 @@ func (m *track) GetString(name string) string {
 	case "ID":
 		sum := sha1.Sum([]byte(m.Location))
 		return string(fmt.Sprintf("%x", sum))
+	case "Comment":
+		return m.Comment()
+	}
+	if names, ok := rawTagNames[name]; ok {
+		return rawString(m, names)
+	}
+	if t, ok := mbzTagNames[name]; ok {
+		return mbz.Extract(m).Get(t)
 	}
 	return ""
 }
@@ -174,6 +205,9 @@ func (k kind) String() string {
 func (m *track) GetStrings(name string) []string {
 	switch name {
 	case "Artist", "AlbumArtist", "Composer":
+		if x, ok := m.Multi[name]; ok {
+			return x
+		}
 		return index.DefaultGetStrings(m, name)
 	}
 	return nil
@@ -196,6 +230,29 @@ func (m *track) GetInt(name string) int {
 	case "DiscCount":
 		_, n := m.Disc()
 		return n
+	case "TotalTime":
+		return int(m.Audio.Duration / time.Millisecond)
+	case "BitRate":
+		return m.Audio.BitRate
+	case "Size":
+		return int(m.FileInfo.Size())
+	case "HasLyrics":
+		if m.Lyrics() != "" {
+			return 1
+		}
+		return 0
+	case "Compilation":
+		if rawInt(m, rawTagNames[name]) != 0 {
+			return 1
+		}
+		return 0
+	case "TrackGain", "AlbumGain":
+		return parseScaled(rawString(m, rawTagNames[name]), 100)
+	case "TrackPeak", "AlbumPeak":
+		return parseScaled(rawString(m, rawTagNames[name]), 1e6)
+	}
+	if names, ok := rawTagNames[name]; ok {
+		return rawInt(m, names)
 	}
 	return 0
 }
@@ -256,10 +313,24 @@ func processPath(path string) (*track, error) {
 		return nil, err
 	}
 
+	audio, err := readAudioInfo(f, m.FileType(), fileInfo.Size())
+	if err != nil {
+		// FIXME
+		log.Printf("error reading audio properties of '%v': %v", path, err)
+	}
+
+	multi, err := readMultiValues(f, m.FileType())
+	if err != nil {
+		// FIXME
+		log.Printf("error reading multi-valued tags of '%v': %v", path, err)
+	}
+
 	return &track{
 		Metadata:    m,
 		Location:    path,
 		FileInfo:    fileInfo,
 		CreatedTime: createdTime,
+		Audio:       audio,
+		Multi:       multi,
 	}, nil
 }
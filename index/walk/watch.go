@@ -0,0 +1,153 @@
+package walk
+
+import (
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// DefaultPollInterval is the interval used to poll for changes when filesystem change
+// notifications are unavailable.
+const DefaultPollInterval = time.Minute
+
+// settleTime is the time to wait after the most recent change before notifying, so that
+// copying a whole album results in a single notification.
+var settleTime = 2 * time.Second
+
+// Watcher watches a directory tree for changes to audio files.
+type Watcher struct {
+	// Changes receives a value when audio files under the root have been added, removed
+	// or modified (and then left unchanged for a short time).  Pending notifications are
+	// coalesced.
+	Changes <-chan struct{}
+
+	done   chan struct{}
+	closer io.Closer
+}
+
+// Watch creates a Watcher for the directory tree under root.  Filesystem change notifications
+// are used where supported (inotify on Linux).  Otherwise, or if poll is non-zero, the tree is
+// walked every poll interval (DefaultPollInterval if poll is zero) and compared to the
+// previous walk.
+func Watch(root string, poll time.Duration) (*Watcher, error) {
+	if _, err := os.Stat(root); err != nil {
+		return nil, err
+	}
+
+	raw := make(chan struct{}, 1)
+	out := make(chan struct{}, 1)
+	w := &Watcher{
+		Changes: out,
+		done:    make(chan struct{}),
+	}
+
+	if poll == 0 {
+		n, err := newNotifier(root, raw)
+		if err == nil {
+			w.closer = n
+		} else {
+			log.Printf("could not watch '%v' for changes, polling instead: %v", root, err)
+			poll = DefaultPollInterval
+		}
+	}
+
+	if w.closer == nil {
+		// The first walk is done now, so that changes made once Watch returns are reported.
+		go pollTree(root, poll, snapshot(root), raw, w.done)
+	}
+	go settle(raw, out, w.done)
+	return w, nil
+}
+
+// Close stops the Watcher.
+func (w *Watcher) Close() error {
+	close(w.done)
+	if w.closer != nil {
+		return w.closer.Close()
+	}
+	return nil
+}
+
+// notify sends to ch without blocking, dropping the value if a send is already pending.
+func notify(ch chan<- struct{}) {
+	select {
+	case ch <- struct{}{}:
+	default:
+	}
+}
+
+// settle forwards values from in to out once no further values have been received
+// for settleTime.
+func settle(in <-chan struct{}, out chan<- struct{}, done <-chan struct{}) {
+	var timer <-chan time.Time
+	for {
+		select {
+		case <-in:
+			timer = time.After(settleTime)
+		case <-timer:
+			timer = nil
+			notify(out)
+		case <-done:
+			return
+		}
+	}
+}
+
+// fileState is the state of a file used to determine if it has changed between polls.
+type fileState struct {
+	size    int64
+	modTime time.Time
+}
+
+// snapshot walks the directory tree under root and records the state of each audio file.
+func snapshot(root string) map[string]fileState {
+	m := make(map[string]fileState)
+	filepath.Walk(root, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return nil
+		}
+		if !info.IsDir() && isValidFile(path) {
+			m[path] = fileState{
+				size:    info.Size(),
+				modTime: info.ModTime(),
+			}
+		}
+		return nil
+	})
+	return m
+}
+
+func sameSnapshot(x, y map[string]fileState) bool {
+	if len(x) != len(y) {
+		return false
+	}
+	for k, v := range x {
+		w, ok := y[k]
+		if !ok || v.size != w.size || !v.modTime.Equal(w.modTime) {
+			return false
+		}
+	}
+	return true
+}
+
+// pollTree walks the directory tree under root every interval d, and notifies ch when
+// anything has changed since the previous walk (starting with last).
+func pollTree(root string, d time.Duration, last map[string]fileState, ch chan<- struct{}, done <-chan struct{}) {
+	t := time.NewTicker(d)
+	defer t.Stop()
+
+	for {
+		select {
+		case <-t.C:
+			curr := snapshot(root)
+			if !sameSnapshot(last, curr) {
+				notify(ch)
+			}
+			last = curr
+		case <-done:
+			return
+		}
+	}
+}
@@ -0,0 +1,111 @@
+// +build linux
+
+package walk
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"unsafe"
+
+	"golang.org/x/sys/unix"
+)
+
+const watchMask = unix.IN_CREATE | unix.IN_DELETE | unix.IN_CLOSE_WRITE | unix.IN_MOVED_FROM |
+	unix.IN_MOVED_TO | unix.IN_DELETE_SELF | unix.IN_MOVE_SELF
+
+// inotify watches a directory tree using inotify(7).  Watches are added for each directory
+// in the tree (including those created after the watch has started).
+type inotify struct {
+	fd  int
+	f   *os.File
+	wds map[int]string // watch descriptor -> directory path
+}
+
+// newNotifier creates an inotify watcher for the directory tree under root, which
+// notifies ch when an audio file or directory changes.  Close the returned io.Closer to
+// stop watching.
+func newNotifier(root string, ch chan<- struct{}) (io.Closer, error) {
+	fd, err := unix.InotifyInit1(unix.IN_CLOEXEC | unix.IN_NONBLOCK)
+	if err != nil {
+		return nil, err
+	}
+
+	n := &inotify{
+		fd:  fd,
+		f:   os.NewFile(uintptr(fd), "inotify"),
+		wds: make(map[int]string),
+	}
+	if err := n.addTree(root); err != nil {
+		n.f.Close()
+		return nil, err
+	}
+	go n.read(ch)
+	return n.f, nil
+}
+
+func (n *inotify) addTree(root string) error {
+	return filepath.Walk(root, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if !info.IsDir() {
+			return nil
+		}
+		wd, err := unix.InotifyAddWatch(n.fd, path, watchMask)
+		if err != nil {
+			return fmt.Errorf("error adding watch for '%v': %v", path, err)
+		}
+		n.wds[wd] = path
+		return nil
+	})
+}
+
+func (n *inotify) read(ch chan<- struct{}) {
+	buf := make([]byte, 64*(unix.SizeofInotifyEvent+unix.PathMax+1))
+	for {
+		k, err := n.f.Read(buf)
+		if err != nil {
+			return
+		}
+
+		for off := 0; off+unix.SizeofInotifyEvent <= k; {
+			e := (*unix.InotifyEvent)(unsafe.Pointer(&buf[off]))
+			start := off + unix.SizeofInotifyEvent
+			end := start + int(e.Len)
+			name := string(bytes.TrimRight(buf[start:end], "\x00"))
+			n.handle(int(e.Wd), e.Mask, name, ch)
+			off = end
+		}
+	}
+}
+
+func (n *inotify) handle(wd int, mask uint32, name string, ch chan<- struct{}) {
+	dir, ok := n.wds[wd]
+	if !ok {
+		return
+	}
+
+	if mask&unix.IN_IGNORED != 0 {
+		delete(n.wds, wd)
+		return
+	}
+
+	path := filepath.Join(dir, name)
+	if mask&unix.IN_ISDIR != 0 {
+		if mask&(unix.IN_CREATE|unix.IN_MOVED_TO) != 0 {
+			if err := n.addTree(path); err != nil {
+				log.Println(err)
+			}
+		}
+		notify(ch)
+		return
+	}
+
+	if mask&(unix.IN_DELETE_SELF|unix.IN_MOVE_SELF) != 0 || isValidFile(path) {
+		notify(ch)
+	}
+}
@@ -0,0 +1,47 @@
+// +build linux
+
+package walk
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestWatchNotify(t *testing.T) {
+	defer setSettleTime(20 * time.Millisecond)()
+
+	dir, err := ioutil.TempDir("", "watch")
+	if err != nil {
+		t.Fatalf("unexpected error creating temp dir: %v", err)
+	}
+	defer os.RemoveAll(dir)
+
+	w, err := Watch(dir, 0)
+	if err != nil {
+		t.Fatalf("unexpected error from Watch: %v", err)
+	}
+	defer w.Close()
+	if w.closer == nil {
+		t.Skip("inotify unavailable")
+	}
+
+	sub := filepath.Join(dir, "album")
+	if err := os.Mkdir(sub, 0755); err != nil {
+		t.Fatalf("unexpected error creating dir: %v", err)
+	}
+	expectChange(t, w.Changes, "directory added")
+
+	// Files in the new directory are watched.
+	if err := ioutil.WriteFile(filepath.Join(sub, "a.mp3"), []byte("x"), 0644); err != nil {
+		t.Fatalf("unexpected error writing file: %v", err)
+	}
+	expectChange(t, w.Changes, "audio file added to new directory")
+
+	if err := ioutil.WriteFile(filepath.Join(sub, "notes.txt"), []byte("x"), 0644); err != nil {
+		t.Fatalf("unexpected error writing file: %v", err)
+	}
+	expectNoChange(t, w.Changes, 100*time.Millisecond, "non-audio file added")
+}
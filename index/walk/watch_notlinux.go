@@ -0,0 +1,12 @@
+// +build !linux
+
+package walk
+
+import (
+	"errors"
+	"io"
+)
+
+func newNotifier(string, chan<- struct{}) (io.Closer, error) {
+	return nil, errors.New("filesystem notifications are not supported on this platform")
+}
@@ -0,0 +1,119 @@
+package walk
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// setSettleTime sets settleTime to d for the duration of a test, and returns a function
+// which restores it.
+func setSettleTime(d time.Duration) func() {
+	old := settleTime
+	settleTime = d
+	return func() { settleTime = old }
+}
+
+// expectChange fails the test if ch doesn't receive a value within five seconds.
+func expectChange(t *testing.T, ch <-chan struct{}, msg string) {
+	select {
+	case <-ch:
+	case <-time.After(5 * time.Second):
+		t.Fatalf("no change received: %v", msg)
+	}
+}
+
+// expectNoChange fails the test if ch receives a value within d.
+func expectNoChange(t *testing.T, ch <-chan struct{}, d time.Duration, msg string) {
+	select {
+	case <-ch:
+		t.Fatalf("unexpected change received: %v", msg)
+	case <-time.After(d):
+	}
+}
+
+func TestSettle(t *testing.T) {
+	defer setSettleTime(50 * time.Millisecond)()
+
+	in := make(chan struct{})
+	out := make(chan struct{}, 1)
+	done := make(chan struct{})
+	defer close(done)
+	go settle(in, out, done)
+
+	// Changes in quick succession result in a single notification after they stop.
+	var last time.Time
+	for i := 0; i < 5; i++ {
+		last = time.Now()
+		in <- struct{}{}
+	}
+	expectChange(t, out, "after changes")
+	if d := time.Since(last); d < settleTime {
+		t.Errorf("notified %v after the last change, expected to wait for changes to settle", d)
+	}
+	expectNoChange(t, out, 100*time.Millisecond, "only one notification expected")
+}
+
+func TestSameSnapshot(t *testing.T) {
+	now := time.Now()
+	x := map[string]fileState{
+		"a.mp3": {1, now},
+		"b.mp3": {2, now},
+	}
+
+	tests := []struct {
+		y        map[string]fileState
+		expected bool
+	}{
+		{map[string]fileState{"a.mp3": {1, now}, "b.mp3": {2, now}}, true},
+		{map[string]fileState{"a.mp3": {1, now}}, false},
+		{map[string]fileState{"a.mp3": {1, now}, "c.mp3": {2, now}}, false},
+		{map[string]fileState{"a.mp3": {1, now}, "b.mp3": {3, now}}, false},
+		{map[string]fileState{"a.mp3": {1, now}, "b.mp3": {2, now.Add(time.Second)}}, false},
+	}
+
+	for ii, tt := range tests {
+		if got := sameSnapshot(x, tt.y); got != tt.expected {
+			t.Errorf("[%d] sameSnapshot() = %v, expected %v", ii, got, tt.expected)
+		}
+	}
+}
+
+func TestWatchPoll(t *testing.T) {
+	defer setSettleTime(20 * time.Millisecond)()
+
+	dir, err := ioutil.TempDir("", "watch")
+	if err != nil {
+		t.Fatalf("unexpected error creating temp dir: %v", err)
+	}
+	defer os.RemoveAll(dir)
+
+	w, err := Watch(dir, 10*time.Millisecond)
+	if err != nil {
+		t.Fatalf("unexpected error from Watch: %v", err)
+	}
+	defer w.Close()
+
+	if err := ioutil.WriteFile(filepath.Join(dir, "notes.txt"), []byte("x"), 0644); err != nil {
+		t.Fatalf("unexpected error writing file: %v", err)
+	}
+	expectNoChange(t, w.Changes, 100*time.Millisecond, "non-audio file added")
+
+	p := filepath.Join(dir, "a.mp3")
+	if err := ioutil.WriteFile(p, []byte("x"), 0644); err != nil {
+		t.Fatalf("unexpected error writing file: %v", err)
+	}
+	expectChange(t, w.Changes, "audio file added")
+
+	if err := ioutil.WriteFile(p, []byte("xx"), 0644); err != nil {
+		t.Fatalf("unexpected error writing file: %v", err)
+	}
+	expectChange(t, w.Changes, "audio file modified")
+
+	if err := os.Remove(p); err != nil {
+		t.Fatalf("unexpected error removing file: %v", err)
+	}
+	expectChange(t, w.Changes, "audio file removed")
+}
@@ -0,0 +1,194 @@
+// Copyright 2015, David Howden
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package index
+
+import (
+	"regexp"
+	"strings"
+)
+
+// catalogueRegexp matches catalogue numbers (i.e. "BWV 1007", "K. 550", "Op. 18 No. 4",
+// "Hob. XVI:52").
+var catalogueRegexp = regexp.MustCompile(`\b(BWV|HWV|RV|Hob|KV|K|D|S|[Oo]pus|[Oo]p)\.?\s*((?:[IVXL]+[a-z]?:)?\d+[a-z]?)(?:,?\s*[Nn]o\.?\s*(\d+[a-z]?))?\b`)
+
+// cataloguePrefixes maps the prefixes matched by catalogueRegexp to their normalised form.
+var cataloguePrefixes = map[string]string{
+	"BWV":  "BWV",
+	"HWV":  "HWV",
+	"RV":   "RV",
+	"Hob":  "Hob.",
+	"KV":   "K.",
+	"K":    "K.",
+	"D":    "D.",
+	"S":    "S.",
+	"Opus": "Op.",
+	"opus": "Op.",
+	"Op":   "Op.",
+	"op":   "Op.",
+}
+
+// keyRegexp matches key signatures (i.e. "in C minor", "in B-flat major", "in E♭").
+var keyRegexp = regexp.MustCompile(`\bin ([A-G])(?:[- ]?(?i:(flat|sharp))|([b#♭♯]))?(?:[- ](?i:(major|minor))\b|\s*(?:[,:;)]|$))`)
+
+// work is the work which a track is part of, as identified from its name.
+type work struct {
+	title     string // i.e. "Symphony No. 5 in C minor, Op. 67", empty if there isn't one
+	catalogue string // normalised catalogue number, i.e. "Op. 67"
+	key       string // normalised key signature, i.e. "C minor"
+	movement  int    // index of the movement in the name, zero if there isn't one
+}
+
+// parseWork identifies the work from a track name.  Works are named by the convention
+// "<Work>: <Movement>" (or "<Work> - <Movement>"), and can include a catalogue number and
+// key signature (i.e. "Symphony No. 5 in C minor, Op. 67: I. Allegro con brio").
+func parseWork(name string) work {
+	var w work
+	start, end := -1, -1
+	if m := catalogueRegexp.FindStringSubmatchIndex(name); m != nil {
+		start, end = m[0], m[1]
+		w.catalogue = cataloguePrefixes[name[m[2]:m[3]]] + " " + name[m[4]:m[5]]
+		if m[6] >= 0 {
+			w.catalogue += " No. " + name[m[6]:m[7]]
+		}
+	}
+
+	// Colons in catalogue numbers (i.e. "Hob. XVI:52") are not separators.
+	sep := len(name)
+	for i := 0; i < len(name); i++ {
+		if i >= start && i < end {
+			continue
+		}
+		if name[i] == ':' {
+			sep, w.movement = i, i+1
+			break
+		}
+		if strings.HasPrefix(name[i:], " - ") {
+			sep, w.movement = i, i+3
+			break
+		}
+	}
+
+	w.title = strings.TrimRight(strings.TrimSpace(name[:sep]), ",")
+	if w.title == "" || w.movement == 0 {
+		w.title, w.movement = "", 0
+	}
+
+	if m := keyRegexp.FindStringSubmatch(name[:sep]); m != nil {
+		w.key = m[1]
+		switch {
+		case m[2] != "":
+			w.key += "-" + strings.ToLower(m[2])
+		case m[3] == "b" || m[3] == "♭":
+			w.key += "-flat"
+		case m[3] == "#" || m[3] == "♯":
+			w.key += "-sharp"
+		}
+		if m[4] != "" {
+			w.key += " " + strings.ToLower(m[4])
+		}
+	}
+	return w
+}
+
+// id returns the identifier of the work: its catalogue number, or its key signature if it
+// has a title.  Returns the empty string if the work can't be identified.
+func (w work) id() string {
+	if w.catalogue != "" {
+		return w.catalogue
+	}
+	if w.title != "" && w.key != "" {
+		return "\x00" + w.key
+	}
+	return ""
+}
+
+// ByCatalogue returns a Collector which groups consecutive tracks into the works they are
+// part of, identified by the catalogue number (i.e. "BWV 1007", "K. 550", "Op. 18 No. 4") or
+// key signature (i.e. "in C minor") in the field, so that movements are grouped even when
+// the text before them varies.  Groups are named by the title of the work (see parseWork),
+// or its catalogue number if it has no title, and have fields Work and Catalogue.  Tracks
+// which aren't part of a work (with at least two tracks) are grouped by ByPrefix.
+func ByCatalogue(field string) Collector {
+	return groupByCatalogue(field)
+}
+
+type groupByCatalogue string
+
+// Collect implements Collector.
+func (f groupByCatalogue) Collect(tracker Tracker) Collection {
+	tracks := tracker.Tracks()
+	gg := pfxCol{col: newCol(collectName(tracker, "Work")), field: string(f)}
+
+	works := make([]work, len(tracks))
+	ids := make([]string, len(tracks))
+	for i, t := range tracks {
+		works[i] = parseWork(t.GetString(string(f)))
+		ids[i] = works[i].id()
+	}
+
+	// Works must have more than one track.
+	for i := 0; i < len(tracks); {
+		j := nextRun(ids, i)
+		if j-i == 1 {
+			ids[i] = ""
+		}
+		i = j
+	}
+
+	for i := 0; i < len(tracks); {
+		j := nextRun(ids, i)
+		gg.key = ""
+		if ids[i] == "" {
+			gg.addPrefixed(tracks[i:j])
+		} else {
+			gg.addWork(works[i:j], tracks[i:j])
+		}
+		i = j
+	}
+	return gg
+}
+
+// nextRun returns the index after the run of ids starting at i which are equal to ids[i].
+func nextRun(ids []string, i int) int {
+	j := i + 1
+	for j < len(ids) && ids[j] == ids[i] {
+		j++
+	}
+	return j
+}
+
+// addWork adds the tracks of a work to a new group, trimming the work title from their
+// field.
+func (c *pfxCol) addWork(works []work, tracks []Track) {
+	w := works[0]
+	var name string
+	for _, x := range works {
+		if x.title != "" {
+			name = x.title
+			break
+		}
+	}
+	if name == "" {
+		name = w.catalogue
+	}
+	if name == "" {
+		name = w.key
+	}
+
+	for i, t := range tracks {
+		c.addGroup(name, works[i].movement, t)
+	}
+
+	flds := make(map[string]interface{})
+	if name != w.catalogue && name != w.key {
+		flds["Work"] = name
+	}
+	if w.catalogue != "" {
+		flds["Catalogue"] = w.catalogue
+	}
+	g := c.grps[c.key]
+	g.fields = flds
+	c.grps[c.key] = g
+}
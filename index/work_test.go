@@ -0,0 +1,126 @@
+// Copyright 2015, David Howden
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package index
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseWork(t *testing.T) {
+	tests := []struct {
+		in       string
+		title    string
+		cat, key string
+		movement string
+	}{
+		{"Aria", "", "", "", ""},
+		{"Symphony No. 5 in C minor, Op. 67: I. Allegro con brio", "Symphony No. 5 in C minor, Op. 67", "Op. 67", "C minor", "I. Allegro con brio"},
+		{"Cello Suite No. 1 in G major, BWV 1007 - Prélude", "Cello Suite No. 1 in G major, BWV 1007", "BWV 1007", "G major", "Prélude"},
+		{"Suite No. 1, BWV1007: Allemande", "Suite No. 1, BWV1007", "BWV 1007", "", "Allemande"},
+		{"Symphony No. 40 in G minor, K. 550: I. Molto allegro", "Symphony No. 40 in G minor, K. 550", "K. 550", "G minor", "I. Molto allegro"},
+		{"Symphony No. 41, KV 551: IV. Molto allegro", "Symphony No. 41, KV 551", "K. 551", "", "IV. Molto allegro"},
+		{"String Quartet in C minor, Op. 18, No. 4: I. Allegro", "String Quartet in C minor, Op. 18, No. 4", "Op. 18 No. 4", "C minor", "I. Allegro"},
+		{"Piano Sonata in E-flat major, Hob. XVI:52: I. Allegro", "Piano Sonata in E-flat major, Hob. XVI:52", "Hob. XVI:52", "E-flat major", "I. Allegro"},
+		{"Piano Sonata in B♭, D. 960: I. Molto moderato", "Piano Sonata in B♭, D. 960", "D. 960", "B-flat", "I. Molto moderato"},
+		{"The Four Seasons, RV 269: Spring", "The Four Seasons, RV 269", "RV 269", "", "Spring"},
+		{"Messiah, HWV 56: Overture", "Messiah, HWV 56", "HWV 56", "", "Overture"},
+		{"Piano Sonata in B minor, S. 178", "", "S. 178", "B minor", ""},
+		{"Études, Opus 10: No. 3 in E major", "Études, Opus 10", "Op. 10", "", "No. 3 in E major"},
+		{"Sonata in A: I. Allegro", "Sonata in A", "", "A", "I. Allegro"},
+		{"Variations in A Small Room: I", "Variations in A Small Room", "", "", "I"},
+		{"Bach's 2 Partitas: I", "Bach's 2 Partitas", "", "", "I"},
+	}
+
+	for ii, tt := range tests {
+		w := parseWork(tt.in)
+		var movement string
+		if w.movement > 0 {
+			movement = pfxTrack{testTrack{Name: tt.in}, "Name", w.movement}.GetString("Name")
+		}
+		if w.title != tt.title || w.catalogue != tt.cat || w.key != tt.key || movement != tt.movement {
+			t.Errorf("[%d] parseWork(%q) = (%q, %q, %q, %q), expected (%q, %q, %q, %q)", ii, tt.in,
+				w.title, w.catalogue, w.key, movement, tt.title, tt.cat, tt.key, tt.movement)
+		}
+	}
+}
+
+func TestByCatalogue(t *testing.T) {
+	tracks := testTracker{
+		{Name: "Cello Suite No. 1 in G major, BWV 1007: I. Prélude"},
+		{Name: "Suite No. 1, BWV 1007: II. Allemande"},
+		{Name: "Suite No. 1 in G, BWV 1007 - III. Courante"},
+		{Name: "Cello Suite No. 2 in D minor, BWV 1008: I. Prélude"},
+		{Name: "Cello Suite No. 2, BWV 1008: II. Allemande"},
+		{Name: "Air on the G String"},
+		{Name: "Toccata and Fugue in D minor, BWV 565"},
+		{Name: "Preludes, Book 1: No. 1: Danseuses de Delphes"},
+		{Name: "Preludes, Book 1: No. 2: Voiles"},
+	}
+
+	c := Collect(tracks, ByCatalogue("Name"))
+
+	type group struct {
+		name   string
+		fields map[string]interface{}
+		tracks []string
+	}
+	var got []group
+	for _, k := range c.Keys() {
+		g := c.Get(k)
+		x := group{
+			name:   g.Name(),
+			fields: make(map[string]interface{}),
+		}
+		for _, f := range []string{"Work", "Catalogue"} {
+			if v := g.Field(f); v != nil {
+				x.fields[f] = v
+			}
+		}
+		for _, t := range g.Tracks() {
+			x.tracks = append(x.tracks, t.GetString("Name"))
+		}
+		got = append(got, x)
+	}
+
+	expected := []group{
+		{
+			"Cello Suite No. 1 in G major, BWV 1007",
+			map[string]interface{}{"Work": "Cello Suite No. 1 in G major, BWV 1007", "Catalogue": "BWV 1007"},
+			[]string{"I. Prélude", "II. Allemande", "III. Courante"},
+		},
+		{
+			"Cello Suite No. 2 in D minor, BWV 1008",
+			map[string]interface{}{"Work": "Cello Suite No. 2 in D minor, BWV 1008", "Catalogue": "BWV 1008"},
+			[]string{"I. Prélude", "II. Allemande"},
+		},
+		{
+			"",
+			map[string]interface{}{},
+			[]string{"Air on the G String", "Toccata and Fugue in D minor, BWV 565"},
+		},
+		{
+			"Preludes, Book 1",
+			map[string]interface{}{},
+			[]string{"No. 1: Danseuses de Delphes", "No. 2: Voiles"},
+		},
+	}
+
+	if !reflect.DeepEqual(got, expected) {
+		t.Errorf("ByCatalogue(Name) = %#v, expected %#v", got, expected)
+	}
+
+	// Without catalogue numbers or key signatures, groups are the same as ByPrefix.
+	tracks = testTracker{
+		{Name: "Symphony No. 1: I. Andante"},
+		{Name: "Symphony No. 1: II. Adagio"},
+		{Name: "Speak to Me"},
+		{Name: "Breathe"},
+	}
+	got1, got2 := Collect(tracks, ByCatalogue("Name")), Collect(tracks, ByPrefix("Name"))
+	if !reflect.DeepEqual(got1.Keys(), got2.Keys()) {
+		t.Errorf("ByCatalogue(Name).Keys() = %v, expected %v", got1.Keys(), got2.Keys())
+	}
+}
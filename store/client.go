@@ -87,6 +87,10 @@ func (c *client) Get(ctx context.Context, path string) (f *File, err error) {
 		return nil, err
 	}
 
+	if resp.Status == StatusNotFound {
+		conn.Close()
+		return nil, notExist(path)
+	}
 	if resp.Status != StatusOK {
 		return nil, fmt.Errorf("error from '%v' (%v): %v", c.addr, c.label, resp.Status)
 	}
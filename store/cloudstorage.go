@@ -44,11 +44,17 @@ func (c *CloudStorageClient) Get(ctx context.Context, path string) (*File, error
 	obj := bh.Object(path)
 
 	attrs, err := obj.Attrs(ctx)
+	if err == storage.ErrObjectNotExist {
+		return nil, notExist(path)
+	}
 	if err != nil {
 		return nil, fmt.Errorf("unable to fetch object attributes: %v", err)
 	}
 
 	r, err := obj.NewReader(ctx)
+	if err == storage.ErrObjectNotExist {
+		return nil, notExist(path)
+	}
 	if err != nil {
 		return nil, fmt.Errorf("error fetching '%v' from '%v': %v", path, c.bucket, err)
 	}
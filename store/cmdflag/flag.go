@@ -36,6 +36,11 @@ func init() {
 	flag.StringVar(&addPathPrefix, "add-path-prefix", "", "add `prefix` to every path")
 }
 
+// PathPrefixes returns the values of the -trim-path-prefix and -add-path-prefix flags.
+func PathPrefixes() (trimPrefix, addPrefix string) {
+	return trimPathPrefix, addPathPrefix
+}
+
 type stores struct {
 	media, artwork store.FileSystem
 }
@@ -71,6 +71,12 @@ func (tfs traceFileSystem) Open(ctx context.Context, path string) (f http.File,
 	return tfs.fs.Open(ctx, path)
 }
 
+// notExist returns an error for the path which satisfies os.IsNotExist, so that files which
+// don't exist in remote stores can be distinguished from other errors.
+func notExist(path string) error {
+	return &os.PathError{Op: "open", Path: path, Err: os.ErrNotExist}
+}
+
 // RemoteFileSystem is an extension of the http.FileSystem interface
 // which includes the RemoteOpen method.
 type RemoteFileSystem interface {
@@ -38,12 +38,12 @@ func (c *S3Client) Get(ctx context.Context, path string) (*File, error) {
 
 	k, err := b.GetKey(path)
 	if err != nil {
-		return nil, err
+		return nil, s3Error(path, err)
 	}
 
 	rc, err := b.GetReader(path)
 	if err != nil {
-		return nil, err
+		return nil, s3Error(path, err)
 	}
 
 	modTime, _ := time.Parse(http.TimeFormat, k.LastModified)
@@ -54,3 +54,11 @@ func (c *S3Client) Get(ctx context.Context, path string) (*File, error) {
 		Size:       k.Size,
 	}, nil
 }
+
+// s3Error converts "not found" errors from S3 into errors which satisfy os.IsNotExist.
+func s3Error(path string, err error) error {
+	if e, ok := err.(*s3.Error); ok && e.StatusCode == http.StatusNotFound {
+		return notExist(path)
+	}
+	return err
+}
@@ -10,6 +10,7 @@ import (
 	"io"
 	"log"
 	"net"
+	"os"
 	"time"
 
 	"golang.org/x/net/context"
@@ -47,6 +48,8 @@ func (r ResponseStatus) String() string {
 	switch r {
 	case StatusOK:
 		return "OK"
+	case StatusLabelNotFound:
+		return "Label Not Found"
 	case StatusPathError:
 		return "Path Error"
 	case StatusInvalidPath:
@@ -127,14 +130,18 @@ func (s *Server) handle(c net.Conn) (err error) {
 
 	fs, ok := s.fileSystems[r.Label]
 	if !ok {
-		writeStatusResponse(c, StatusNotFound)
+		writeStatusResponse(c, StatusLabelNotFound)
 		return fmt.Errorf("invalid label: %v", r.Label)
 	}
 
 	// FIXME: Transfer the context from the request?
 	f, err := fs.Open(context.Background(), r.Path)
 	if err != nil {
-		writeStatusResponse(c, StatusNotFound)
+		status := ResponseStatus(StatusFileError)
+		if os.IsNotExist(err) {
+			status = StatusNotFound
+		}
+		writeStatusResponse(c, status)
 		return fmt.Errorf("error opening file '%v': %v", r.Path, err)
 	}
 	defer f.Close()
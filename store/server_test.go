@@ -0,0 +1,63 @@
+package store
+
+import (
+	"io/ioutil"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"golang.org/x/net/context"
+)
+
+func TestClientNotFound(t *testing.T) {
+	dir, err := ioutil.TempDir("", "server")
+	if err != nil {
+		t.Fatalf("unexpected error creating temp dir: %v", err)
+	}
+	defer os.RemoveAll(dir)
+	if err := ioutil.WriteFile(filepath.Join(dir, "1.mp3"), []byte("data"), 0644); err != nil {
+		t.Fatalf("unexpected error creating file: %v", err)
+	}
+
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("unexpected error listening: %v", err)
+	}
+	defer l.Close()
+
+	s := NewServer(l.Addr().String())
+	s.SetDefault(NewFileSystem(http.Dir(dir), "test"))
+	go func() {
+		for {
+			c, err := l.Accept()
+			if err != nil {
+				return
+			}
+			go s.handle(c)
+		}
+	}()
+
+	ctx := context.Background()
+	c := NewClient(l.Addr().String(), "")
+	f, err := c.Get(ctx, "/1.mp3")
+	if err != nil {
+		t.Fatalf("unexpected error from Get: %v", err)
+	}
+	b, err := ioutil.ReadAll(f)
+	f.Close()
+	if err != nil || string(b) != "data" {
+		t.Errorf("Get(/1.mp3) read (%q, %v), expected (%q, nil)", b, err, "data")
+	}
+
+	_, err = c.Get(ctx, "/2.mp3")
+	if !os.IsNotExist(err) {
+		t.Errorf("Get(/2.mp3) error = %v, expected an error satisfying os.IsNotExist", err)
+	}
+
+	_, err = NewClient(l.Addr().String(), "unknown").Get(ctx, "/1.mp3")
+	if err == nil || os.IsNotExist(err) {
+		t.Errorf("Get(/1.mp3) with unknown label error = %v, expected an error not satisfying os.IsNotExist", err)
+	}
+}
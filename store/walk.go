@@ -0,0 +1,52 @@
+// Copyright 2015, David Howden
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package store
+
+import (
+	"os"
+	"path"
+	"sort"
+
+	"golang.org/x/net/context"
+)
+
+// Walk calls fn for each file in the tree rooted at root in the FileSystem fs, in lexical
+// order.  Directories are listed using Readdir, so only FileSystems which can list
+// directories (i.e. local stores) can be walked: remote stores return no files.  If fn
+// returns an error then Walk stops and returns it.
+func Walk(ctx context.Context, fs FileSystem, root string, fn func(path string, info os.FileInfo) error) error {
+	f, err := fs.Open(ctx, root)
+	if err != nil {
+		return err
+	}
+	infos, err := f.Readdir(-1)
+	f.Close()
+	if err != nil {
+		return err
+	}
+
+	names := make([]string, 0, len(infos))
+	byName := make(map[string]os.FileInfo, len(infos))
+	for _, info := range infos {
+		names = append(names, info.Name())
+		byName[info.Name()] = info
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		info := byName[name]
+		p := path.Join(root, name)
+		if info.IsDir() {
+			if err := Walk(ctx, fs, p, fn); err != nil {
+				return err
+			}
+			continue
+		}
+		if err := fn(p, info); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,45 @@
+package store
+
+import (
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"golang.org/x/net/context"
+)
+
+func TestWalk(t *testing.T) {
+	dir, err := ioutil.TempDir("", "walk")
+	if err != nil {
+		t.Fatalf("unexpected error creating temp dir: %v", err)
+	}
+	defer os.RemoveAll(dir)
+
+	for _, p := range []string{"b/2.mp3", "a/1.mp3", "a/c/3.flac", "4.m4a"} {
+		p = filepath.Join(dir, filepath.FromSlash(p))
+		if err := os.MkdirAll(filepath.Dir(p), 0755); err != nil {
+			t.Fatalf("unexpected error creating dir: %v", err)
+		}
+		if err := ioutil.WriteFile(p, nil, 0644); err != nil {
+			t.Fatalf("unexpected error creating file: %v", err)
+		}
+	}
+
+	fs := NewFileSystem(http.Dir(dir), "test")
+	var got []string
+	err = Walk(context.Background(), fs, "/", func(path string, info os.FileInfo) error {
+		got = append(got, path)
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("unexpected error from Walk: %v", err)
+	}
+
+	expected := []string{"/4.m4a", "/a/1.mp3", "/a/c/3.flac", "/b/2.mp3"}
+	if !reflect.DeepEqual(got, expected) {
+		t.Errorf("Walk() = %v, expected %v", got, expected)
+	}
+}